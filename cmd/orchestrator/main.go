@@ -17,6 +17,10 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Failed to load config: %v", err)
 	}
+	conf.Units, err = configs.LoadUnits(conf.UnitsPath)
+	if err != nil {
+		logger.Fatalf("Failed to load units: %v", err)
+	}
 
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 	app, err := orchestrator.New(conf)
@@ -1,10 +1,17 @@
 package agent
 
 import (
+	"calculator/internal/shared/complexnum"
+	"calculator/internal/shared/entities"
+	"calculator/internal/shared/exact"
+	"calculator/internal/shared/functions"
 	"calculator/pkg/logger"
 	"calculator/proto/calculator/proto"
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"strings"
 	"time"
 
@@ -48,13 +55,16 @@ func (w *Worker) doWork(ctx context.Context) {
 
 	}
 
-	result, err := w.performOperation(task)
+	result, err := w.compute(task)
 	if err != nil {
 		logger.Errorf("Failed to perform operation: %v", err)
+		if err = w.reportError(ctx, task, err); err != nil {
+			logger.Errorf("Failed to report task error: %v", err)
+		}
 		return
 	}
 
-	err = w.sendResult(ctx, task.Id, result)
+	err = w.sendResult(ctx, result)
 	if err != nil {
 		logger.Errorf("Failed to send result: %v", err)
 	}
@@ -71,6 +81,16 @@ func (w *Worker) getTask(ctx context.Context) (*proto.Task, error) {
 
 func (w *Worker) performOperation(task *proto.Task) (float64, error) {
 	var result float64
+	var err error
+
+	if task.Kind == proto.TaskKind_TASK_KIND_UNARY {
+		result, err := functions.ApplyUnary(task.Operation, task.Arg1)
+		if err != nil {
+			return 0, err
+		}
+		time.Sleep(time.Duration(task.OperationTime))
+		return result, nil
+	}
 
 	switch task.Operation {
 	case "+":
@@ -81,25 +101,156 @@ func (w *Worker) performOperation(task *proto.Task) (float64, error) {
 		result = task.Arg1 * task.Arg2
 	case "/":
 		if task.Arg2 == 0 {
-			return 0, fmt.Errorf("division by zero")
+			return 0, functions.ErrDivisionByZero
 		}
 		result = task.Arg1 / task.Arg2
+	case "%":
+		result, err = functions.Modulo(task.Arg1, task.Arg2)
+	case "//":
+		result, err = functions.FloorDivide(task.Arg1, task.Arg2)
+	case "^":
+		// 2^1024 overflows, (-8)^(1/3) is not real and 0^-1 is infinite
+		result = math.Pow(task.Arg1, task.Arg2)
+		if math.IsInf(result, 0) || math.IsNaN(result) {
+			err = fmt.Errorf("%g ^ %g is not a finite real number", task.Arg1, task.Arg2)
+		}
+	case "min":
+		result = math.Min(task.Arg1, task.Arg2)
+	case "max":
+		result = math.Max(task.Arg1, task.Arg2)
+	case "<", "<=", ">", ">=", "==", "!=", "and", "or":
+		result, err = functions.ApplyLogical(task.Operation, task.Arg1, task.Arg2)
 	default:
-		return 0, fmt.Errorf("unknown operation: %s", task.Operation)
+		return 0, fmt.Errorf("%w: %s", functions.ErrUnknownOperation, task.Operation)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	time.Sleep(time.Duration(task.OperationTime))
+	return result, nil
+}
+
+// compute performs the operation of the task in the arithmetic of its mode.
+func (w *Worker) compute(task *proto.Task) (*proto.TaskResult, error) {
+	if task.Mode == proto.Mode_MODE_FLOAT {
+		result, err := w.performOperation(task)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.TaskResult{Id: task.Id, Result: result, Lease: task.Lease}, nil
+	}
+	if task.Mode == proto.Mode_MODE_COMPLEX {
+		result, err := w.performComplexOperation(task)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.TaskResult{Id: task.Id, Result: real(result), ImagResult: imag(result), ExactResult: complexnum.Format(result), Lease: task.Lease}, nil
+	}
+
+	exactResult, err := w.performExactOperation(task)
+	if err != nil {
+		return nil, err
+	}
+	x, err := exact.Parse(exactResult)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := x.Float64()
+	return &proto.TaskResult{Id: task.Id, Result: result, ExactResult: exactResult, Lease: task.Lease}, nil
+}
+
+// performExactOperation computes the task with arbitrary precision.
+// In decimal mode the result is rounded to the scale of the task and written
+// as a decimal string, in rational mode it is written as a reduced fraction.
+func (w *Worker) performExactOperation(task *proto.Task) (string, error) {
+	scale := int(task.Scale)
+
+	arg1, err := exact.Parse(task.ExactArg1)
+	if err != nil {
+		return "", err
+	}
+
+	var result *big.Rat
+	if task.Kind == proto.TaskKind_TASK_KIND_UNARY && task.Mode == proto.Mode_MODE_RATIONAL {
+		result, err = exact.ApplyUnary(task.Operation, arg1)
+	} else if task.Kind == proto.TaskKind_TASK_KIND_UNARY {
+		result, err = exact.ApplyUnaryDecimal(task.Operation, arg1, scale)
+	} else {
+		var arg2 *big.Rat
+		arg2, err = exact.Parse(task.ExactArg2)
+		if err != nil {
+			return "", err
+		}
+		result, err = exact.Apply(task.Operation, arg1, arg2)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	time.Sleep(time.Duration(task.OperationTime))
+	if task.Mode == proto.Mode_MODE_RATIONAL {
+		return result.RatString(), nil
+	}
+	return exact.FormatDecimal(result, scale), nil
+}
+
+// performComplexOperation computes the task with the complex numbers
+// made of the real and imaginary parts of its arguments.
+func (w *Worker) performComplexOperation(task *proto.Task) (complex128, error) {
+	arg1 := complex(task.Arg1, task.ImagArg1)
+
+	var result complex128
+	var err error
+	if task.Kind == proto.TaskKind_TASK_KIND_UNARY {
+		result, err = complexnum.ApplyUnary(task.Operation, arg1)
+	} else {
+		result, err = complexnum.Apply(task.Operation, arg1, complex(task.Arg2, task.ImagArg2))
+	}
+	if err != nil {
+		return 0, err
 	}
 
 	time.Sleep(time.Duration(task.OperationTime))
 	return result, nil
 }
 
-func (w *Worker) sendResult(ctx context.Context, taskID string, result float64) error {
-	_, err := w.client.SubmitResult(ctx, &proto.TaskResult{
-		Id:     taskID,
-		Result: result,
+func (w *Worker) sendResult(ctx context.Context, result *proto.TaskResult) error {
+	_, err := w.client.SubmitResult(ctx, result)
+	if err != nil {
+		return err
+	}
+	if result.ExactResult != "" {
+		logger.Infof("Send result for task %s: %s", result.Id, result.ExactResult)
+		return nil
+	}
+	logger.Infof("Send result for task %s: %f", result.Id, result.Result)
+	return nil
+}
+
+// reportError tells the orchestrator the task could not be computed, so it
+// fails the expression instead of waiting for the result.
+func (w *Worker) reportError(ctx context.Context, task *proto.Task, err error) error {
+	_, err = w.client.ReportTaskError(ctx, &proto.TaskError{
+		Id:      task.Id,
+		Lease:   task.Lease,
+		Code:    string(errorCode(err)),
+		Message: err.Error(),
 	})
 	if err != nil {
 		return err
 	}
-	logger.Infof("Send result for task %s: %f", taskID, result)
+	logger.Infof("Report error for task %s", task.Id)
 	return nil
 }
+
+// errorCode classifies the error of an operation.
+func errorCode(err error) entities.ErrorCode {
+	switch {
+	case errors.Is(err, functions.ErrDivisionByZero):
+		return entities.ErrorCodeDivisionByZero
+	case errors.Is(err, functions.ErrUnknownOperation), errors.Is(err, functions.ErrUnknownFunction):
+		return entities.ErrorCodeUnknownOperation
+	}
+	return entities.ErrorCodeComputation
+}
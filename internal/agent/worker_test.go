@@ -1,8 +1,11 @@
 package agent
 
 import (
+	"calculator/internal/shared/complexnum"
 	"calculator/proto/calculator/proto"
 	"context"
+	"math"
+	"strings"
 	"testing"
 
 	"google.golang.org/grpc"
@@ -13,6 +16,7 @@ import (
 type mockCalculatorClient struct {
 	getTaskFunc      func(ctx context.Context, in *proto.GetTaskRequest, opts ...grpc.CallOption) (*proto.Task, error)
 	submitResultFunc func(ctx context.Context, in *proto.TaskResult, opts ...grpc.CallOption) (*proto.SubmitResultResponse, error)
+	reportErrorFunc  func(ctx context.Context, in *proto.TaskError, opts ...grpc.CallOption) (*proto.ReportTaskErrorResponse, error)
 }
 
 func (m *mockCalculatorClient) GetTask(ctx context.Context, in *proto.GetTaskRequest, opts ...grpc.CallOption) (*proto.Task, error) {
@@ -23,6 +27,10 @@ func (m *mockCalculatorClient) SubmitResult(ctx context.Context, in *proto.TaskR
 	return m.submitResultFunc(ctx, in, opts...)
 }
 
+func (m *mockCalculatorClient) ReportTaskError(ctx context.Context, in *proto.TaskError, opts ...grpc.CallOption) (*proto.ReportTaskErrorResponse, error) {
+	return m.reportErrorFunc(ctx, in, opts...)
+}
+
 func TestGetTask(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -149,13 +157,148 @@ func TestPerformOperation(t *testing.T) {
 			errMsg: "division by zero",
 		},
 		{
-			name: "unknown operation",
+			name: "comparison",
+			task: &proto.Task{
+				Operation: ">=",
+				Arg1:      3,
+				Arg2:      2,
+			},
+			expected: 1,
+		},
+		{
+			name: "logical and",
+			task: &proto.Task{
+				Operation: "and",
+				Arg1:      1,
+				Arg2:      0,
+			},
+			expected: 0,
+		},
+		{
+			name: "logical not",
+			task: &proto.Task{
+				Operation: "not",
+				Kind:      proto.TaskKind_TASK_KIND_UNARY,
+				Arg1:      0,
+			},
+			expected: 1,
+		},
+		{
+			name: "modulo",
+			task: &proto.Task{
+				Operation: "%",
+				Arg1:      -7,
+				Arg2:      3,
+			},
+			expected: 2,
+		},
+		{
+			name: "modulo by zero",
+			task: &proto.Task{
+				Operation: "%",
+				Arg1:      7,
+				Arg2:      0,
+			},
+			errMsg: "division by zero",
+		},
+		{
+			name: "floor division",
+			task: &proto.Task{
+				Operation: "//",
+				Arg1:      -7,
+				Arg2:      2,
+			},
+			expected: -4,
+		},
+		{
+			name: "floor division of non-finite operand",
+			task: &proto.Task{
+				Operation: "//",
+				Arg1:      math.Inf(1),
+				Arg2:      2,
+			},
+			errMsg: "non-finite operand",
+		},
+		{
+			name: "exponentiation",
 			task: &proto.Task{
 				Operation: "^",
 				Arg1:      2,
 				Arg2:      3,
 			},
-			errMsg: "unknown operation: ^",
+			expected: 8,
+		},
+		{
+			name: "exponentiation overflow",
+			task: &proto.Task{
+				Operation: "^",
+				Arg1:      2,
+				Arg2:      1024,
+			},
+			errMsg: "2 ^ 1024 is not a finite real number",
+		},
+		{
+			name: "fractional power of negative base",
+			task: &proto.Task{
+				Operation: "^",
+				Arg1:      -8,
+				Arg2:      1.0 / 3,
+			},
+			errMsg: "-8 ^ 0.3333333333333333 is not a finite real number",
+		},
+		{
+			name: "negative power of zero",
+			task: &proto.Task{
+				Operation: "^",
+				Arg1:      0,
+				Arg2:      -1,
+			},
+			errMsg: "0 ^ -1 is not a finite real number",
+		},
+		{
+			name: "minimum",
+			task: &proto.Task{
+				Operation: "min",
+				Arg1:      2,
+				Arg2:      -3,
+			},
+			expected: -3,
+		},
+		{
+			name: "maximum",
+			task: &proto.Task{
+				Operation: "max",
+				Arg1:      2,
+				Arg2:      -3,
+			},
+			expected: 2,
+		},
+		{
+			name: "unary function",
+			task: &proto.Task{
+				Operation: "sqrt",
+				Kind:      proto.TaskKind_TASK_KIND_UNARY,
+				Arg1:      9,
+			},
+			expected: 3,
+		},
+		{
+			name: "unary function domain error",
+			task: &proto.Task{
+				Operation: "ln",
+				Kind:      proto.TaskKind_TASK_KIND_UNARY,
+				Arg1:      -1,
+			},
+			errMsg: "logarithm of non-positive number",
+		},
+		{
+			name: "unknown operation",
+			task: &proto.Task{
+				Operation: "?",
+				Arg1:      2,
+				Arg2:      3,
+			},
+			errMsg: "unknown operation: ?",
 		},
 	}
 
@@ -181,6 +324,179 @@ func TestPerformOperation(t *testing.T) {
 	}
 }
 
+func TestPerformExactOperation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		task     *proto.Task
+		expected string
+		errMsg   string
+	}{
+		{
+			name:     "addition keeps decimal digits",
+			task:     &proto.Task{ExactArg1: "0.1", ExactArg2: "0.2", Operation: "+", Scale: 28},
+			expected: "0.3",
+		},
+		{
+			name:     "division is rounded to the scale",
+			task:     &proto.Task{ExactArg1: "2", ExactArg2: "3", Operation: "/", Scale: 5},
+			expected: "0.66667",
+		},
+		{
+			name:     "large integers",
+			task:     &proto.Task{ExactArg1: "12345678901234567890", ExactArg2: "10", Operation: "*", Scale: 28},
+			expected: "123456789012345678900",
+		},
+		{
+			name:     "integer exponent",
+			task:     &proto.Task{ExactArg1: "1.1", ExactArg2: "2", Operation: "^", Scale: 28},
+			expected: "1.21",
+		},
+		{
+			name:     "square root",
+			task:     &proto.Task{ExactArg1: "2", Operation: "sqrt", Kind: proto.TaskKind_TASK_KIND_UNARY, Scale: 10},
+			expected: "1.4142135624",
+		},
+		{
+			name:     "rational addition",
+			task:     &proto.Task{ExactArg1: "1/3", ExactArg2: "1/6", Operation: "+", Mode: proto.Mode_MODE_RATIONAL},
+			expected: "1/2",
+		},
+		{
+			name:     "rational division",
+			task:     &proto.Task{ExactArg1: "0.1", ExactArg2: "3", Operation: "/", Mode: proto.Mode_MODE_RATIONAL},
+			expected: "1/30",
+		},
+		{
+			name:     "rational integer result",
+			task:     &proto.Task{ExactArg1: "2/3", ExactArg2: "3/2", Operation: "*", Mode: proto.Mode_MODE_RATIONAL},
+			expected: "1",
+		},
+		{
+			name:   "rational square root",
+			task:   &proto.Task{ExactArg1: "2", Operation: "sqrt", Kind: proto.TaskKind_TASK_KIND_UNARY, Mode: proto.Mode_MODE_RATIONAL},
+			errMsg: "function sqrt has no exact result",
+		},
+		{
+			name:   "rational power with a huge denominator",
+			task:   &proto.Task{ExactArg1: "1/" + strings.Repeat("9", 100), ExactArg2: "4000", Operation: "^", Mode: proto.Mode_MODE_RATIONAL},
+			errMsg: "result too large",
+		},
+		{
+			name:   "division by zero",
+			task:   &proto.Task{ExactArg1: "1", ExactArg2: "0", Operation: "/", Scale: 28},
+			errMsg: "division by zero",
+		},
+		{
+			name:   "non-integer exponent",
+			task:   &proto.Task{ExactArg1: "2", ExactArg2: "0.5", Operation: "^", Scale: 28},
+			errMsg: "non-integer exponent",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			worker := &Worker{}
+			result, err := worker.performExactOperation(tc.task)
+
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if err.Error() != tc.errMsg {
+					t.Errorf("Expected error %q, got %q", tc.errMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestComputeRational(t *testing.T) {
+	worker := &Worker{}
+	result, err := worker.compute(&proto.Task{Id: "task1", ExactArg1: "1", ExactArg2: "3", Operation: "/", Mode: proto.Mode_MODE_RATIONAL, Lease: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.ExactResult != "1/3" {
+		t.Errorf("Expected 1/3, got %s", result.ExactResult)
+	}
+	if result.Lease != 2 {
+		t.Errorf("Expected the result of lease 2, got %d", result.Lease)
+	}
+	if math.Abs(result.Result-1.0/3) > 1e-15 {
+		t.Errorf("Expected float approximation of 1/3, got %f", result.Result)
+	}
+}
+
+func TestComputeComplex(t *testing.T) {
+	testCases := []struct {
+		name     string
+		task     *proto.Task
+		expected string
+		errMsg   string
+	}{
+		{
+			name:     "addition",
+			task:     &proto.Task{Arg1: 3, ImagArg1: 4, Arg2: 1, ImagArg2: -2, Operation: "+"},
+			expected: "4+2i",
+		},
+		{
+			name:     "subtraction",
+			task:     &proto.Task{Arg1: 3, ImagArg1: 4, Arg2: 3, ImagArg2: 1, Operation: "-"},
+			expected: "3i",
+		},
+		{
+			name:     "multiplication",
+			task:     &proto.Task{ImagArg1: 2, ImagArg2: 2, Operation: "*"},
+			expected: "-4",
+		},
+		{
+			name:     "division",
+			task:     &proto.Task{Arg1: 3, ImagArg1: 4, Arg2: 1, ImagArg2: 2, Operation: "/"},
+			expected: "2.2-0.4i",
+		},
+		{
+			name:     "conjugate",
+			task:     &proto.Task{Arg1: 3, ImagArg1: 4, Operation: "conj", Kind: proto.TaskKind_TASK_KIND_UNARY},
+			expected: "3-4i",
+		},
+		{
+			name:   "division by zero",
+			task:   &proto.Task{ImagArg1: 1, Operation: "/"},
+			errMsg: "division by zero",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			worker := &Worker{}
+			tc.task.Mode = proto.Mode_MODE_COMPLEX
+			result, err := worker.compute(tc.task)
+
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Errorf("Expected error %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.ExactResult != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, result.ExactResult)
+			}
+			if z, _ := complexnum.Parse(tc.expected); complex(result.Result, result.ImagResult) != z {
+				t.Errorf("Expected parts of %s, got %f and %f", tc.expected, result.Result, result.ImagResult)
+			}
+		})
+	}
+}
+
 func TestSendResult(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -214,7 +530,7 @@ func TestSendResult(t *testing.T) {
 			}
 
 			ctx := context.Background()
-			err := worker.sendResult(ctx, tc.taskID, tc.result)
+			err := worker.sendResult(ctx, &proto.TaskResult{Id: tc.taskID, Result: tc.result})
 
 			if tc.expectedErr != "" {
 				if err == nil {
@@ -228,3 +544,79 @@ func TestSendResult(t *testing.T) {
 		})
 	}
 }
+
+func TestReportError(t *testing.T) {
+	testCases := []struct {
+		name    string
+		task    *proto.Task
+		code    string
+		message string
+	}{
+		{
+			name:    "division by zero",
+			task:    &proto.Task{Id: "t1", Arg1: 1, Arg2: 0, Operation: "/", Lease: 2},
+			code:    "division_by_zero",
+			message: "division by zero",
+		},
+		{
+			name:    "exact division by zero",
+			task:    &proto.Task{Id: "t2", ExactArg1: "1", ExactArg2: "0", Operation: "/", Mode: proto.Mode_MODE_RATIONAL, Lease: 1},
+			code:    "division_by_zero",
+			message: "division by zero",
+		},
+		{
+			name:    "unknown operation",
+			task:    &proto.Task{Id: "t3", Arg1: 1, Arg2: 2, Operation: "?", Lease: 1},
+			code:    "unknown_operation",
+			message: "unknown operation: ?",
+		},
+		{
+			name:    "other error",
+			task:    &proto.Task{Id: "t4", Arg1: -1, Operation: "sqrt", Kind: proto.TaskKind_TASK_KIND_UNARY, Lease: 1},
+			code:    "computation_error",
+			message: "square root of negative number",
+		},
+		{
+			name:    "power overflow",
+			task:    &proto.Task{Id: "t5", Arg1: 2, Arg2: 1024, Operation: "^", Lease: 1},
+			code:    "computation_error",
+			message: "2 ^ 1024 is not a finite real number",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var reported *proto.TaskError
+			worker := &Worker{
+				client: &mockCalculatorClient{
+					getTaskFunc: func(ctx context.Context, in *proto.GetTaskRequest, opts ...grpc.CallOption) (*proto.Task, error) {
+						return tc.task, nil
+					},
+					submitResultFunc: func(ctx context.Context, in *proto.TaskResult, opts ...grpc.CallOption) (*proto.SubmitResultResponse, error) {
+						t.Errorf("Unexpected result %v", in)
+						return &proto.SubmitResultResponse{}, nil
+					},
+					reportErrorFunc: func(ctx context.Context, in *proto.TaskError, opts ...grpc.CallOption) (*proto.ReportTaskErrorResponse, error) {
+						reported = in
+						return &proto.ReportTaskErrorResponse{}, nil
+					},
+				},
+			}
+
+			worker.doWork(context.Background())
+
+			if reported == nil {
+				t.Fatal("Expected the error to be reported")
+			}
+			if reported.Id != tc.task.Id || reported.Lease != tc.task.Lease {
+				t.Errorf("Expected task %s with lease %d, got %s with lease %d", tc.task.Id, tc.task.Lease, reported.Id, reported.Lease)
+			}
+			if reported.Code != tc.code {
+				t.Errorf("Expected code %q, got %q", tc.code, reported.Code)
+			}
+			if reported.Message != tc.message {
+				t.Errorf("Expected message %q, got %q", tc.message, reported.Message)
+			}
+		})
+	}
+}
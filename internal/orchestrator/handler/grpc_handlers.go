@@ -1,9 +1,15 @@
 package handler
 
 import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
 	"calculator/internal/orchestrator/use_cases/scheduler"
+	"calculator/internal/shared/entities"
 	"calculator/proto/calculator/proto"
 	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type GRPCHandler struct {
@@ -28,14 +34,60 @@ func (h *GRPCHandler) GetTask(ctx context.Context, req *proto.GetTaskRequest) (*
 		Arg1:          task.Arg1,
 		Arg2:          task.Arg2,
 		Operation:     task.Operation,
+		Kind:          proto.TaskKind(task.Kind),
+		Mode:          modeToProto(task.Mode),
+		Scale:         int32(task.Scale),
+		ExactArg1:     task.ExactArg1,
+		ExactArg2:     task.ExactArg2,
+		ImagArg1:      task.Imag1,
+		ImagArg2:      task.Imag2,
 		OperationTime: int64(task.OperationTime),
+		Lease:         task.Lease,
 	}, nil
 }
 
 func (h *GRPCHandler) SubmitResult(ctx context.Context, result *proto.TaskResult) (*proto.SubmitResultResponse, error) {
-	err := h.scheduler.ProcessResult(result.Id, result.Result)
+	err := h.scheduler.ProcessResult(entities.TaskResult{
+		ID:     result.Id,
+		Result: result.Result,
+		Imag:   result.ImagResult,
+		Exact:  result.ExactResult,
+		Lease:  result.Lease,
+	})
+	if errors.Is(err, use_cases_errors.ErrLeaseExpired) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &proto.SubmitResultResponse{}, nil
 }
+
+func (h *GRPCHandler) ReportTaskError(ctx context.Context, taskError *proto.TaskError) (*proto.ReportTaskErrorResponse, error) {
+	err := h.scheduler.ProcessError(entities.TaskError{
+		ID:      taskError.Id,
+		Lease:   taskError.Lease,
+		Code:    entities.ErrorCode(taskError.Code),
+		Message: taskError.Message,
+	})
+	if errors.Is(err, use_cases_errors.ErrLeaseExpired) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ReportTaskErrorResponse{}, nil
+}
+
+func modeToProto(mode entities.Mode) proto.Mode {
+	switch mode {
+	case entities.ModeDecimal:
+		return proto.Mode_MODE_DECIMAL
+	case entities.ModeRational:
+		return proto.Mode_MODE_RATIONAL
+	case entities.ModeComplex:
+		return proto.Mode_MODE_COMPLEX
+	default:
+		return proto.Mode_MODE_FLOAT
+	}
+}
@@ -2,14 +2,21 @@ package handler
 
 import (
 	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/orchestrator/use_cases/parser"
 	"calculator/internal/orchestrator/use_cases/scheduler"
 	"calculator/internal/shared/entities"
 	"calculator/pkg/logger"
 	"calculator/pkg/utils"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 )
 
+// maxBodyBytes bounds the body of a request, the expression and its variables.
+// The length of the expression itself is bounded by the scheduler.
+const maxBodyBytes = 1 << 20
+
 // Handler represents the HTTP handler for the orchestrator.
 type Handler struct {
 	scheduler *scheduler.Scheduler
@@ -22,23 +29,60 @@ func NewHandler(scheduler *scheduler.Scheduler) *Handler {
 	}
 }
 
+// calculateRequest is the body of the request to calculate an expression.
+// It holds only the fields a client sets, the ones the orchestrator computes,
+// like the result, the bindings or the unit, cannot be sent.
+type calculateRequest struct {
+	ID          string             `json:"id"`
+	Expression  string             `json:"expression"`
+	Variables   map[string]float64 `json:"variables"`
+	Mode        entities.Mode      `json:"mode"`
+	Scale       int                `json:"scale"`
+	AngleUnit   entities.AngleUnit `json:"angleUnit"`
+	Notation    entities.Notation  `json:"notation"`
+	Dialect     entities.Dialect   `json:"dialect"`
+	Locale      string             `json:"locale"`
+	To          string             `json:"to"`
+	StrictOrder bool               `json:"strictOrder"`
+	Priority    int                `json:"priority"`
+}
+
+// expression makes the expression to schedule of the request.
+func (req *calculateRequest) expression() *entities.Expression {
+	return &entities.Expression{
+		ID:          req.ID,
+		Expression:  req.Expression,
+		Variables:   req.Variables,
+		Mode:        req.Mode,
+		Scale:       req.Scale,
+		AngleUnit:   req.AngleUnit,
+		Notation:    req.Notation,
+		Dialect:     req.Dialect,
+		Locale:      req.Locale,
+		To:          req.To,
+		StrictOrder: req.StrictOrder,
+		Priority:    req.Priority,
+	}
+}
+
 // HandleCalculate handles the request to calculate an arithmetic expression.
 func (h *Handler) HandleCalculate(w http.ResponseWriter, r *http.Request) {
-	var expr entities.Expression
-	err := json.NewDecoder(r.Body).Decode(&expr)
+	var req calculateRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req)
 	if err != nil {
 		logger.Errorf("Failed to decode request body: %v", err)
-		if err = utils.RespondWith422(w); err != nil {
+		if err = respondWithDecodeError(w, err); err != nil {
 			logger.Error(err)
 		}
 		return
 	}
 	defer r.Body.Close()
 
-	err = h.scheduler.ScheduleExpression(expr.ID, expr.Expression)
+	expr := req.expression()
+	err = h.scheduler.ScheduleExpression(expr)
 	if err != nil {
 		logger.Errorf("Failed to schedule expression: %v", err)
-		if err = utils.RespondWith500(w); err != nil {
+		if err = respondWithScheduleError(w, err); err != nil {
 			logger.Error(err)
 		}
 		return
@@ -51,6 +95,82 @@ func (h *Handler) HandleCalculate(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// functionRequest is the body of the request to define a function.
+type functionRequest struct {
+	Definition string `json:"definition"`
+}
+
+// HandleDefineFunction handles the request to define a function like f(x, y) = x^2 + y.
+func (h *Handler) HandleDefineFunction(w http.ResponseWriter, r *http.Request) {
+	var req functionRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req)
+	if err != nil {
+		logger.Errorf("Failed to decode request body: %v", err)
+		if err = respondWithDecodeError(w, err); err != nil {
+			logger.Error(err)
+		}
+		return
+	}
+	defer r.Body.Close()
+
+	fn, err := h.scheduler.DefineFunction(req.Definition)
+	if err != nil {
+		logger.Errorf("Failed to define function: %v", err)
+		if err = respondWithScheduleError(w, err); err != nil {
+			logger.Error(err)
+		}
+		return
+	}
+	logger.Infof("Define function: %s", fn.Definition)
+
+	if err = utils.SuccessRepondWith201(w, fn); err != nil {
+		logger.Error(err)
+	}
+}
+
+// syntaxErrorResponse is the body of the response to an expression with syntax errors.
+// Details let the client point to the offending parts of the expression.
+type syntaxErrorResponse struct {
+	Error   string              `json:"error"`
+	Details parser.SyntaxErrors `json:"details"`
+}
+
+// respondWithDecodeError maps the error of decoding a request body to a response.
+func respondWithDecodeError(w http.ResponseWriter, err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return utils.RespondWith413(w, fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit))
+	}
+	return utils.RespondWith422(w)
+}
+
+// respondWithScheduleError maps the error of scheduling an expression to a response.
+func respondWithScheduleError(w http.ResponseWriter, err error) error {
+	var syntaxErrors parser.SyntaxErrors
+	switch {
+	case errors.As(err, &syntaxErrors):
+		return utils.RespondWithJSON(w, http.StatusBadRequest, syntaxErrorResponse{
+			Error:   err.Error(),
+			Details: syntaxErrors,
+		})
+	case errors.Is(err, use_cases_errors.ErrInvalidAngleUnit),
+		errors.Is(err, use_cases_errors.ErrInvalidPrecision),
+		errors.Is(err, use_cases_errors.ErrInvalidUnit),
+		errors.Is(err, use_cases_errors.ErrInvalidDialect),
+		errors.Is(err, use_cases_errors.ErrInvalidNotation),
+		errors.Is(err, use_cases_errors.ErrInvalidPriority):
+		return utils.RespondWith400(w, err.Error())
+	case errors.Is(err, use_cases_errors.ErrExpressionTooLarge):
+		return utils.RespondWith413(w, err.Error())
+	case errors.Is(err, use_cases_errors.ErrExpressionTooComplex):
+		return utils.RespondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, use_cases_errors.ErrExpressionExists):
+		return utils.RespondWith409(w, err.Error())
+	default:
+		return utils.RespondWith500(w)
+	}
+}
+
 // HandleGetExpressions handles the request to get a list of expressions.
 func (h *Handler) HandleGetExpressions(w http.ResponseWriter, r *http.Request) {
 	expressions, err := h.scheduler.GetExpressions()
@@ -75,7 +195,13 @@ func (h *Handler) HandleGetExpressions(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) HandleGetExpression(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
-	expr, err := h.scheduler.GetExpression(id)
+	var expr *entities.Expression
+	var err error
+	if notation := r.URL.Query().Get("notation"); notation != "" {
+		expr, err = h.scheduler.RenderExpression(id, entities.Notation(notation))
+	} else {
+		expr, err = h.scheduler.GetExpression(id)
+	}
 	if err != nil {
 		if err == use_cases_errors.ErrExpressionNotFound {
 			if err = utils.RespondWith404(w); err != nil {
@@ -83,6 +209,12 @@ func (h *Handler) HandleGetExpression(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
+		if errors.Is(err, use_cases_errors.ErrInvalidNotation) {
+			if err = utils.RespondWith400(w, err.Error()); err != nil {
+				logger.Error(err)
+			}
+			return
+		}
 		logger.Errorf("Failed to get expression: %v", err)
 		if err = utils.RespondWith500(w); err != nil {
 			logger.Error(err)
@@ -98,3 +230,47 @@ func (h *Handler) HandleGetExpression(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+// HandleCancelExpression handles the request to cancel an expression.
+func (h *Handler) HandleCancelExpression(w http.ResponseWriter, r *http.Request) {
+	h.controlExpression(w, r, h.scheduler.CancelExpression)
+}
+
+// HandlePauseExpression handles the request to pause an expression.
+func (h *Handler) HandlePauseExpression(w http.ResponseWriter, r *http.Request) {
+	h.controlExpression(w, r, h.scheduler.PauseExpression)
+}
+
+// HandleResumeExpression handles the request to resume a paused expression.
+func (h *Handler) HandleResumeExpression(w http.ResponseWriter, r *http.Request) {
+	h.controlExpression(w, r, h.scheduler.ResumeExpression)
+}
+
+// controlExpression applies the action to the expression of the request and
+// responds with the expression. An expression the action is not allowed for
+// in its status is a conflict.
+func (h *Handler) controlExpression(w http.ResponseWriter, r *http.Request, action func(id string) (*entities.Expression, error)) {
+	expr, err := action(r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, use_cases_errors.ErrExpressionNotFound):
+			err = utils.RespondWith404(w)
+		case errors.Is(err, use_cases_errors.ErrInvalidStatus):
+			err = utils.RespondWith409(w, err.Error())
+		default:
+			logger.Errorf("Failed to change expression: %v", err)
+			err = utils.RespondWith500(w)
+		}
+		if err != nil {
+			logger.Error(err)
+		}
+		return
+	}
+
+	logger.Infof("Expression %s is %s", expr.ID, expr.Status)
+
+	ex := map[string]entities.Expression{"expression": *expr}
+	if err = utils.SuccessRespondWith200(w, ex); err != nil {
+		logger.Error(err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"calculator/internal/orchestrator/impl/memory_task_storage"
 	"calculator/internal/orchestrator/use_cases/scheduler"
 	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -98,3 +100,475 @@ func TestHandleCalculate_DecodeError(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, rr.Code)
 	}
 }
+
+func TestHandleCalculate_Variables(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "bound variables",
+			body:         `{"id": "1", "expression": "price * qty * (1 - discount)", "variables": {"price": 9.5, "qty": 3, "discount": 0.1}}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "unbound variables",
+			body:         `{"id": "1", "expression": "price * qty", "variables": {"price": 9.5}}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/calculate", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			rr := httptest.NewRecorder()
+
+			storage := memory_expression_storage.NewStorage()
+			handler := &Handler{
+				scheduler: scheduler.NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{}),
+			}
+
+			handler.HandleCalculate(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, rr.Code)
+			}
+			if tc.expectedCode != http.StatusCreated {
+				return
+			}
+
+			expr, err := storage.GetExpression("1")
+			if err != nil {
+				t.Fatalf("Expected expression to be stored, got %v", err)
+			}
+			if expr.Variables["qty"] != 3 {
+				t.Errorf("Expected variables to be stored, got %v", expr.Variables)
+			}
+		})
+	}
+}
+
+func TestHandleCalculate_Precision(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "decimal mode",
+			body:         `{"id": "1", "expression": "0.1 + 0.2", "mode": "decimal", "scale": 10}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "rational mode",
+			body:         `{"id": "1", "expression": "1/3 + 1/6", "mode": "rational"}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "scale in rational mode",
+			body:         `{"id": "1", "expression": "1/3 + 1/6", "mode": "rational", "scale": 10}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "square root in rational mode",
+			body:         `{"id": "1", "expression": "sqrt(2)", "mode": "rational"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown mode",
+			body:         `{"id": "1", "expression": "0.1 + 0.2", "mode": "binary"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "scale too large",
+			body:         `{"id": "1", "expression": "0.1 + 0.2", "mode": "decimal", "scale": 100000}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "scale in float mode",
+			body:         `{"id": "1", "expression": "0.1 + 0.2", "scale": 10}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "function without exact result",
+			body:         `{"id": "1", "expression": "sin(1)", "mode": "decimal"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown unit of the result",
+			body:         `{"id": "1", "expression": "0.1 + 0.2", "mode": "decimal", "to": "parsec"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/calculate", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			rr := httptest.NewRecorder()
+
+			handler := &Handler{
+				scheduler: scheduler.NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{}),
+			}
+
+			handler.HandleCalculate(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleCalculate_SyntaxError(t *testing.T) {
+	reqBody := strings.NewReader(`{"id": "1", "expression": "2 + * 3 $"}`)
+	req, err := http.NewRequest("POST", "/calculate", reqBody)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+
+	handler := &Handler{
+		scheduler: scheduler.NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{}),
+	}
+
+	handler.HandleCalculate(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	var body syntaxErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(body.Details) != 1 {
+		t.Fatalf("Expected 1 error, got %v", body.Details)
+	}
+	if body.Details[0].Offset != 8 || body.Details[0].Length != 1 || body.Details[0].Message != "unexpected character: $" {
+		t.Errorf("Unexpected error details: %+v", body.Details[0])
+	}
+}
+
+func TestHandleCalculate_Exists(t *testing.T) {
+	handler := &Handler{
+		scheduler: scheduler.NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{}),
+	}
+
+	for i, expectedCode := range []int{http.StatusCreated, http.StatusConflict} {
+		req, err := http.NewRequest("POST", "/calculate", strings.NewReader(`{"id": "1", "expression": "2+2"}`))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		rr := httptest.NewRecorder()
+
+		handler.HandleCalculate(rr, req)
+
+		if rr.Code != expectedCode {
+			t.Errorf("Request %d: expected status code %d, got %d", i+1, expectedCode, rr.Code)
+		}
+	}
+}
+
+func TestHandleCalculate_OutputFields(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	handler := &Handler{
+		scheduler: scheduler.NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{}),
+	}
+
+	// the fields the orchestrator computes are not taken from the client
+	body := `{"id": "1", "expression": "2+2", "bindings": {"injected": 42}, "exactBindings": {"injected": "42"}, "unit": "m", "tasksSaved": 5, "depth": 9, "status": "completed", "result": 7}`
+	req, err := http.NewRequest("POST", "/calculate", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+
+	handler.HandleCalculate(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rr.Code)
+	}
+	expr, err := storage.GetExpression("1")
+	if err != nil {
+		t.Fatalf("GetExpression returned error: %v", err)
+	}
+	if expr.Bindings != nil || expr.ExactBindings != nil || expr.Unit != "" || expr.TasksSaved != 0 || expr.Depth != 1 ||
+		expr.Status != entities.ExpressionStatusPending || expr.Result != 0 {
+		t.Errorf("Expected a pending expression without the fields sent by the client, got %+v", expr)
+	}
+}
+
+func TestHandleDefineFunction(t *testing.T) {
+	handler := &Handler{
+		scheduler: scheduler.NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{MaxExpansionNodes: 100}),
+	}
+
+	testCases := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "valid definition",
+			body:         `{"definition": "f(x,y)=(x^2)+y"}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"name":"f","params":["x","y"],"definition":"f(x, y) = x^2 + y"}`,
+		},
+		{
+			name:         "invalid JSON",
+			body:         `{"definition": `,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "not a definition",
+			body:         `{"definition": "2 + 2"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "recursive definition",
+			body:         `{"definition": "g(x) = g(x - 1)"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "call of a defined function",
+			body:         `{"definition": "h(x) = f(x, 1) * 2"}`,
+			expectedCode: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/functions", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.HandleDefineFunction(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, rr.Code)
+			}
+			if tc.expectedBody != "" && strings.TrimSpace(rr.Body.String()) != tc.expectedBody {
+				t.Errorf("Expected body %s, got %s", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleGetExpression_Notation(t *testing.T) {
+	handler := &Handler{
+		scheduler: scheduler.NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{}),
+	}
+	req, err := http.NewRequest("POST", "/calculate", strings.NewReader(`{"id": "1", "expression": "3 4 + 2 *", "notation": "rpn"}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	handler.HandleCalculate(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rr.Code)
+	}
+
+	testCases := []struct {
+		query              string
+		expectedCode       int
+		expectedExpression string
+	}{
+		{query: "", expectedCode: http.StatusOK, expectedExpression: "3 4 + 2 *"},
+		{query: "?notation=infix", expectedCode: http.StatusOK, expectedExpression: "(3 + 4) * 2"},
+		{query: "?notation=prefix", expectedCode: http.StatusOK, expectedExpression: "(* (+ 3 4) 2)"},
+		{query: "?notation=postfix", expectedCode: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/expressions/1"+tc.query, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.SetPathValue("id", "1")
+			rr := httptest.NewRecorder()
+
+			handler.HandleGetExpression(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedCode, rr.Code)
+			}
+			if tc.expectedExpression == "" {
+				return
+			}
+			var body struct {
+				Expression struct {
+					Expression string `json:"expression"`
+				} `json:"expression"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body.Expression.Expression != tc.expectedExpression {
+				t.Errorf("Expected expression %q, got %q", tc.expectedExpression, body.Expression.Expression)
+			}
+		})
+	}
+}
+
+func TestHandleGetExpression_Failed(t *testing.T) {
+	handler := &Handler{
+		scheduler: scheduler.NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{}),
+	}
+	req, err := http.NewRequest("POST", "/calculate", strings.NewReader(`{"id": "1", "expression": "1 / x", "variables": {"x": 0}}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	handler.HandleCalculate(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rr.Code)
+	}
+	task, err := handler.scheduler.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+	err = handler.scheduler.ProcessError(entities.TaskError{ID: task.ID, Lease: task.Lease, Code: entities.ErrorCodeDivisionByZero, Message: "division by zero"})
+	if err != nil {
+		t.Fatalf("ProcessError returned error: %v", err)
+	}
+
+	req, err = http.NewRequest("GET", "/expressions/1", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.SetPathValue("id", "1")
+	rr = httptest.NewRecorder()
+	handler.HandleGetExpression(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	var body struct {
+		Expression struct {
+			Status string                   `json:"status"`
+			Error  entities.ExpressionError `json:"error"`
+		} `json:"expression"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	expected := entities.ExpressionError{Code: "division_by_zero", Message: "division by zero", TaskID: task.ID}
+	if body.Expression.Status != "failed" || body.Expression.Error != expected {
+		t.Errorf("Expected failed expression with error %+v, got %+v", expected, body.Expression)
+	}
+}
+
+func TestHandleCalculate_Limits(t *testing.T) {
+	handler := &Handler{
+		scheduler: scheduler.NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(),
+			&configs.Config{MaxExpressionLength: 100, MaxNestingDepth: 10}),
+	}
+
+	testCases := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "body too large",
+			body:         `{"id": "1", "expression": "` + strings.Repeat(" ", maxBodyBytes) + `1"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "expression too long",
+			body:         `{"id": "2", "expression": "` + strings.Repeat("1+", 100) + `1"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "expression nested too deep",
+			body:         `{"id": "3", "expression": "` + strings.Repeat("-", 20) + `1"}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/calculate", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.HandleCalculate(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d: %s", tc.expectedCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleControlExpression(t *testing.T) {
+	handler := &Handler{
+		scheduler: scheduler.NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{}),
+	}
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	req, err := http.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"id": "1", "expression": "x + 2", "variables": {"x": 1}}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rr.Code)
+	}
+
+	testCases := []struct {
+		method         string
+		path           string
+		expectedCode   int
+		expectedStatus string
+	}{
+		{method: "POST", path: "/api/v1/expressions/1/pause", expectedCode: http.StatusOK, expectedStatus: "paused"},
+		{method: "POST", path: "/api/v1/expressions/1/pause", expectedCode: http.StatusConflict},
+		{method: "POST", path: "/api/v1/expressions/1/resume", expectedCode: http.StatusOK, expectedStatus: "pending"},
+		{method: "DELETE", path: "/api/v1/expressions/1", expectedCode: http.StatusOK, expectedStatus: "cancelled"},
+		{method: "DELETE", path: "/api/v1/expressions/1", expectedCode: http.StatusConflict},
+		{method: "POST", path: "/api/v1/expressions/1/resume", expectedCode: http.StatusConflict},
+		{method: "DELETE", path: "/api/v1/expressions/2", expectedCode: http.StatusNotFound},
+		{method: "POST", path: "/api/v1/expressions/2/pause", expectedCode: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(tc.method, tc.path, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tc.expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d", tc.method, tc.path, tc.expectedCode, rr.Code)
+		}
+		if tc.expectedStatus == "" {
+			continue
+		}
+		var body struct {
+			Expression struct {
+				Status string `json:"status"`
+			} `json:"expression"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if body.Expression.Status != tc.expectedStatus {
+			t.Errorf("%s %s: expected status %q, got %q", tc.method, tc.path, tc.expectedStatus, body.Expression.Status)
+		}
+	}
+}
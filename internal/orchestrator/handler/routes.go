@@ -8,6 +8,10 @@ import (
 func (h *Handler) RegisterRoutes(r *http.ServeMux) {
 	//api
 	r.HandleFunc("POST /api/v1/calculate", h.HandleCalculate)
+	r.HandleFunc("POST /api/v1/functions", h.HandleDefineFunction)
 	r.HandleFunc("GET /api/v1/expressions/", h.HandleGetExpressions)
 	r.HandleFunc("GET /api/v1/expressions/{id}/", h.HandleGetExpression)
+	r.HandleFunc("DELETE /api/v1/expressions/{id}", h.HandleCancelExpression)
+	r.HandleFunc("POST /api/v1/expressions/{id}/pause", h.HandlePauseExpression)
+	r.HandleFunc("POST /api/v1/expressions/{id}/resume", h.HandleResumeExpression)
 }
@@ -3,13 +3,16 @@ package memory_expression_storage
 import (
 	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
 	"calculator/internal/shared/entities"
+	"maps"
 	"slices"
+	"strings"
 	"sync"
 )
 
 // Storage represents a simple in-memory storage for arithmetic expressions.
 type Storage struct {
 	expressions map[string]*entities.Expression
+	functions   map[string]entities.Function
 	mu          sync.RWMutex
 }
 
@@ -17,27 +20,43 @@ type Storage struct {
 func NewStorage() *Storage {
 	return &Storage{
 		expressions: make(map[string]*entities.Expression),
+		functions:   make(map[string]entities.Function),
 	}
 }
 
 // CreateExpression creates a new arithmetic expression.
-func (s *Storage) CreateExpression(id string, expr string) error {
+func (s *Storage) CreateExpression(expr *entities.Expression) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.expressions[id]; ok {
+	if _, ok := s.expressions[expr.ID]; ok {
 		return use_cases_errors.ErrExpressionExists
 	}
 
-	s.expressions[id] = &entities.Expression{
-		ID:         id,
-		Expression: expr,
-		Status:     entities.ExpressionStatusPending,
+	s.expressions[expr.ID] = &entities.Expression{
+		ID:            expr.ID,
+		Expression:    expr.Expression,
+		Variables:     maps.Clone(expr.Variables),
+		AngleUnit:     expr.AngleUnit,
+		Notation:      expr.Notation,
+		Dialect:       expr.Dialect,
+		Locale:        expr.Locale,
+		Mode:          expr.Mode,
+		Scale:         expr.Scale,
+		TasksSaved:    expr.TasksSaved,
+		StrictOrder:   expr.StrictOrder,
+		Priority:      expr.Priority,
+		Depth:         expr.Depth,
+		To:            expr.To,
+		Unit:          expr.Unit,
+		Status:        entities.ExpressionStatusPending,
+		Bindings:      maps.Clone(expr.Bindings),
+		ExactBindings: maps.Clone(expr.ExactBindings),
 	}
 	return nil
 }
 
-// GetExpression retrieves an arithmetic expression by its ID.
+// GetExpression retrieves a copy of an arithmetic expression by its ID.
 func (s *Storage) GetExpression(id string) (*entities.Expression, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -47,17 +66,32 @@ func (s *Storage) GetExpression(id string) (*entities.Expression, error) {
 		return nil, use_cases_errors.ErrExpressionNotFound
 	}
 
-	return expr, nil
+	copied := copyExpression(expr)
+	return &copied, nil
 }
 
-// GetExpressions retrieves all arithmetic expressions.
+// copyExpression copies the expression with its maps, so the copy is read
+// without the lock while the results of its tasks update the stored one.
+func copyExpression(expr *entities.Expression) entities.Expression {
+	copied := *expr
+	copied.Variables = maps.Clone(expr.Variables)
+	copied.Bindings = maps.Clone(expr.Bindings)
+	copied.ExactBindings = maps.Clone(expr.ExactBindings)
+	if expr.Error != nil {
+		exprError := *expr.Error
+		copied.Error = &exprError
+	}
+	return copied
+}
+
+// GetExpressions retrieves copies of all arithmetic expressions.
 func (s *Storage) GetExpressions() ([]entities.Expression, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	expressions := make([]entities.Expression, 0, len(s.expressions))
 	for _, expr := range s.expressions {
-		expressions = append(expressions, *expr)
+		expressions = append(expressions, copyExpression(expr))
 	}
 
 	slices.SortFunc(expressions, func(a, b entities.Expression) int {
@@ -72,8 +106,9 @@ func (s *Storage) GetExpressions() ([]entities.Expression, error) {
 	return expressions, nil
 }
 
-// UpdateExpression updates the status and result of an arithmetic expression.
-func (s *Storage) UpdateExpression(id string, status entities.ExpressionStatus, result float64) error {
+// UpdateExpression updates the status and result of an arithmetic expression
+// that is not completed, failed or cancelled yet.
+func (s *Storage) UpdateExpression(id string, status entities.ExpressionStatus, result entities.TaskResult) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -82,8 +117,107 @@ func (s *Storage) UpdateExpression(id string, status entities.ExpressionStatus,
 		return use_cases_errors.ErrExpressionNotFound
 	}
 
-	expr.Result = result
+	if expr.Status.IsFinal() {
+		return nil
+	}
+	expr.Result = result.Result
+	expr.Imag = result.Imag
+	expr.ExactResult = result.Exact
+	expr.Status = status
+
+	return nil
+}
+
+// ChangeStatus sets the status of the expression if it has one of the
+// statuses from. It reports whether the status was changed.
+func (s *Storage) ChangeStatus(id string, status entities.ExpressionStatus, from ...entities.ExpressionStatus) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expr, ok := s.expressions[id]
+	if !ok {
+		return false, use_cases_errors.ErrExpressionNotFound
+	}
+
+	if !slices.Contains(from, expr.Status) {
+		return false, nil
+	}
 	expr.Status = status
 
+	return true, nil
+}
+
+// FailExpression marks the expression failed with the error of its task,
+// unless it is completed, failed or cancelled already.
+func (s *Storage) FailExpression(id string, exprError entities.ExpressionError) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expr, ok := s.expressions[id]
+	if !ok {
+		return use_cases_errors.ErrExpressionNotFound
+	}
+
+	if expr.Status.IsFinal() {
+		return nil
+	}
+	expr.Status = entities.ExpressionStatusFailed
+	expr.Error = &exprError
+
 	return nil
 }
+
+// UpdateBindings records the result as the value of the names assigned in the expression.
+func (s *Storage) UpdateBindings(id string, names []string, result entities.TaskResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expr, ok := s.expressions[id]
+	if !ok {
+		return use_cases_errors.ErrExpressionNotFound
+	}
+
+	if expr.Bindings == nil {
+		expr.Bindings = make(map[string]float64, len(names))
+	}
+	for _, name := range names {
+		expr.Bindings[name] = result.Result
+		if result.Exact == "" {
+			continue
+		}
+		if expr.ExactBindings == nil {
+			expr.ExactBindings = make(map[string]string, len(names))
+		}
+		expr.ExactBindings[name] = result.Exact
+	}
+
+	return nil
+}
+
+// SaveFunction stores the user-defined function, replacing the function with the same name.
+func (s *Storage) SaveFunction(fn *entities.Function) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.functions[fn.Name] = entities.Function{
+		Name:       fn.Name,
+		Params:     slices.Clone(fn.Params),
+		Definition: fn.Definition,
+	}
+	return nil
+}
+
+// GetFunctions returns the stored user-defined functions ordered by name.
+func (s *Storage) GetFunctions() ([]entities.Function, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	functions := make([]entities.Function, 0, len(s.functions))
+	for _, fn := range s.functions {
+		functions = append(functions, fn)
+	}
+	slices.SortFunc(functions, func(a, b entities.Function) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return functions, nil
+}
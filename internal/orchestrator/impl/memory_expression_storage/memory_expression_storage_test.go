@@ -3,6 +3,8 @@ package memory_expression_storage
 import (
 	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
 	"calculator/internal/shared/entities"
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -14,7 +16,7 @@ func TestCreateExpression(t *testing.T) {
 		id := "1"
 		expr := "2+2"
 
-		err := storage.CreateExpression(id, expr)
+		err := storage.CreateExpression(&entities.Expression{ID: id, Expression: expr})
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
@@ -32,13 +34,13 @@ func TestCreateExpression(t *testing.T) {
 		expr := "2+2"
 
 		// Create the expression for the first time
-		err := storage.CreateExpression(id, expr)
+		err := storage.CreateExpression(&entities.Expression{ID: id, Expression: expr})
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
 
 		// Try to create the expression again
-		err = storage.CreateExpression(id, expr)
+		err = storage.CreateExpression(&entities.Expression{ID: id, Expression: expr})
 		if err == nil {
 			t.Errorf("Expected error, got nil")
 		}
@@ -184,9 +186,10 @@ func TestUpdateExpression(t *testing.T) {
 				Status:     entities.ExpressionStatusPending,
 				Result:     0,
 			},
+			"3": {ID: "3", Expression: "2+2", Status: entities.ExpressionStatusCancelled},
 		},
 	}
-	err := storage.UpdateExpression("1", entities.ExpressionStatusProcessing, 2)
+	err := storage.UpdateExpression("1", entities.ExpressionStatusProcessing, entities.TaskResult{Result: 2})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -197,9 +200,144 @@ func TestUpdateExpression(t *testing.T) {
 		t.Errorf("expected result to be %v, got %v", 2, storage.expressions["1"].Result)
 	}
 
+	// Test case: a cancelled expression is not completed by a late result
+	err = storage.UpdateExpression("3", entities.ExpressionStatusCompleted, entities.TaskResult{Result: 4})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expr := storage.expressions["3"]; expr.Status != entities.ExpressionStatusCancelled || expr.Result != 0 {
+		t.Errorf("expected the cancelled expression to stay unchanged, got %+v", expr)
+	}
+
 	// Test case: expression does not exist
-	err = storage.UpdateExpression("2", entities.ExpressionStatusProcessing, 2)
+	err = storage.UpdateExpression("2", entities.ExpressionStatusProcessing, entities.TaskResult{Result: 2})
 	if err != use_cases_errors.ErrExpressionNotFound {
 		t.Errorf("expected error to be %v, got %v", use_cases_errors.ErrExpressionNotFound, err)
 	}
 }
+
+func TestSaveFunction(t *testing.T) {
+	storage := NewStorage()
+	definitions := []entities.Function{
+		{Name: "sq", Params: []string{"x"}, Definition: "sq(x) = x^2"},
+		{Name: "f", Params: []string{"x", "y"}, Definition: "f(x, y) = x + y"},
+		// the second definition of a function replaces the first one
+		{Name: "sq", Params: []string{"y"}, Definition: "sq(y) = y * y"},
+	}
+	for _, fn := range definitions {
+		if err := storage.SaveFunction(&fn); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	functions, err := storage.GetFunctions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []entities.Function{definitions[1], definitions[2]}
+	if !reflect.DeepEqual(functions, expected) {
+		t.Errorf("expected functions %v, got %v", expected, functions)
+	}
+}
+
+func TestFailExpression(t *testing.T) {
+	storage := &Storage{
+		expressions: map[string]*entities.Expression{
+			"1": {ID: "1", Expression: "1/0", Status: entities.ExpressionStatusPending},
+			"3": {ID: "3", Expression: "2/0", Status: entities.ExpressionStatusCancelled},
+		},
+	}
+	exprError := entities.ExpressionError{Code: entities.ErrorCodeDivisionByZero, Message: "division by zero", TaskID: "t1"}
+	if err := storage.FailExpression("1", exprError); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expr := storage.expressions["1"]
+	if expr.Status != entities.ExpressionStatusFailed {
+		t.Errorf("expected status to be %v, got %v", entities.ExpressionStatusFailed, expr.Status)
+	}
+	if expr.Error == nil || *expr.Error != exprError {
+		t.Errorf("expected error to be %+v, got %+v", exprError, expr.Error)
+	}
+
+	// a cancelled expression is not failed by a late error
+	if err := storage.FailExpression("3", exprError); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expr = storage.expressions["3"]; expr.Status != entities.ExpressionStatusCancelled || expr.Error != nil {
+		t.Errorf("expected the cancelled expression to stay unchanged, got %+v", expr)
+	}
+
+	if err := storage.FailExpression("2", exprError); err != use_cases_errors.ErrExpressionNotFound {
+		t.Errorf("expected error to be %v, got %v", use_cases_errors.ErrExpressionNotFound, err)
+	}
+}
+
+func TestChangeStatus(t *testing.T) {
+	storage := &Storage{
+		expressions: map[string]*entities.Expression{
+			"1": {ID: "1", Expression: "1+1", Status: entities.ExpressionStatusPending},
+			"2": {ID: "2", Expression: "1/0", Status: entities.ExpressionStatusFailed},
+		},
+	}
+	changed, err := storage.ChangeStatus("1", entities.ExpressionStatusProcessing, entities.ExpressionStatusPending)
+	if err != nil || !changed {
+		t.Errorf("expected the status to change, got %v, %v", changed, err)
+	}
+	if storage.expressions["1"].Status != entities.ExpressionStatusProcessing {
+		t.Errorf("expected status to be %v, got %v", entities.ExpressionStatusProcessing, storage.expressions["1"].Status)
+	}
+
+	// only an expression with one of the given statuses changes
+	changed, err = storage.ChangeStatus("2", entities.ExpressionStatusProcessing, entities.ExpressionStatusPending)
+	if err != nil || changed {
+		t.Errorf("expected the status not to change, got %v, %v", changed, err)
+	}
+	if storage.expressions["2"].Status != entities.ExpressionStatusFailed {
+		t.Errorf("expected status to be %v, got %v", entities.ExpressionStatusFailed, storage.expressions["2"].Status)
+	}
+
+	if _, err = storage.ChangeStatus("3", entities.ExpressionStatusProcessing, entities.ExpressionStatusPending); err != use_cases_errors.ErrExpressionNotFound {
+		t.Errorf("expected error to be %v, got %v", use_cases_errors.ErrExpressionNotFound, err)
+	}
+}
+
+// TestGetExpressionWhileUpdated reads the expressions while the results of
+// its tasks arrive, run it with -race to check the copies share no maps.
+func TestGetExpressionWhileUpdated(t *testing.T) {
+	storage := NewStorage()
+	if err := storage.CreateExpression(&entities.Expression{ID: "1", Expression: "a = 1 + 2; a"}); err != nil {
+		t.Fatalf("CreateExpression returned error: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			storage.UpdateBindings("1", []string{fmt.Sprintf("a%d", i)}, entities.TaskResult{Result: float64(i), Exact: "1"})
+		}
+	}()
+	for polling := true; polling; {
+		select {
+		case <-done:
+			polling = false
+		default:
+		}
+		expr, err := storage.GetExpression("1")
+		if err != nil {
+			t.Fatalf("GetExpression returned error: %v", err)
+		}
+		if _, err = json.Marshal(expr); err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+		expressions, _ := storage.GetExpressions()
+		if _, err = json.Marshal(expressions); err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+	}
+
+	expr, _ := storage.GetExpression("1")
+	expr.Bindings["changed"] = 1
+	if stored, _ := storage.GetExpression("1"); len(stored.Bindings) != 1000 {
+		t.Errorf("Expected the copy not to change the stored bindings, got %d names", len(stored.Bindings))
+	}
+}
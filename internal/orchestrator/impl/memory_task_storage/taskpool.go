@@ -3,25 +3,86 @@ package memory_task_storage
 import (
 	"calculator/internal/shared/entities"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 )
 
 // TaskPool is a struct that represents a task pool in the orchestrator.
+// A task may be used by several tasks, taskOwners lists all of them.
+// expressionsRoot maps the root tasks to their expressions and results keeps
+// the results of the computed ones until the other tasks of the expression,
+// like the values of the names the result does not use, are computed.
+// sentTasks maps the tasks leased to agents to the deadlines of their leases.
+// completed counts the computed tasks of every expression and started keeps
+// the time the first task of an expression was leased, both are forgotten
+// when the expression is completed or cancelled. cancelledTasks keeps the
+// cancelled tasks that were leased and paused keeps the paused expressions.
+// queues keeps the place of every expression in the order the tasks are
+// leased in, turns counts the leases and arrivals the expressions added.
 type TaskPool struct {
 	tasks           map[string]*entities.Task
-	taskOwners      map[string]string
-	sentTasks       map[string]bool
+	taskOwners      map[string][]string
+	sentTasks       map[string]time.Time
 	expressionsRoot map[string]string
+	results         map[string]entities.TaskResult
+	completed       map[string]int
+	started         map[string]time.Time
+	cancelledTasks  map[string]bool
+	paused          map[string]bool
+	queues          map[string]*queue
+	turns           int64
+	arrivals        int64
 	mu              sync.RWMutex
 }
 
+// queue is the place of an expression in the order the tasks are leased in.
+// waitingSince is the time the expression was added or its last task was
+// leased, turn is the number of that lease and arrival the order the
+// expression was added in.
+type queue struct {
+	priority     int
+	waitingSince time.Time
+	turn         int64
+	arrival      int64
+}
+
+// level is the priority of the expression raised by one for every aging it
+// waits.
+func (q *queue) level(now time.Time, aging time.Duration) int {
+	if aging <= 0 || q.waitingSince.IsZero() {
+		return q.priority
+	}
+	return q.priority + int(now.Sub(q.waitingSince)/aging)
+}
+
+// ahead reports whether the expression of the queue q takes its turn before
+// the one of other: it is of a higher level, or of the same level and its
+// last task was leased earlier, or it was added earlier.
+func (q *queue) ahead(other *queue, now time.Time, aging time.Duration) bool {
+	level, otherLevel := q.level(now, aging), other.level(now, aging)
+	if level != otherLevel {
+		return level > otherLevel
+	}
+	if q.turn != other.turn {
+		return q.turn < other.turn
+	}
+	return q.arrival < other.arrival
+}
+
 // NewTaskPool creates a new instance of the TaskPool struct.
 func NewTaskPool() *TaskPool {
 	taskPool := &TaskPool{
 		tasks:           make(map[string]*entities.Task),
-		sentTasks:       make(map[string]bool),
-		taskOwners:      make(map[string]string),
+		sentTasks:       make(map[string]time.Time),
+		taskOwners:      make(map[string][]string),
 		expressionsRoot: make(map[string]string),
+		results:         make(map[string]entities.TaskResult),
+		completed:       make(map[string]int),
+		started:         make(map[string]time.Time),
+		cancelledTasks:  make(map[string]bool),
+		paused:          make(map[string]bool),
+		queues:          make(map[string]*queue),
 		mu:              sync.RWMutex{},
 	}
 
@@ -36,102 +97,301 @@ func (tp *TaskPool) AddTasks(tasks []entities.Task) error {
 	for _, task := range tasks {
 		tp.tasks[task.ID] = &task
 		if task.ArgLeft.ArgType == entities.IsTask {
-			tp.taskOwners[task.ArgLeft.ArgTask.ID] = task.ID
+			tp.addOwner(task.ArgLeft.ArgTask.ID, task.ID)
 		}
 
 		if task.ArgRight.ArgType == entities.IsTask {
-			tp.taskOwners[task.ArgRight.ArgTask.ID] = task.ID
+			tp.addOwner(task.ArgRight.ArgTask.ID, task.ID)
+		}
+
+		if task.Kind == entities.TaskKindConditional {
+			if task.ArgThen.ArgType == entities.IsTask {
+				tp.addOwner(task.ArgThen.ArgTask.ID, task.ID)
+			}
+			if task.ArgElse.ArgType == entities.IsTask {
+				tp.addOwner(task.ArgElse.ArgTask.ID, task.ID)
+			}
 		}
 	}
 	tp.expressionsRoot[tasks[0].ID] = tasks[0].ExprID
+	if _, ok := tp.queues[tasks[0].ExprID]; !ok {
+		if tp.queues == nil {
+			tp.queues = make(map[string]*queue)
+		}
+		tp.arrivals++
+		tp.queues[tasks[0].ExprID] = &queue{priority: tasks[0].Priority, waitingSince: time.Now(), arrival: tp.arrivals}
+	}
 	return nil
 }
 
-// GetTaskToCompute returns the next task to compute in the task pool.
-func (tp *TaskPool) GetTaskToCompute() (entities.Task, error) {
+// addOwner records that the task ownerID uses the result of the task id.
+func (tp *TaskPool) addOwner(id, ownerID string) {
+	if !slices.Contains(tp.taskOwners[id], ownerID) {
+		tp.taskOwners[id] = append(tp.taskOwners[id], ownerID)
+	}
+}
+
+// removeOwner records that the task ownerID no longer uses the result of the task id.
+func (tp *TaskPool) removeOwner(id, ownerID string) {
+	ownerIDs := slices.DeleteFunc(tp.taskOwners[id], func(owner string) bool { return owner == ownerID })
+	if len(ownerIDs) == 0 {
+		delete(tp.taskOwners, id)
+		return
+	}
+	tp.taskOwners[id] = ownerIDs
+}
+
+// GetTaskToCompute leases the next task of the expression whose turn it is in queues.
+func (tp *TaskPool) GetTaskToCompute(aging time.Duration, deadline func(task entities.Task) time.Time) (entities.Task, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	now := time.Now()
+	var next *entities.Task
+	var nextQueue *queue
+	for _, task := range tp.tasks {
+		if tp.paused[task.ExprID] || !tp.isDispatchable(task) {
+			continue
+		}
+		q, ok := tp.queues[task.ExprID]
+		if !ok {
+			q = &queue{}
+		}
+		// the tasks of an expression are leased in the order of their IDs
+		if next == nil || q.ahead(nextQueue, now, aging) ||
+			!nextQueue.ahead(q, now, aging) && task.ID < next.ID {
+			next, nextQueue = task, q
+		}
+	}
+	if next == nil {
+		return entities.Task{}, fmt.Errorf("no tasks to compute")
+	}
+
+	next.Lease++
+	tp.sentTasks[next.ID] = deadline(*next)
+	if _, ok := tp.started[next.ExprID]; !ok {
+		tp.started[next.ExprID] = now
+	}
+	tp.turns++
+	nextQueue.waitingSince, nextQueue.turn = now, tp.turns
+	return *next, nil
+}
+
+// isDispatchable reports whether the task is ready to be sent to an agent: it
+// is not leased, its arguments are computed and it is not inside an unchosen
+// branch.
+func (tp *TaskPool) isDispatchable(task *entities.Task) bool {
+	_, sent := tp.sentTasks[task.ID]
+	return !sent && task.IsReady() && task.Kind != entities.TaskKindConditional && task.Guard == ""
+}
+
+// HoldsLease checks the lease of the task sent to an agent.
+func (tp *TaskPool) HoldsLease(id string, lease int64) (bool, error) {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
-	for _, task := range tp.tasks {
-		if task.ArgLeft.ArgType == entities.IsNumber &&
-			task.ArgRight.ArgType == entities.IsNumber &&
-			!tp.sentTasks[task.ID] {
 
-			tp.sentTasks[task.ID] = true
-			return *task, nil
+	task, ok := tp.tasks[id]
+	if !ok {
+		return false, fmt.Errorf("task %s not found", id)
+	}
+	_, sent := tp.sentTasks[id]
+	return sent && task.Lease == lease, nil
+}
+
+// ExpireLeases forgets the sent tasks whose leases ended before now.
+func (tp *TaskPool) ExpireLeases(now time.Time) (int, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	expired := 0
+	for id, deadline := range tp.sentTasks {
+		if deadline.Before(now) {
+			delete(tp.sentTasks, id)
+			expired++
 		}
 	}
-	return entities.Task{}, fmt.Errorf("no tasks to compute")
+	return expired, nil
 }
 
 // SetTaskResultAfterCompute sets the result of a task after it has been computed.
-func (tp *TaskPool) SetTaskResultAfterCompute(id string, result float64) error {
+// The result is passed to every task that uses it.
+func (tp *TaskPool) SetTaskResultAfterCompute(result entities.TaskResult) error {
+	id := result.ID
 
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
-	if _, ok := tp.tasks[id]; !ok {
+	task, ok := tp.tasks[id]
+	if !ok {
 		return fmt.Errorf("task %s not found", id)
 	}
-
-	if _, ok := tp.expressionsRoot[id]; ok {
-		return nil
+	if exprID, root := tp.expressionsRoot[id]; root {
+		if tp.results == nil {
+			tp.results = make(map[string]entities.TaskResult)
+		}
+		tp.results[exprID] = result
 	}
 
-	ownerID, ok := tp.taskOwners[id]
+	ownerIDs, ok := tp.taskOwners[id]
 
 	if !ok {
+		// the root task is used by no task, and neither may be
+		// the value of an assigned name
+		if _, root := tp.expressionsRoot[id]; root || len(task.Bindings) > 0 {
+			return nil
+		}
 		return fmt.Errorf("task %s owner not found", id)
 	}
 
-	owner := tp.tasks[ownerID]
+	for _, ownerID := range ownerIDs {
+		owner, ok := tp.tasks[ownerID]
+		if !ok {
+			return fmt.Errorf("task %s owner not found", id)
+		}
 
-	if tp.isIdArg(id, owner.ArgLeft) {
-		owner.ArgLeft.ArgType = entities.IsNumber
-		owner.ArgLeft.ArgFloat = result
-	} else if tp.isIdArg(id, owner.ArgRight) {
-		owner.ArgRight.ArgType = entities.IsNumber
-		owner.ArgRight.ArgFloat = result
-	} else {
-		return fmt.Errorf("task %s owner not found", id)
+		found := false
+		for _, arg := range []*entities.Arg{&owner.ArgLeft, &owner.ArgRight, &owner.ArgThen, &owner.ArgElse} {
+			if tp.isIdArg(id, *arg) {
+				arg.ArgType = entities.IsNumber
+				arg.ArgFloat = result.Result
+				arg.ArgImag = result.Imag
+				arg.ArgExact = result.Exact
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("task %s owner not found", id)
+		}
 	}
 
 	return nil
 }
 
-// DeleteTask deletes a task from the task pool.
+// DeleteTask deletes a computed task from the task pool.
 func (tp *TaskPool) DeleteTask(id string) error {
 
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
+	if task, ok := tp.tasks[id]; ok {
+		tp.completed[task.ExprID]++
+	}
 	delete(tp.sentTasks, id)
 	delete(tp.taskOwners, id)
+	delete(tp.expressionsRoot, id)
 	delete(tp.tasks, id)
 	return nil
 }
 
-// DeleteExpression deletes an expression from the task pool.
+// DeleteExpression deletes a completed expression from the task pool,
+// its progress is forgotten.
 func (tp *TaskPool) DeleteExpression(id string) error {
 
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
-	delete(tp.expressionsRoot, id)
+	delete(tp.results, id)
+	delete(tp.queues, id)
+	delete(tp.completed, id)
+	delete(tp.started, id)
+	delete(tp.paused, id)
 	return nil
 
 }
 
-// IsLastTask returns true if the task is the last task in the expression.
-func (tp *TaskPool) IsLastTask(id string) (bool, error) {
+// CancelExpression deletes the tasks of the expression, the leased ones are kept in cancelledTasks.
+func (tp *TaskPool) CancelExpression(exprID string) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	for id, task := range tp.tasks {
+		if task.ExprID != exprID {
+			continue
+		}
+		if task.Lease > 0 {
+			tp.cancelledTasks[id] = true
+		}
+		delete(tp.sentTasks, id)
+		delete(tp.taskOwners, id)
+		delete(tp.expressionsRoot, id)
+		delete(tp.tasks, id)
+	}
+	delete(tp.results, exprID)
+	delete(tp.completed, exprID)
+	delete(tp.started, exprID)
+	delete(tp.paused, exprID)
+	delete(tp.queues, exprID)
+	return nil
+}
 
+// IsCancelled looks the task up in cancelledTasks.
+func (tp *TaskPool) IsCancelled(taskID string) (bool, error) {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
 
-	if _, ok := tp.expressionsRoot[id]; ok {
-		return true, nil
+	return tp.cancelledTasks[taskID], nil
+}
+
+// PauseExpression adds the expression to paused.
+func (tp *TaskPool) PauseExpression(exprID string) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	tp.paused[exprID] = true
+	return nil
+}
+
+// ResumeExpression sends the tasks of the paused expression to agents again.
+// The time it was paused does not raise its priority.
+func (tp *TaskPool) ResumeExpression(exprID string) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	delete(tp.paused, exprID)
+	if q, ok := tp.queues[exprID]; ok {
+		q.waitingSince = time.Now()
 	}
+	return nil
+}
+
+// GetProgress counts the tasks of the expression and returns the time its
+// first task was leased, the zero time if none was.
+func (tp *TaskPool) GetProgress(exprID string) (entities.Progress, time.Time, error) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	progress := entities.Progress{Completed: tp.completed[exprID]}
+	for id, task := range tp.tasks {
+		if task.ExprID != exprID {
+			continue
+		}
+		progress.Total++
+		if _, sent := tp.sentTasks[id]; sent {
+			progress.InFlight++
+		} else if tp.isDispatchable(task) {
+			progress.Ready++
+		}
+	}
+	progress.Total += progress.Completed
+	return progress, tp.started[exprID], nil
+}
 
-	return false, nil
+// ExpressionResult reports whether the root task of the expression is
+// computed and no other task of it is left. It returns the result of the root task.
+func (tp *TaskPool) ExpressionResult(exprID string) (entities.TaskResult, bool, error) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	result, ok := tp.results[exprID]
+	if !ok {
+		return entities.TaskResult{}, false, nil
+	}
+	for _, task := range tp.tasks {
+		if task.ExprID == exprID {
+			return entities.TaskResult{}, false, nil
+		}
+	}
+	return result, true, nil
 }
 
 func (tp *TaskPool) GetExpressionIDByTaskID(taskID string) (string, error) {
@@ -146,9 +406,121 @@ func (tp *TaskPool) GetExpressionIDByTaskID(taskID string) (string, error) {
 	return task.ExprID, nil
 }
 
+// GetBindings returns the names the script assigns the result of the task to.
+func (tp *TaskPool) GetBindings(taskID string) ([]string, error) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	task, ok := tp.tasks[taskID]
+	if !ok {
+		return nil, fmt.Errorf("task %s not found", taskID)
+	}
+
+	return slices.Clone(task.Bindings), nil
+}
+
+// GetReadyConditionals returns the conditional tasks of the expression whose condition is computed.
+func (tp *TaskPool) GetReadyConditionals(exprID string) ([]entities.Task, error) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	var conditionals []entities.Task
+	for _, task := range tp.tasks {
+		if task.ExprID == exprID && task.Kind == entities.TaskKindConditional && task.Guard == "" && task.IsReady() {
+			conditionals = append(conditionals, *task)
+		}
+	}
+	return conditionals, nil
+}
+
+// ChooseBranch resolves the conditional task and returns the chosen argument.
+func (tp *TaskPool) ChooseBranch(id string, condition bool) (entities.Arg, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	task, ok := tp.tasks[id]
+	if !ok {
+		return entities.Arg{}, fmt.Errorf("task %s not found", id)
+	}
+
+	chosen, rejected := task.ArgThen, task.ArgElse
+	if !condition {
+		chosen, rejected = rejected, chosen
+	}
+
+	if rejected.ArgType == entities.IsTask {
+		tp.release(rejected.ArgTask.ID, id)
+	}
+	for _, t := range tp.tasks {
+		if t.Guard == id {
+			t.Guard = task.Guard
+		}
+	}
+
+	if chosen.ArgType != entities.IsTask {
+		return chosen, nil
+	}
+
+	// the chosen task takes the place of the conditional task
+	chosenID := chosen.ArgTask.ID
+	tp.removeOwner(chosenID, id)
+	if chosenTask, ok := tp.tasks[chosenID]; ok {
+		chosenTask.Bindings = append(chosenTask.Bindings, task.Bindings...)
+		slices.Sort(chosenTask.Bindings)
+	}
+	if exprID, ok := tp.expressionsRoot[id]; ok {
+		delete(tp.expressionsRoot, id)
+		tp.expressionsRoot[chosenID] = exprID
+	} else {
+		ownerIDs := tp.taskOwners[id]
+		for _, ownerID := range ownerIDs {
+			owner, ok := tp.tasks[ownerID]
+			if !ok {
+				return entities.Arg{}, fmt.Errorf("task %s owner not found", id)
+			}
+			for _, arg := range []*entities.Arg{&owner.ArgLeft, &owner.ArgRight, &owner.ArgThen, &owner.ArgElse} {
+				if tp.isIdArg(id, *arg) {
+					arg.ArgTask = tp.tasks[chosenID]
+				}
+			}
+			tp.addOwner(chosenID, ownerID)
+		}
+	}
+
+	delete(tp.sentTasks, id)
+	delete(tp.taskOwners, id)
+	delete(tp.tasks, id)
+	return chosen, nil
+}
+
+// release records that the task ownerID no longer uses the task id. The task
+// and the tasks it depends on are deleted when no other task uses them,
+// unless the task computes the value of an assigned name.
+func (tp *TaskPool) release(id, ownerID string) {
+	task, ok := tp.tasks[id]
+	if !ok {
+		return
+	}
+
+	tp.removeOwner(id, ownerID)
+	if len(tp.taskOwners[id]) > 0 || len(task.Bindings) > 0 {
+		return
+	}
+
+	for _, arg := range []entities.Arg{task.ArgLeft, task.ArgRight, task.ArgThen, task.ArgElse} {
+		if arg.ArgType == entities.IsTask && arg.ArgTask != nil {
+			tp.release(arg.ArgTask.ID, id)
+		}
+	}
+
+	delete(tp.sentTasks, id)
+	delete(tp.taskOwners, id)
+	delete(tp.tasks, id)
+}
+
 func (tp *TaskPool) isIdArg(id string, arg entities.Arg) bool {
 
-	if arg.ArgType == entities.IsTask && arg.ArgTask.ID == id {
+	if arg.ArgType == entities.IsTask && arg.ArgTask != nil && arg.ArgTask.ID == id {
 		return true
 	}
 	return false
@@ -1,8 +1,13 @@
 package memory_task_storage
 
 import (
+	"calculator/internal/orchestrator/impl/tasktest"
+	"calculator/internal/orchestrator/use_cases/scheduler"
 	"calculator/internal/shared/entities"
+	"reflect"
+	"slices"
 	"testing"
+	"time"
 )
 
 // TestGetTaskToCompute tests the GetTaskToCompute function of the TaskPool struct.
@@ -13,10 +18,11 @@ func TestGetTaskToCompute(t *testing.T) {
 	// Create an empty task pool and call the function. Expect an error.
 	taskPool := &TaskPool{
 		tasks:      map[string]*entities.Task{},
-		sentTasks:  map[string]bool{},
-		taskOwners: map[string]string{},
+		sentTasks:  map[string]time.Time{},
+		started:    map[string]time.Time{},
+		taskOwners: map[string][]string{},
 	}
-	_, err := taskPool.GetTaskToCompute()
+	_, err := taskPool.GetTaskToCompute(0, tasktest.LeaseForHour)
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -35,17 +41,18 @@ func TestGetTaskToCompute(t *testing.T) {
 		tasks: map[string]*entities.Task{
 			"task1": &task,
 		},
-		sentTasks:  map[string]bool{},
-		taskOwners: map[string]string{},
+		sentTasks:  map[string]time.Time{},
+		started:    map[string]time.Time{},
+		taskOwners: map[string][]string{},
 	}
-	resultTask, err := taskPool.GetTaskToCompute()
+	resultTask, err := taskPool.GetTaskToCompute(0, tasktest.LeaseForHour)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	if resultTask != task {
+	if !reflect.DeepEqual(resultTask, task) {
 		t.Errorf("Expected task %v, got %v", task, resultTask)
 	}
-	if !taskPool.sentTasks["task1"] {
+	if _, sent := taskPool.sentTasks["task1"]; !sent {
 		t.Errorf("Expected task1 to be marked as sent")
 	}
 
@@ -64,14 +71,213 @@ func TestGetTaskToCompute(t *testing.T) {
 			"task2": &task,
 			"task3": {ID: "task3", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2.0}},
 		},
-		sentTasks:  map[string]bool{},
-		taskOwners: map[string]string{"task3": "task2"},
+		sentTasks:  map[string]time.Time{},
+		started:    map[string]time.Time{},
+		taskOwners: map[string][]string{"task3": {"task2"}},
 	}
-	_, err = taskPool.GetTaskToCompute()
+	_, err = taskPool.GetTaskToCompute(0, tasktest.LeaseForHour)
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
+	// Test case 4: Unary task with its argument computed
+	// Create a task pool with one unary task. The empty right argument must
+	// not prevent the task from being returned.
+	task = entities.Task{
+		ID:        "task4",
+		ArgLeft:   entities.Arg{ArgType: entities.IsNumber, ArgFloat: 4.0},
+		ArgRight:  entities.Arg{ArgType: entities.IsEmpty},
+		Operation: "sqrt",
+		Kind:      entities.TaskKindUnary,
+	}
+	taskPool = &TaskPool{
+		tasks: map[string]*entities.Task{
+			"task4": &task,
+		},
+		sentTasks:  map[string]time.Time{},
+		started:    map[string]time.Time{},
+		taskOwners: map[string][]string{},
+	}
+	resultTask, err = taskPool.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(resultTask, task) {
+		t.Errorf("Expected task %v, got %v", task, resultTask)
+	}
+
+	// Test case 5: Conditional task and task inside an unchosen branch
+	// Both tasks are ready, but neither may be sent to an agent.
+	taskPool = &TaskPool{
+		tasks: map[string]*entities.Task{
+			"if1": {ID: "if1", Kind: entities.TaskKindConditional, Operation: "if",
+				ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1.0},
+				ArgRight: entities.Arg{ArgType: entities.IsEmpty},
+				ArgThen:  entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "task5"}},
+				ArgElse:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 0.0}},
+			"task5": {ID: "task5", Guard: "if1", Operation: "+",
+				ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1.0},
+				ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2.0}},
+		},
+		sentTasks:  map[string]time.Time{},
+		started:    map[string]time.Time{},
+		taskOwners: map[string][]string{"task5": {"if1"}},
+	}
+	_, err = taskPool.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+// TestChooseBranch tests that choosing a branch of a conditional task releases
+// the tasks of the chosen branch and deletes the tasks of the other one.
+func TestChooseBranch(t *testing.T) {
+	// if(1, 2 * 3, (4 + 5) * 6) + 1
+	then := entities.Task{ID: "then", ExprID: "expr", Guard: "if", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2.0},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3.0}}
+	sum := entities.Task{ID: "sum", ExprID: "expr", Guard: "if", Operation: "+",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 4.0},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 5.0}}
+	otherwise := entities.Task{ID: "else", ExprID: "expr", Guard: "if", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &sum},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 6.0}}
+	conditional := entities.Task{ID: "if", ExprID: "expr", Kind: entities.TaskKindConditional, Operation: "if",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1.0},
+		ArgRight: entities.Arg{ArgType: entities.IsEmpty},
+		ArgThen:  entities.Arg{ArgType: entities.IsTask, ArgTask: &then},
+		ArgElse:  entities.Arg{ArgType: entities.IsTask, ArgTask: &otherwise}}
+	root := entities.Task{ID: "root", ExprID: "expr", Operation: "+",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &conditional},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1.0}}
+
+	tp := NewTaskPool()
+	tp.AddTasks([]entities.Task{root, conditional, then, otherwise, sum})
+
+	if _, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err == nil {
+		t.Fatalf("Expected no task to be sent before the branch is chosen")
+	}
+
+	conditionals, err := tp.GetReadyConditionals("expr")
+	if err != nil || len(conditionals) != 1 || conditionals[0].ID != "if" {
+		t.Fatalf("Expected the conditional task to be ready, got %v, %v", conditionals, err)
+	}
+
+	chosen, err := tp.ChooseBranch("if", true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if chosen.ArgType != entities.IsTask || chosen.ArgTask.ID != "then" {
+		t.Errorf("Expected the then branch to be chosen, got %v", chosen)
+	}
+
+	for _, id := range []string{"if", "else", "sum"} {
+		if _, ok := tp.tasks[id]; ok {
+			t.Errorf("Expected task %s to be deleted", id)
+		}
+	}
+	if !slices.Equal(tp.taskOwners["then"], []string{"root"}) || tp.tasks["root"].ArgLeft.ArgTask.ID != "then" {
+		t.Errorf("Expected the chosen task to take the place of the conditional task")
+	}
+
+	task, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil || task.ID != "then" {
+		t.Errorf("Expected the chosen task to be sent, got %v, %v", task, err)
+	}
+}
+
+// TestChooseBranchNumber tests choosing a branch that is a number.
+func TestChooseBranchNumber(t *testing.T) {
+	// if(0, 2 * 3, 7)
+	then := entities.Task{ID: "then", ExprID: "expr", Guard: "if", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2.0},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3.0}}
+	conditional := entities.Task{ID: "if", ExprID: "expr", Kind: entities.TaskKindConditional, Operation: "if",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 0.0},
+		ArgRight: entities.Arg{ArgType: entities.IsEmpty},
+		ArgThen:  entities.Arg{ArgType: entities.IsTask, ArgTask: &then},
+		ArgElse:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 7.0}}
+
+	tp := NewTaskPool()
+	tp.AddTasks([]entities.Task{conditional, then})
+
+	chosen, err := tp.ChooseBranch("if", false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if chosen.ArgType != entities.IsNumber || chosen.ArgFloat != 7.0 {
+		t.Errorf("Expected the else branch to be chosen, got %v", chosen)
+	}
+	if _, ok := tp.tasks["then"]; ok {
+		t.Errorf("Expected the then branch to be deleted")
+	}
+	if tp.expressionsRoot["if"] != "expr" {
+		t.Errorf("Expected the conditional task to stay the root task")
+	}
 }
+
+// TestChooseBranchBindings tests choosing a branch that uses the value of an assigned name.
+func TestChooseBranchBindings(t *testing.T) {
+	// a = 2 * 3; b = if(1, a, a + 1); b * a
+	double := entities.Task{ID: "a", ExprID: "expr", Operation: "*", Bindings: []string{"a"},
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2.0},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3.0}}
+	increment := entities.Task{ID: "else", ExprID: "expr", Guard: "if", Operation: "+",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &double},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1.0}}
+	conditional := entities.Task{ID: "if", ExprID: "expr", Kind: entities.TaskKindConditional, Operation: "if", Bindings: []string{"b"},
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1.0},
+		ArgRight: entities.Arg{ArgType: entities.IsEmpty},
+		ArgThen:  entities.Arg{ArgType: entities.IsTask, ArgTask: &double},
+		ArgElse:  entities.Arg{ArgType: entities.IsTask, ArgTask: &increment}}
+	root := entities.Task{ID: "root", ExprID: "expr", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &conditional},
+		ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &double}}
+
+	tp := NewTaskPool()
+	tp.AddTasks([]entities.Task{root, conditional, double, increment})
+
+	if _, err := tp.ChooseBranch("if", true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := tp.tasks["else"]; ok {
+		t.Errorf("Expected the else branch to be deleted")
+	}
+	if !slices.Equal(tp.taskOwners["a"], []string{"root"}) {
+		t.Errorf("Expected the root task to be the only owner of a, got %v", tp.taskOwners["a"])
+	}
+	if bindings, _ := tp.GetBindings("a"); !slices.Equal(bindings, []string{"a", "b"}) {
+		t.Errorf("Expected the chosen task to take the names of the conditional task, got %v", bindings)
+	}
+	if tp.tasks["root"].ArgLeft.ArgTask.ID != "a" || tp.tasks["root"].ArgRight.ArgTask.ID != "a" {
+		t.Errorf("Expected the root task to use a twice, got %v", tp.tasks["root"])
+	}
+
+	// the value of an assigned name is kept when the task that used it is deleted
+	// a = 2 * 3; if(0, a + 1, 7)
+	conditional = entities.Task{ID: "if", ExprID: "expr", Kind: entities.TaskKindConditional, Operation: "if",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 0.0},
+		ArgRight: entities.Arg{ArgType: entities.IsEmpty},
+		ArgThen:  entities.Arg{ArgType: entities.IsTask, ArgTask: &increment},
+		ArgElse:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 7.0}}
+	increment.ID = "then"
+
+	tp = NewTaskPool()
+	tp.AddTasks([]entities.Task{conditional, double, increment})
+
+	if _, err := tp.ChooseBranch("if", false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := tp.tasks["then"]; ok {
+		t.Errorf("Expected the then branch to be deleted")
+	}
+	if _, ok := tp.tasks["a"]; !ok {
+		t.Fatalf("Expected the task of a to be kept")
+	}
+	if err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "a", Result: 6}); err != nil {
+		t.Errorf("Expected no error for a task no other task uses, got %v", err)
+	}
+}
+
 func TestSetTaskResultAfterCompute1(t *testing.T) {
 	// Test case 1: Task not found
 	tp := &TaskPool{
@@ -79,7 +285,7 @@ func TestSetTaskResultAfterCompute1(t *testing.T) {
 			"task1": {ID: "task1"},
 		},
 	}
-	err := tp.SetTaskResultAfterCompute("task2", 1.0)
+	err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "task2", Result: 1.0})
 	if err == nil {
 		t.Errorf("Expected error for task not found, got nil")
 	}
@@ -93,7 +299,7 @@ func TestSetTaskResultAfterCompute1(t *testing.T) {
 			"task1": "task1",
 		},
 	}
-	err = tp.SetTaskResultAfterCompute("task1", 1.0)
+	err = tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "task1", Result: 1.0})
 	if err != nil {
 		t.Errorf("Expected no error for task is root of expression, got %v", err)
 	}
@@ -104,11 +310,11 @@ func TestSetTaskResultAfterCompute1(t *testing.T) {
 			"task1": {ID: "task1"},
 			"task2": {ID: "task2"},
 		},
-		taskOwners: map[string]string{
-			"task1": "task1",
+		taskOwners: map[string][]string{
+			"task1": {"task1"},
 		},
 	}
-	err = tp.SetTaskResultAfterCompute("task2", 1.0)
+	err = tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "task2", Result: 1.0})
 	if err == nil {
 		t.Errorf("Expected error for task owner not found, got nil")
 	}
@@ -132,11 +338,11 @@ func TestSetTaskResultAfterCompute2(t *testing.T) {
 				ID: "task2",
 			},
 		},
-		taskOwners: map[string]string{
-			"task2": "task1",
+		taskOwners: map[string][]string{
+			"task2": {"task1"},
 		},
 	}
-	err := tp.SetTaskResultAfterCompute("task2", 1.0)
+	err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "task2", Result: 1.0})
 	if err != nil {
 		t.Errorf("Expected no error for task owner found, got %v", err)
 	}
@@ -162,11 +368,11 @@ func TestSetTaskResultAfterCompute2(t *testing.T) {
 				ID: "task2",
 			},
 		},
-		taskOwners: map[string]string{
-			"task2": "task1",
+		taskOwners: map[string][]string{
+			"task2": {"task1"},
 		},
 	}
-	err = tp.SetTaskResultAfterCompute("task2", 1.0)
+	err = tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "task2", Result: 1.0})
 	if err != nil {
 		t.Errorf("Expected no error for task owner found, got %v", err)
 	}
@@ -174,3 +380,363 @@ func TestSetTaskResultAfterCompute2(t *testing.T) {
 		t.Errorf("Expected ArgRight to be updated, got %v", tp.tasks["task1"].ArgRight)
 	}
 }
+
+func TestSetTaskResultAfterComputeShared(t *testing.T) {
+	// (a*b + c) / (a*b - c), the product feeds both the sum and the difference
+	product := entities.Task{
+		ID:       "product",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3},
+	}
+	sum := entities.Task{
+		ID:       "sum",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1},
+	}
+	difference := entities.Task{
+		ID:       "difference",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1},
+	}
+	square := entities.Task{
+		ID:       "square",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+	}
+	quotient := entities.Task{
+		ID:       "quotient",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &sum},
+		ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &difference},
+	}
+
+	tp := NewTaskPool()
+	tp.AddTasks([]entities.Task{quotient, product, sum, difference, square})
+	if !slices.Equal(tp.taskOwners["product"], []string{"sum", "difference", "square"}) {
+		t.Fatalf("Expected the product to have three owners, got %v", tp.taskOwners["product"])
+	}
+
+	err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "product", Result: 6})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, id := range []string{"sum", "difference", "square"} {
+		task := tp.tasks[id]
+		if task.ArgLeft.ArgType != entities.IsNumber || task.ArgLeft.ArgFloat != 6 {
+			t.Errorf("Expected the result to be passed to %s, got %v", id, task.ArgLeft)
+		}
+	}
+	if tp.tasks["square"].ArgRight.ArgType != entities.IsNumber || tp.tasks["square"].ArgRight.ArgFloat != 6 {
+		t.Errorf("Expected the result to be passed to both arguments, got %v", tp.tasks["square"].ArgRight)
+	}
+}
+
+func TestExpressionResult(t *testing.T) {
+	// a = 1 + 2; b = a * 4; a, the result is computed before the value of b
+	root := entities.Task{ID: "a", ExprID: "expr", Operation: "+", Bindings: []string{"a"},
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}}
+	unused := entities.Task{ID: "b", ExprID: "expr", Operation: "*", Bindings: []string{"b"},
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &root},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 4}}
+
+	tp := NewTaskPool()
+	if err := tp.AddTasks([]entities.Task{root, unused}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+	for _, result := range []entities.TaskResult{{ID: "a", Result: 3}, {ID: "b", Result: 12}} {
+		if _, computed, err := tp.ExpressionResult("expr"); err != nil || computed {
+			t.Fatalf("Expected the expression not to be computed before task %s, got %v, %v", result.ID, computed, err)
+		}
+		if err := tp.SetTaskResultAfterCompute(result); err != nil {
+			t.Fatalf("SetTaskResultAfterCompute returned error: %v", err)
+		}
+		if err := tp.DeleteTask(result.ID); err != nil {
+			t.Fatalf("DeleteTask returned error: %v", err)
+		}
+	}
+
+	result, computed, err := tp.ExpressionResult("expr")
+	if err != nil || !computed || result.Result != 3 {
+		t.Errorf("Expected the expression computed with the result of the root task, got %+v, %v, %v", result, computed, err)
+	}
+	if err = tp.DeleteExpression("expr"); err != nil {
+		t.Fatalf("DeleteExpression returned error: %v", err)
+	}
+	if _, computed, _ = tp.ExpressionResult("expr"); computed {
+		t.Errorf("Expected the result of a deleted expression to be forgotten")
+	}
+}
+
+// TestChooseBranchShared tests that the rejected branch keeps the tasks it
+// shares with the rest of the expression.
+func TestChooseBranchShared(t *testing.T) {
+	// if(1, 2 * 3, 2 * 3 + 1) * (2 * 3), the product is shared by both branches and the root
+	product := entities.Task{ID: "product", ExprID: "expr", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2.0},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3.0}}
+	increment := entities.Task{ID: "else", ExprID: "expr", Guard: "if", Operation: "+",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1.0}}
+	conditional := entities.Task{ID: "if", ExprID: "expr", Kind: entities.TaskKindConditional, Operation: "if",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1.0},
+		ArgRight: entities.Arg{ArgType: entities.IsEmpty},
+		ArgThen:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgElse:  entities.Arg{ArgType: entities.IsTask, ArgTask: &increment}}
+	root := entities.Task{ID: "root", ExprID: "expr", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &conditional},
+		ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &product}}
+
+	tp := NewTaskPool()
+	tp.AddTasks([]entities.Task{root, conditional, product, increment})
+
+	if _, err := tp.ChooseBranch("if", true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, id := range []string{"if", "else"} {
+		if _, ok := tp.tasks[id]; ok {
+			t.Errorf("Expected task %s to be deleted", id)
+		}
+	}
+	// the product is kept, the deleted branch no longer uses it
+	if _, ok := tp.tasks["product"]; !ok || !slices.Equal(tp.taskOwners["product"], []string{"root"}) {
+		t.Errorf("Expected the root task to be the only owner of the product, got %v", tp.taskOwners["product"])
+	}
+
+	if err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "product", Result: 6}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tp.tasks["root"].ArgLeft.ArgFloat != 6 || tp.tasks["root"].ArgRight.ArgFloat != 6 {
+		t.Errorf("Expected the root task to get the product twice, got %+v", tp.tasks["root"])
+	}
+}
+
+func TestLeases(t *testing.T) {
+	tp := NewTaskPool()
+	task := entities.Task{
+		ID:        "task",
+		ExprID:    "expr",
+		ArgLeft:   entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1},
+		ArgRight:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2},
+		Operation: "+",
+	}
+	if err := tp.AddTasks([]entities.Task{task}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+
+	now := time.Now()
+	first, err := tp.GetTaskToCompute(0, func(entities.Task) time.Time { return now.Add(time.Second) })
+	if err != nil || first.Lease != 1 {
+		t.Fatalf("Expected the first lease, got %+v, %v", first, err)
+	}
+	if _, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err == nil {
+		t.Errorf("Expected the leased task not to be sent again")
+	}
+
+	// the lease has not ended yet
+	if expired, err := tp.ExpireLeases(now); err != nil || expired != 0 {
+		t.Errorf("Expected no expired leases, got %d, %v", expired, err)
+	}
+	if expired, err := tp.ExpireLeases(now.Add(2 * time.Second)); err != nil || expired != 1 {
+		t.Errorf("Expected 1 expired lease, got %d, %v", expired, err)
+	}
+	if held, err := tp.HoldsLease("task", first.Lease); err != nil || held {
+		t.Errorf("Expected the expired lease not to be held, got %v, %v", held, err)
+	}
+
+	second, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil || second.Lease != 2 {
+		t.Fatalf("Expected the second lease, got %+v, %v", second, err)
+	}
+	if held, _ := tp.HoldsLease("task", first.Lease); held {
+		t.Errorf("Expected the first lease to be fenced off by the second")
+	}
+	if held, _ := tp.HoldsLease("task", second.Lease); !held {
+		t.Errorf("Expected the second lease to be held")
+	}
+	if _, err := tp.HoldsLease("unknown", 1); err == nil {
+		t.Errorf("Expected error for an unknown task")
+	}
+}
+
+func TestCancelExpression(t *testing.T) {
+	tp := NewTaskPool()
+	tasks := []entities.Task{
+		{ID: "root", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "left"}}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3}, Operation: "*"},
+		{ID: "left", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 0}, Operation: "/"},
+	}
+	other := entities.Task{ID: "other", ExprID: "other", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "+"}
+	if err := tp.AddTasks(tasks); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+	if err := tp.AddTasks([]entities.Task{other}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+	for range 2 {
+		if _, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err != nil {
+			t.Fatalf("GetTaskToCompute returned error: %v", err)
+		}
+	}
+
+	if err := tp.CancelExpression("expr"); err != nil {
+		t.Fatalf("CancelExpression returned error: %v", err)
+	}
+	if len(tp.tasks) != 1 || tp.tasks["other"] == nil {
+		t.Errorf("Expected only the task of the other expression, got %v", tp.tasks)
+	}
+	if len(tp.sentTasks) != 1 || len(tp.taskOwners) != 0 || len(tp.expressionsRoot) != 1 {
+		t.Errorf("Expected the 2 cancelled tasks to be forgotten, got sent %v, owners %v, roots %v", tp.sentTasks, tp.taskOwners, tp.expressionsRoot)
+	}
+	if _, err := tp.HoldsLease("left", 1); err == nil {
+		t.Errorf("Expected error for a cancelled task")
+	}
+	// only the leased task may still be returned by an agent
+	for id, expected := range map[string]bool{"root": false, "left": true, "other": false} {
+		if cancelled, err := tp.IsCancelled(id); err != nil || cancelled != expected {
+			t.Errorf("Expected task %s cancelled %v, got %v, %v", id, expected, cancelled, err)
+		}
+	}
+
+	// the cancelled task is remembered after its lease ends
+	if _, err := tp.ExpireLeases(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatalf("ExpireLeases returned error: %v", err)
+	}
+	if cancelled, err := tp.IsCancelled("left"); err != nil || !cancelled {
+		t.Errorf("Expected the cancelled task to be remembered, got %v, %v", cancelled, err)
+	}
+}
+
+func TestPauseExpression(t *testing.T) {
+	tp := NewTaskPool()
+	for _, exprID := range []string{"paused", "other"} {
+		task := entities.Task{ID: exprID, ExprID: exprID, ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "+"}
+		if err := tp.AddTasks([]entities.Task{task}); err != nil {
+			t.Fatalf("AddTasks returned error: %v", err)
+		}
+	}
+
+	if err := tp.PauseExpression("paused"); err != nil {
+		t.Fatalf("PauseExpression returned error: %v", err)
+	}
+	task, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil || task.ID != "other" {
+		t.Fatalf("Expected the task of the other expression, got %+v, %v", task, err)
+	}
+	if task, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err == nil {
+		t.Errorf("Expected the task of the paused expression not to be sent, got %+v", task)
+	}
+	// the task is still ready, it waits for the expression to be resumed
+	if progress, _, _ := tp.GetProgress("paused"); progress != (entities.Progress{Total: 1, Ready: 1}) {
+		t.Errorf("Expected 1 ready task, got %+v", progress)
+	}
+
+	if err := tp.ResumeExpression("paused"); err != nil {
+		t.Fatalf("ResumeExpression returned error: %v", err)
+	}
+	if task, err = tp.GetTaskToCompute(0, tasktest.LeaseForHour); err != nil || task.ID != "paused" {
+		t.Errorf("Expected the task of the resumed expression, got %+v, %v", task, err)
+	}
+
+	// an expression completed while it is paused is forgotten
+	if err = tp.PauseExpression("other"); err != nil {
+		t.Fatalf("PauseExpression returned error: %v", err)
+	}
+	if err = tp.DeleteExpression("other"); err != nil {
+		t.Fatalf("DeleteExpression returned error: %v", err)
+	}
+	if len(tp.paused) != 0 {
+		t.Errorf("Expected no paused expressions, got %v", tp.paused)
+	}
+}
+
+func TestGetProgress(t *testing.T) {
+	tp := NewTaskPool()
+	tasks := []entities.Task{
+		{ID: "root", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "left"}}, ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "right"}}, Operation: "+"},
+		{ID: "left", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "*"},
+		{ID: "right", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 4}, Operation: "*"},
+	}
+	if err := tp.AddTasks(tasks); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+
+	progress, started, err := tp.GetProgress("expr")
+	if err != nil || progress != (entities.Progress{Total: 3, Ready: 2}) || !started.IsZero() {
+		t.Errorf("Expected 2 ready of 3 tasks, not started, got %+v, %v, %v", progress, started, err)
+	}
+
+	before := time.Now()
+	leased, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil {
+		t.Fatalf("GetTaskToCompute returned error: %v", err)
+	}
+	progress, started, _ = tp.GetProgress("expr")
+	if progress != (entities.Progress{Total: 3, Ready: 1, InFlight: 1}) || started.Before(before) {
+		t.Errorf("Expected 1 ready and 1 leased task, started after %v, got %+v, %v", before, progress, started)
+	}
+
+	if err = tp.SetTaskResultAfterCompute(entities.TaskResult{ID: leased.ID, Result: 2}); err != nil {
+		t.Fatalf("SetTaskResultAfterCompute returned error: %v", err)
+	}
+	if err = tp.DeleteTask(leased.ID); err != nil {
+		t.Fatalf("DeleteTask returned error: %v", err)
+	}
+	progress, _, _ = tp.GetProgress("expr")
+	if progress != (entities.Progress{Total: 3, Ready: 1, Completed: 1}) {
+		t.Errorf("Expected 1 ready and 1 completed task, got %+v", progress)
+	}
+
+	if progress, _, _ = tp.GetProgress("unknown"); progress != (entities.Progress{}) {
+		t.Errorf("Expected no tasks of an unknown expression, got %+v", progress)
+	}
+
+	// the progress of a deleted expression is forgotten
+	if err = tp.DeleteExpression("expr"); err != nil {
+		t.Fatalf("DeleteExpression returned error: %v", err)
+	}
+	if len(tp.completed) != 0 || len(tp.started) != 0 {
+		t.Errorf("Expected the progress of the deleted expression to be forgotten, got completed %v, started %v", tp.completed, tp.started)
+	}
+}
+
+// TestForgetProgress checks that the progress of a completed expression, which
+// is deleted, and of a cancelled or failed one, which is cancelled, is forgotten.
+func TestForgetProgress(t *testing.T) {
+	for name, finish := range map[string]func(tp *TaskPool) error{
+		"completed": func(tp *TaskPool) error { return tp.DeleteExpression("expr") },
+		"cancelled": func(tp *TaskPool) error { return tp.CancelExpression("expr") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			tp := NewTaskPool()
+			tasks := []entities.Task{
+				{ID: "root", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "left"}}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3}, Operation: "*"},
+				{ID: "left", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "+"},
+			}
+			if err := tp.AddTasks(tasks); err != nil {
+				t.Fatalf("AddTasks returned error: %v", err)
+			}
+			if _, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err != nil {
+				t.Fatalf("GetTaskToCompute returned error: %v", err)
+			}
+			if err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "left", Result: 3}); err != nil {
+				t.Fatalf("SetTaskResultAfterCompute returned error: %v", err)
+			}
+			if err := tp.DeleteTask("left"); err != nil {
+				t.Fatalf("DeleteTask returned error: %v", err)
+			}
+
+			if err := finish(tp); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(tp.completed) != 0 || len(tp.started) != 0 {
+				t.Errorf("Expected the progress to be forgotten, got completed %v, started %v", tp.completed, tp.started)
+			}
+		})
+	}
+}
+
+func TestGetTaskToComputeOrder(t *testing.T) {
+	tasktest.TestGetTaskToComputeOrder(t, func(t *testing.T) scheduler.TaskService { return NewTaskPool() },
+		func(t *testing.T, ts scheduler.TaskService, exprID string, since time.Time) {
+			ts.(*TaskPool).queues[exprID].waitingSince = since
+		})
+}
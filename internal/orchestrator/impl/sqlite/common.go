@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"database/sql"
+	"fmt"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -21,19 +22,51 @@ func NewSQLiteDB(dbPath string) (*SQLiteDB, error) {
         CREATE TABLE IF NOT EXISTS expressions (
             id TEXT PRIMARY KEY,
             expression TEXT,
+            variables TEXT,
+            angle_unit TEXT NOT NULL DEFAULT 'radians',
+            notation TEXT NOT NULL DEFAULT 'infix',
+            dialect TEXT NOT NULL DEFAULT 'strict',
+            locale TEXT NOT NULL DEFAULT '',
+            mode TEXT NOT NULL DEFAULT 'float',
+            scale INTEGER NOT NULL DEFAULT 0,
             status TEXT,
-            result REAL
+            result REAL,
+            exact_result TEXT NOT NULL DEFAULT '',
+            tasks_saved INTEGER NOT NULL DEFAULT 0,
+            strict_order INTEGER NOT NULL DEFAULT 0,
+            priority INTEGER NOT NULL DEFAULT 0,
+            depth INTEGER NOT NULL DEFAULT 0,
+            bindings TEXT,
+            exact_bindings TEXT,
+            imag REAL NOT NULL DEFAULT 0,
+            to_unit TEXT NOT NULL DEFAULT '',
+            unit TEXT NOT NULL DEFAULT '',
+            error TEXT
+        );
+        CREATE TABLE IF NOT EXISTS functions (
+            name TEXT PRIMARY KEY,
+            params TEXT,
+            definition TEXT
         );
         CREATE TABLE IF NOT EXISTS tasks (
             id TEXT PRIMARY KEY,
             expr_id TEXT,
             arg_left TEXT,
             arg_right TEXT,
+            arg_then TEXT,
+            arg_else TEXT,
             operation TEXT,
-            result REAL
+            kind INTEGER NOT NULL DEFAULT 0,
+            mode TEXT NOT NULL DEFAULT 'float',
+            scale INTEGER NOT NULL DEFAULT 0,
+            guard TEXT NOT NULL DEFAULT '',
+            bindings TEXT,
+            result REAL,
+            lease INTEGER NOT NULL DEFAULT 0
         );
         CREATE TABLE IF NOT EXISTS sent_tasks (
-            task_id TEXT PRIMARY KEY
+            task_id TEXT PRIMARY KEY,
+            deadline INTEGER NOT NULL DEFAULT 0
         );
         CREATE TABLE IF NOT EXISTS task_owners (
             child_id TEXT,
@@ -44,10 +77,93 @@ func NewSQLiteDB(dbPath string) (*SQLiteDB, error) {
             task_id TEXT PRIMARY KEY,
             expr_id TEXT
         );
+        CREATE TABLE IF NOT EXISTS expression_results (
+            expr_id TEXT PRIMARY KEY,
+            result TEXT
+        );
+        CREATE TABLE IF NOT EXISTS expression_progress (
+            expr_id TEXT PRIMARY KEY,
+            completed INTEGER NOT NULL DEFAULT 0,
+            started INTEGER NOT NULL DEFAULT 0
+        );
+        CREATE TABLE IF NOT EXISTS cancelled_tasks (
+            task_id TEXT PRIMARY KEY
+        );
+        CREATE TABLE IF NOT EXISTS paused_expressions (
+            expr_id TEXT PRIMARY KEY
+        );
+        CREATE TABLE IF NOT EXISTS expression_queue (
+            expr_id TEXT PRIMARY KEY,
+            priority INTEGER NOT NULL DEFAULT 0,
+            waiting_since INTEGER NOT NULL DEFAULT 0,
+            turn INTEGER NOT NULL DEFAULT 0
+        );
     `)
 	if err != nil {
 		return nil, err
 	}
 
+	if err = migrate(db); err != nil {
+		return nil, err
+	}
+
 	return &SQLiteDB{DB: db}, nil
 }
+
+// columns lists the columns added after the initial schema.
+// They are created in databases made by older versions.
+var columns = []struct {
+	table      string
+	name       string
+	definition string
+}{
+	{"expressions", "angle_unit", "TEXT NOT NULL DEFAULT 'radians'"},
+	{"expressions", "variables", "TEXT"},
+	{"tasks", "kind", "INTEGER NOT NULL DEFAULT 0"},
+	{"expressions", "mode", "TEXT NOT NULL DEFAULT 'float'"},
+	{"expressions", "scale", "INTEGER NOT NULL DEFAULT 0"},
+	{"expressions", "exact_result", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "mode", "TEXT NOT NULL DEFAULT 'float'"},
+	{"tasks", "scale", "INTEGER NOT NULL DEFAULT 0"},
+	{"tasks", "arg_then", "TEXT"},
+	{"tasks", "arg_else", "TEXT"},
+	{"tasks", "guard", "TEXT NOT NULL DEFAULT ''"},
+	{"expressions", "tasks_saved", "INTEGER NOT NULL DEFAULT 0"},
+	{"expressions", "strict_order", "INTEGER NOT NULL DEFAULT 0"},
+	{"expressions", "depth", "INTEGER NOT NULL DEFAULT 0"},
+	{"expressions", "bindings", "TEXT"},
+	{"expressions", "exact_bindings", "TEXT"},
+	{"tasks", "bindings", "TEXT"},
+	{"expressions", "imag", "REAL NOT NULL DEFAULT 0"},
+	{"expressions", "to_unit", "TEXT NOT NULL DEFAULT ''"},
+	{"expressions", "unit", "TEXT NOT NULL DEFAULT ''"},
+	{"expressions", "dialect", "TEXT NOT NULL DEFAULT 'strict'"},
+	{"expressions", "locale", "TEXT NOT NULL DEFAULT ''"},
+	{"expressions", "notation", "TEXT NOT NULL DEFAULT 'infix'"},
+	{"tasks", "lease", "INTEGER NOT NULL DEFAULT 0"},
+	// the tasks sent before there were leases expire at once
+	{"sent_tasks", "deadline", "INTEGER NOT NULL DEFAULT 0"},
+	{"expressions", "error", "TEXT"},
+	{"expressions", "priority", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+func migrate(db *sql.DB) error {
+	for _, column := range columns {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?",
+			column.table, column.name).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+			column.table, column.name, column.definition))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
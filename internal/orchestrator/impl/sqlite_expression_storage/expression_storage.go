@@ -2,9 +2,12 @@ package sqlite_expression_storage
 
 import (
 	"calculator/internal/orchestrator/impl/sqlite"
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
 	"calculator/internal/shared/entities"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type Storage struct {
@@ -15,24 +18,33 @@ func NewStorage(db *sqlite.SQLiteDB) *Storage {
 	return &Storage{db: db}
 }
 
-func (s *Storage) CreateExpression(id string, expr string) error {
-	_, err := s.db.Exec("INSERT INTO expressions (id, expression, status, result) VALUES (?, ?, ?, ?)",
-		id, expr, entities.ExpressionStatusPending, 0)
+func (s *Storage) CreateExpression(expr *entities.Expression) error {
+	variables, _ := json.Marshal(expr.Variables)
+	bindings, _ := json.Marshal(expr.Bindings)
+	exactBindings, _ := json.Marshal(expr.ExactBindings)
+
+	_, err := s.db.Exec("INSERT INTO expressions (id, expression, variables, angle_unit, notation, dialect, locale, mode, scale, tasks_saved, strict_order, priority, depth, bindings, exact_bindings, to_unit, unit, status, result) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		expr.ID, expr.Expression, variables, expr.AngleUnit, expr.Notation, expr.Dialect, expr.Locale, expr.Mode, expr.Scale, expr.TasksSaved, expr.StrictOrder, expr.Priority, expr.Depth, bindings, exactBindings, expr.To, expr.Unit, entities.ExpressionStatusPending, 0)
 	return err
 }
 
 func (s *Storage) GetExpression(id string) (*entities.Expression, error) {
 	var expr entities.Expression
-	err := s.db.QueryRow("SELECT id, expression, status, result FROM expressions WHERE id = ?", id).
-		Scan(&expr.ID, &expr.Expression, &expr.Status, &expr.Result)
+	var variables, bindings, exactBindings, exprError []byte
+	err := s.db.QueryRow("SELECT id, expression, variables, angle_unit, notation, dialect, locale, mode, scale, tasks_saved, strict_order, priority, depth, bindings, exact_bindings, to_unit, unit, status, result, imag, exact_result, error FROM expressions WHERE id = ?", id).
+		Scan(&expr.ID, &expr.Expression, &variables, &expr.AngleUnit, &expr.Notation, &expr.Dialect, &expr.Locale, &expr.Mode, &expr.Scale, &expr.TasksSaved, &expr.StrictOrder, &expr.Priority, &expr.Depth, &bindings, &exactBindings, &expr.To, &expr.Unit, &expr.Status, &expr.Result, &expr.Imag, &expr.ExactResult, &exprError)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("expression not found")
+		return nil, use_cases_errors.ErrExpressionNotFound
 	}
+	json.Unmarshal(variables, &expr.Variables)
+	json.Unmarshal(bindings, &expr.Bindings)
+	json.Unmarshal(exactBindings, &expr.ExactBindings)
+	unmarshalError(exprError, &expr)
 	return &expr, err
 }
 
 func (s *Storage) GetExpressions() ([]entities.Expression, error) {
-	rows, err := s.db.Query("SELECT id, expression, status, result FROM expressions ORDER BY id")
+	rows, err := s.db.Query("SELECT id, expression, variables, angle_unit, notation, dialect, locale, mode, scale, tasks_saved, strict_order, priority, depth, bindings, exact_bindings, to_unit, unit, status, result, imag, exact_result, error FROM expressions ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
@@ -41,17 +53,155 @@ func (s *Storage) GetExpressions() ([]entities.Expression, error) {
 	var expressions []entities.Expression
 	for rows.Next() {
 		var expr entities.Expression
-		err := rows.Scan(&expr.ID, &expr.Expression, &expr.Status, &expr.Result)
+		var variables, bindings, exactBindings, exprError []byte
+		err := rows.Scan(&expr.ID, &expr.Expression, &variables, &expr.AngleUnit, &expr.Notation, &expr.Dialect, &expr.Locale, &expr.Mode, &expr.Scale, &expr.TasksSaved, &expr.StrictOrder, &expr.Priority, &expr.Depth, &bindings, &exactBindings, &expr.To, &expr.Unit, &expr.Status, &expr.Result, &expr.Imag, &expr.ExactResult, &exprError)
 		if err != nil {
 			return nil, err
 		}
+		json.Unmarshal(variables, &expr.Variables)
+		json.Unmarshal(bindings, &expr.Bindings)
+		json.Unmarshal(exactBindings, &expr.ExactBindings)
+		unmarshalError(exprError, &expr)
 		expressions = append(expressions, expr)
 	}
 	return expressions, nil
 }
 
-func (s *Storage) UpdateExpression(id string, status entities.ExpressionStatus, result float64) error {
-	_, err := s.db.Exec("UPDATE expressions SET status = ?, result = ? WHERE id = ?",
-		status, result, id)
+// UpdateExpression updates the status and result of an arithmetic expression
+// that is not completed, failed or cancelled yet.
+func (s *Storage) UpdateExpression(id string, status entities.ExpressionStatus, result entities.TaskResult) error {
+	_, err := s.db.Exec("UPDATE expressions SET status = ?, result = ?, imag = ?, exact_result = ? WHERE id = ? AND status NOT IN (?, ?, ?)",
+		status, result.Result, result.Imag, result.Exact, id,
+		entities.ExpressionStatusCompleted, entities.ExpressionStatusFailed, entities.ExpressionStatusCancelled)
+	return err
+}
+
+// ChangeStatus sets the status of the expression if it has one of the
+// statuses from. It reports whether the status was changed.
+func (s *Storage) ChangeStatus(id string, status entities.ExpressionStatus, from ...entities.ExpressionStatus) (bool, error) {
+	args := []any{status, id}
+	placeholders := make([]string, len(from))
+	for i, f := range from {
+		placeholders[i] = "?"
+		args = append(args, f)
+	}
+	res, err := s.db.Exec("UPDATE expressions SET status = ? WHERE id = ? AND status IN ("+strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		return false, err
+	}
+	changed, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if changed > 0 {
+		return true, nil
+	}
+
+	var exists bool
+	if err = s.db.QueryRow("SELECT EXISTS (SELECT 1 FROM expressions WHERE id = ?)", id).Scan(&exists); err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, use_cases_errors.ErrExpressionNotFound
+	}
+	return false, nil
+}
+
+// FailExpression marks the expression failed with the error of its task,
+// unless it is completed, failed or cancelled already.
+func (s *Storage) FailExpression(id string, exprError entities.ExpressionError) error {
+	errorBytes, _ := json.Marshal(exprError)
+	_, err := s.db.Exec("UPDATE expressions SET status = ?, error = ? WHERE id = ? AND status NOT IN (?, ?, ?)",
+		entities.ExpressionStatusFailed, errorBytes, id,
+		entities.ExpressionStatusCompleted, entities.ExpressionStatusFailed, entities.ExpressionStatusCancelled)
+	return err
+}
+
+// unmarshalError sets the error of a failed expression, the others have none.
+func unmarshalError(data []byte, expr *entities.Expression) {
+	if len(data) == 0 {
+		return
+	}
+	expr.Error = &entities.ExpressionError{}
+	json.Unmarshal(data, expr.Error)
+}
+
+// UpdateBindings records the result as the value of the names assigned in the expression.
+func (s *Storage) UpdateBindings(id string, names []string, result entities.TaskResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var bindingsBytes, exactBindingsBytes []byte
+	err = tx.QueryRow("SELECT bindings, exact_bindings FROM expressions WHERE id = ?", id).
+		Scan(&bindingsBytes, &exactBindingsBytes)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("expression not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	var bindings map[string]float64
+	var exactBindings map[string]string
+	json.Unmarshal(bindingsBytes, &bindings)
+	json.Unmarshal(exactBindingsBytes, &exactBindings)
+	if bindings == nil {
+		bindings = map[string]float64{}
+	}
+	if exactBindings == nil {
+		exactBindings = map[string]string{}
+	}
+	for _, name := range names {
+		bindings[name] = result.Result
+		if result.Exact != "" {
+			exactBindings[name] = result.Exact
+		}
+	}
+
+	updatedBindings, err := json.Marshal(bindings)
+	if err != nil {
+		return err
+	}
+	var updatedExactBindings []byte
+	if len(exactBindings) > 0 {
+		updatedExactBindings, _ = json.Marshal(exactBindings)
+	}
+	_, err = tx.Exec("UPDATE expressions SET bindings = ?, exact_bindings = ? WHERE id = ?",
+		updatedBindings, updatedExactBindings, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveFunction stores the user-defined function, replacing the function with the same name.
+func (s *Storage) SaveFunction(fn *entities.Function) error {
+	params, _ := json.Marshal(fn.Params)
+	_, err := s.db.Exec("INSERT INTO functions (name, params, definition) VALUES (?, ?, ?) ON CONFLICT(name) DO UPDATE SET params = excluded.params, definition = excluded.definition",
+		fn.Name, params, fn.Definition)
 	return err
 }
+
+// GetFunctions returns the stored user-defined functions ordered by name.
+func (s *Storage) GetFunctions() ([]entities.Function, error) {
+	rows, err := s.db.Query("SELECT name, params, definition FROM functions ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var functions []entities.Function
+	for rows.Next() {
+		var fn entities.Function
+		var params []byte
+		if err := rows.Scan(&fn.Name, &params, &fn.Definition); err != nil {
+			return nil, err
+		}
+		json.Unmarshal(params, &fn.Params)
+		functions = append(functions, fn)
+	}
+	return functions, rows.Err()
+}
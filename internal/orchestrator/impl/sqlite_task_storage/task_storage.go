@@ -6,6 +6,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"time"
 )
 
 type TaskPool struct {
@@ -24,17 +26,19 @@ func (tp *TaskPool) AddTasks(tasks []entities.Task) error {
 	defer tx.Rollback()
 
 	for _, task := range tasks {
-		argLeft, _ := json.Marshal(task.ArgLeft)
-		argRight, _ := json.Marshal(task.ArgRight)
+		encoded, err := marshalAll(task.ArgLeft, task.ArgRight, task.ArgThen, task.ArgElse, task.Bindings)
+		if err != nil {
+			return err
+		}
 
-		_, err = tx.Exec("INSERT INTO tasks (id, expr_id, arg_left, arg_right, operation) VALUES (?, ?, ?, ?, ?)",
-			task.ID, task.ExprID, argLeft, argRight, task.Operation)
+		_, err = tx.Exec("INSERT INTO tasks (id, expr_id, arg_left, arg_right, arg_then, arg_else, operation, kind, mode, scale, guard, bindings) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			task.ID, task.ExprID, encoded[0], encoded[1], encoded[2], encoded[3], task.Operation, task.Kind, task.Mode, task.Scale, task.Guard, encoded[4])
 		if err != nil {
 			return err
 		}
 
 		if task.ArgLeft.ArgType == entities.IsTask {
-			_, err = tx.Exec("INSERT INTO task_owners (child_id, parent_id) VALUES (?, ?)",
+			_, err = tx.Exec("INSERT OR IGNORE INTO task_owners (child_id, parent_id) VALUES (?, ?)",
 				task.ArgLeft.ArgTask.ID, task.ID)
 			if err != nil {
 				return err
@@ -42,12 +46,26 @@ func (tp *TaskPool) AddTasks(tasks []entities.Task) error {
 		}
 
 		if task.ArgRight.ArgType == entities.IsTask {
-			_, err = tx.Exec("INSERT INTO task_owners (child_id, parent_id) VALUES (?, ?)",
+			_, err = tx.Exec("INSERT OR IGNORE INTO task_owners (child_id, parent_id) VALUES (?, ?)",
 				task.ArgRight.ArgTask.ID, task.ID)
 			if err != nil {
 				return err
 			}
 		}
+
+		if task.Kind != entities.TaskKindConditional {
+			continue
+		}
+		for _, arg := range []entities.Arg{task.ArgThen, task.ArgElse} {
+			if arg.ArgType != entities.IsTask {
+				continue
+			}
+			_, err = tx.Exec("INSERT OR IGNORE INTO task_owners (child_id, parent_id) VALUES (?, ?)",
+				arg.ArgTask.ID, task.ID)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	_, err = tx.Exec("INSERT INTO expressions_root (task_id, expr_id) VALUES (?, ?)",
@@ -55,23 +73,48 @@ func (tp *TaskPool) AddTasks(tasks []entities.Task) error {
 	if err != nil {
 		return err
 	}
+	_, err = tx.Exec("INSERT OR IGNORE INTO expression_queue (expr_id, priority, waiting_since) VALUES (?, ?, ?)",
+		tasks[0].ExprID, tasks[0].Priority, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
 
 	return tx.Commit()
 }
 
-func (tp *TaskPool) GetTaskToCompute() (entities.Task, error) {
+// dispatchable selects the tasks ready to be sent to an agent: they are not
+// leased, their arguments are computed and they are not inside an unchosen branch.
+const dispatchable = `id NOT IN (SELECT task_id FROM sent_tasks)
+        AND json_extract(arg_left, '$.ArgType') <> ?
+        AND json_extract(arg_right, '$.ArgType') <> ?
+        AND kind <> ?
+        AND guard = ''`
+
+// GetTaskToCompute leases the next task of the expression whose turn it is in expression_queue.
+func (tp *TaskPool) GetTaskToCompute(aging time.Duration, deadline func(task entities.Task) time.Time) (entities.Task, error) {
 	var task entities.Task
 	var argLeftBytes, argRightBytes []byte
 
-	err := tp.db.QueryRow(`
-        SELECT id, expr_id, arg_left, arg_right, operation
-        FROM tasks
-        WHERE id NOT IN (SELECT task_id FROM sent_tasks)
-        AND json_extract(arg_left, '$.ArgType') = ?
-        AND json_extract(arg_right, '$.ArgType') = ?
+	tx, err := tp.db.Begin()
+	if err != nil {
+		return entities.Task{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixMilli()
+	err = tx.QueryRow(`
+        SELECT id, t.expr_id, arg_left, arg_right, operation, kind, mode, scale, lease
+        FROM tasks t LEFT JOIN expression_queue q ON q.expr_id = t.expr_id
+        WHERE `+dispatchable+`
+        AND t.expr_id NOT IN (SELECT expr_id FROM paused_expressions)
+        ORDER BY COALESCE(q.priority, 0) + CASE
+                WHEN ?4 > 0 AND q.waiting_since > 0 THEN (?5 - q.waiting_since) / ?4
+                ELSE 0
+            END DESC,
+            COALESCE(q.turn, 0), q.rowid, id
         LIMIT 1
-    `, entities.IsNumber, entities.IsNumber).Scan(
-		&task.ID, &task.ExprID, &argLeftBytes, &argRightBytes, &task.Operation)
+    `, entities.IsTask, entities.IsTask, entities.TaskKindConditional, aging.Milliseconds(), now).Scan(
+		&task.ID, &task.ExprID, &argLeftBytes, &argRightBytes, &task.Operation, &task.Kind, &task.Mode, &task.Scale, &task.Lease)
 
 	if err != nil {
 		return entities.Task{}, fmt.Errorf("no tasks to compute")
@@ -80,61 +123,141 @@ func (tp *TaskPool) GetTaskToCompute() (entities.Task, error) {
 	json.Unmarshal(argLeftBytes, &task.ArgLeft)
 	json.Unmarshal(argRightBytes, &task.ArgRight)
 
-	_, err = tp.db.Exec("INSERT INTO sent_tasks (task_id) VALUES (?)", task.ID)
+	task.Lease++
+	_, err = tx.Exec("UPDATE tasks SET lease = ? WHERE id = ?", task.Lease, task.ID)
+	if err != nil {
+		return entities.Task{}, err
+	}
+	_, err = tx.Exec("INSERT INTO sent_tasks (task_id, deadline) VALUES (?, ?)", task.ID, deadline(task).UnixMilli())
+	if err != nil {
+		return entities.Task{}, err
+	}
+	_, err = tx.Exec("INSERT INTO expression_progress (expr_id, started) VALUES (?, ?) ON CONFLICT(expr_id) DO UPDATE SET started = excluded.started WHERE started = 0",
+		task.ExprID, now)
 	if err != nil {
 		return entities.Task{}, err
 	}
+	_, err = tx.Exec("UPDATE expression_queue SET waiting_since = ?, turn = (SELECT MAX(turn) + 1 FROM expression_queue) WHERE expr_id = ?",
+		now, task.ExprID)
+	if err != nil {
+		return entities.Task{}, err
+	}
+
+	return task, tx.Commit()
+}
 
-	return task, nil
+// HoldsLease checks the lease of the task against its row in sent_tasks.
+func (tp *TaskPool) HoldsLease(id string, lease int64) (bool, error) {
+	var current int64
+	var sent bool
+	err := tp.db.QueryRow("SELECT lease, EXISTS (SELECT 1 FROM sent_tasks WHERE task_id = id) FROM tasks WHERE id = ?", id).
+		Scan(&current, &sent)
+	if err != nil {
+		return false, fmt.Errorf("task %s not found", id)
+	}
+	return sent && current == lease, nil
+}
+
+// ExpireLeases deletes the rows of sent_tasks whose leases ended before now.
+func (tp *TaskPool) ExpireLeases(now time.Time) (int, error) {
+	res, err := tp.db.Exec("DELETE FROM sent_tasks WHERE deadline < ?", now.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	expired, err := res.RowsAffected()
+	return int(expired), err
 }
 
-func (tp *TaskPool) SetTaskResultAfterCompute(id string, result float64) error {
+// SetTaskResultAfterCompute passes the result of the task to every task that uses it.
+func (tp *TaskPool) SetTaskResultAfterCompute(result entities.TaskResult) error {
+	id := result.ID
 	tx, err := tp.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	var ownerID string
-	err = tx.QueryRow("SELECT parent_id FROM task_owners WHERE child_id = ?", id).Scan(&ownerID)
+	// the result of the root task is kept until the other tasks of the expression are computed
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT OR REPLACE INTO expression_results (expr_id, result) SELECT expr_id, ? FROM expressions_root WHERE task_id = ?",
+		resultBytes, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil // This is the root task
-		}
 		return err
 	}
 
-	var ownerArgLeft, ownerArgRight []byte
-	err = tx.QueryRow("SELECT arg_left, arg_right FROM tasks WHERE id = ?", ownerID).Scan(&ownerArgLeft, &ownerArgRight)
+	ownerIDs, err := tp.owners(tx, id)
 	if err != nil {
 		return err
 	}
 
-	var argLeft, argRight entities.Arg
-	json.Unmarshal(ownerArgLeft, &argLeft)
-	json.Unmarshal(ownerArgRight, &argRight)
+	// the root task and the tasks of the assigned names no task uses
+	// have no owners, there is nothing to update
+	for _, ownerID := range ownerIDs {
+		var ownerArgLeft, ownerArgRight, ownerArgThen, ownerArgElse []byte
+		err = tx.QueryRow("SELECT arg_left, arg_right, arg_then, arg_else FROM tasks WHERE id = ?", ownerID).
+			Scan(&ownerArgLeft, &ownerArgRight, &ownerArgThen, &ownerArgElse)
+		if err != nil {
+			return err
+		}
 
-	if argLeft.ArgType == entities.IsTask && argLeft.ArgTask.ID == id {
-		argLeft.ArgType = entities.IsNumber
-		argLeft.ArgFloat = result
-		updatedArgLeft, _ := json.Marshal(argLeft)
-		_, err = tx.Exec("UPDATE tasks SET arg_left = ? WHERE id = ?", updatedArgLeft, ownerID)
-	} else if argRight.ArgType == entities.IsTask && argRight.ArgTask.ID == id {
-		argRight.ArgType = entities.IsNumber
-		argRight.ArgFloat = result
-		updatedArgRight, _ := json.Marshal(argRight)
-		_, err = tx.Exec("UPDATE tasks SET arg_right = ? WHERE id = ?", updatedArgRight, ownerID)
+		var argLeft, argRight, argThen, argElse entities.Arg
+		json.Unmarshal(ownerArgLeft, &argLeft)
+		json.Unmarshal(ownerArgRight, &argRight)
+		json.Unmarshal(ownerArgThen, &argThen)
+		json.Unmarshal(ownerArgElse, &argElse)
+
+		for _, arg := range []*entities.Arg{&argLeft, &argRight, &argThen, &argElse} {
+			if arg.ArgType == entities.IsTask && arg.ArgTask != nil && arg.ArgTask.ID == id {
+				arg.ArgType = entities.IsNumber
+				arg.ArgFloat = result.Result
+				arg.ArgImag = result.Imag
+				arg.ArgExact = result.Exact
+			}
+		}
+
+		updated, err := marshalAll(argLeft, argRight, argThen, argElse)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE tasks SET arg_left = ?, arg_right = ?, arg_then = ?, arg_else = ? WHERE id = ?",
+			updated[0], updated[1], updated[2], updated[3], ownerID)
+		if err != nil {
+			return err
+		}
 	}
 
+	return tx.Commit()
+}
+
+// owners returns the IDs of the tasks that use the result of the task id.
+func (tp *TaskPool) owners(tx *sql.Tx, id string) ([]string, error) {
+	rows, err := tx.Query("SELECT parent_id FROM task_owners WHERE child_id = ?", id)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return tx.Commit()
+	var ownerIDs []string
+	for rows.Next() {
+		var ownerID string
+		if err := rows.Scan(&ownerID); err != nil {
+			return nil, err
+		}
+		ownerIDs = append(ownerIDs, ownerID)
+	}
+	return ownerIDs, rows.Err()
 }
 
+// DeleteTask deletes a computed task, it is counted as completed.
 func (tp *TaskPool) DeleteTask(id string) error {
-	_, err := tp.db.Exec("DELETE FROM tasks WHERE id = ?", id)
+	_, err := tp.db.Exec("INSERT INTO expression_progress (expr_id, completed) SELECT expr_id, 1 FROM tasks WHERE id = ? ON CONFLICT(expr_id) DO UPDATE SET completed = completed + 1", id)
+	if err != nil {
+		return err
+	}
+	_, err = tp.db.Exec("DELETE FROM tasks WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
@@ -146,15 +269,115 @@ func (tp *TaskPool) DeleteTask(id string) error {
 	return err
 }
 
+// DeleteExpression deletes a completed expression, its progress is forgotten.
 func (tp *TaskPool) DeleteExpression(id string) error {
-	_, err := tp.db.Exec("DELETE FROM expressions_root WHERE expr_id = ?", id)
+	for _, statement := range []string{
+		"DELETE FROM expressions_root WHERE expr_id = ?",
+		"DELETE FROM expression_results WHERE expr_id = ?",
+		"DELETE FROM expression_queue WHERE expr_id = ?",
+		"DELETE FROM expression_progress WHERE expr_id = ?",
+		"DELETE FROM paused_expressions WHERE expr_id = ?",
+	} {
+		if _, err := tp.db.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelExpression deletes the tasks of the expression, the leased ones are kept in cancelled_tasks.
+func (tp *TaskPool) CancelExpression(exprID string) error {
+	tx, err := tp.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"INSERT OR IGNORE INTO cancelled_tasks (task_id) SELECT id FROM tasks WHERE expr_id = ? AND lease > 0",
+		"DELETE FROM paused_expressions WHERE expr_id = ?",
+		"DELETE FROM expression_queue WHERE expr_id = ?",
+		"DELETE FROM sent_tasks WHERE task_id IN (SELECT id FROM tasks WHERE expr_id = ?)",
+		"DELETE FROM task_owners WHERE child_id IN (SELECT id FROM tasks WHERE expr_id = ?)",
+		"DELETE FROM expressions_root WHERE expr_id = ?",
+		"DELETE FROM expression_results WHERE expr_id = ?",
+		"DELETE FROM expression_progress WHERE expr_id = ?",
+		"DELETE FROM tasks WHERE expr_id = ?",
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement, exprID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetProgress counts the tasks of the expression and returns the time its
+// first task was leased, the zero time if none was.
+func (tp *TaskPool) GetProgress(exprID string) (entities.Progress, time.Time, error) {
+	var progress entities.Progress
+	var started int64
+	err := tp.db.QueryRow(`
+        SELECT
+            (SELECT COUNT(*) FROM tasks WHERE expr_id = ?),
+            (SELECT COUNT(*) FROM tasks WHERE expr_id = ? AND id IN (SELECT task_id FROM sent_tasks)),
+            (SELECT COUNT(*) FROM tasks WHERE expr_id = ? AND `+dispatchable+`),
+            COALESCE((SELECT completed FROM expression_progress WHERE expr_id = ?), 0),
+            COALESCE((SELECT started FROM expression_progress WHERE expr_id = ?), 0)
+    `, exprID, exprID, exprID, entities.IsTask, entities.IsTask, entities.TaskKindConditional, exprID, exprID).
+		Scan(&progress.Total, &progress.InFlight, &progress.Ready, &progress.Completed, &started)
+	if err != nil {
+		return entities.Progress{}, time.Time{}, err
+	}
+	progress.Total += progress.Completed
+	if started == 0 {
+		return progress, time.Time{}, nil
+	}
+	return progress, time.UnixMilli(started), nil
+}
+
+// IsCancelled looks the task up in cancelled_tasks.
+func (tp *TaskPool) IsCancelled(taskID string) (bool, error) {
+	var cancelled bool
+	err := tp.db.QueryRow("SELECT EXISTS (SELECT 1 FROM cancelled_tasks WHERE task_id = ?)", taskID).Scan(&cancelled)
+	return cancelled, err
+}
+
+// PauseExpression adds the expression to paused_expressions.
+func (tp *TaskPool) PauseExpression(exprID string) error {
+	_, err := tp.db.Exec("INSERT OR IGNORE INTO paused_expressions (expr_id) VALUES (?)", exprID)
+	return err
+}
+
+// ResumeExpression sends the tasks of the paused expression to agents again.
+// The time it was paused does not raise its priority.
+func (tp *TaskPool) ResumeExpression(exprID string) error {
+	_, err := tp.db.Exec("DELETE FROM paused_expressions WHERE expr_id = ?", exprID)
+	if err != nil {
+		return err
+	}
+	_, err = tp.db.Exec("UPDATE expression_queue SET waiting_since = ? WHERE expr_id = ?", time.Now().UnixMilli(), exprID)
 	return err
 }
 
-func (tp *TaskPool) IsLastTask(id string) (bool, error) {
-	var count int
-	err := tp.db.QueryRow("SELECT COUNT(*) FROM expressions_root WHERE task_id = ?", id).Scan(&count)
-	return count > 0, err
+// ExpressionResult reports whether the root task of the expression is
+// computed and no other task of it is left. It returns the result of the root task.
+func (tp *TaskPool) ExpressionResult(exprID string) (entities.TaskResult, bool, error) {
+	var resultBytes []byte
+	err := tp.db.QueryRow("SELECT result FROM expression_results WHERE expr_id = ?1 AND NOT EXISTS (SELECT 1 FROM tasks WHERE expr_id = ?1)", exprID).
+		Scan(&resultBytes)
+	if err == sql.ErrNoRows {
+		return entities.TaskResult{}, false, nil
+	}
+	if err != nil {
+		return entities.TaskResult{}, false, err
+	}
+
+	var result entities.TaskResult
+	if err = json.Unmarshal(resultBytes, &result); err != nil {
+		return entities.TaskResult{}, false, err
+	}
+	return result, true, nil
 }
 
 func (tp *TaskPool) GetExpressionIDByTaskID(taskID string) (string, error) {
@@ -165,3 +388,243 @@ func (tp *TaskPool) GetExpressionIDByTaskID(taskID string) (string, error) {
 	}
 	return exprID, nil
 }
+
+// GetBindings returns the names the script assigns the result of the task to.
+func (tp *TaskPool) GetBindings(taskID string) ([]string, error) {
+	var bindingsBytes []byte
+	err := tp.db.QueryRow("SELECT bindings FROM tasks WHERE id = ?", taskID).Scan(&bindingsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("task %s not found", taskID)
+	}
+
+	var bindings []string
+	json.Unmarshal(bindingsBytes, &bindings)
+	return bindings, nil
+}
+
+func (tp *TaskPool) GetReadyConditionals(exprID string) ([]entities.Task, error) {
+	rows, err := tp.db.Query(`
+        SELECT id, expr_id, arg_left, arg_right, arg_then, arg_else, operation, kind, mode, scale
+        FROM tasks
+        WHERE expr_id = ?
+        AND kind = ?
+        AND guard = ''
+        AND json_extract(arg_left, '$.ArgType') <> ?
+    `, exprID, entities.TaskKindConditional, entities.IsTask)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conditionals []entities.Task
+	for rows.Next() {
+		var task entities.Task
+		var argLeft, argRight, argThen, argElse []byte
+		err := rows.Scan(&task.ID, &task.ExprID, &argLeft, &argRight, &argThen, &argElse,
+			&task.Operation, &task.Kind, &task.Mode, &task.Scale)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(argLeft, &task.ArgLeft)
+		json.Unmarshal(argRight, &task.ArgRight)
+		json.Unmarshal(argThen, &task.ArgThen)
+		json.Unmarshal(argElse, &task.ArgElse)
+		conditionals = append(conditionals, task)
+	}
+	return conditionals, rows.Err()
+}
+
+func (tp *TaskPool) ChooseBranch(id string, condition bool) (entities.Arg, error) {
+	tx, err := tp.db.Begin()
+	if err != nil {
+		return entities.Arg{}, err
+	}
+	defer tx.Rollback()
+
+	var argThenBytes, argElseBytes, bindingsBytes []byte
+	var guard string
+	err = tx.QueryRow("SELECT arg_then, arg_else, guard, bindings FROM tasks WHERE id = ?", id).
+		Scan(&argThenBytes, &argElseBytes, &guard, &bindingsBytes)
+	if err != nil {
+		return entities.Arg{}, fmt.Errorf("task %s not found", id)
+	}
+
+	var chosen, rejected entities.Arg
+	json.Unmarshal(argThenBytes, &chosen)
+	json.Unmarshal(argElseBytes, &rejected)
+	if !condition {
+		chosen, rejected = rejected, chosen
+	}
+
+	if rejected.ArgType == entities.IsTask {
+		if err = tp.release(tx, rejected.ArgTask.ID, id); err != nil {
+			return entities.Arg{}, err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE tasks SET guard = ? WHERE guard = ?", guard, id)
+	if err != nil {
+		return entities.Arg{}, err
+	}
+
+	if chosen.ArgType != entities.IsTask {
+		return chosen, tx.Commit()
+	}
+
+	// the chosen task takes the place of the conditional task
+	chosenID := chosen.ArgTask.ID
+	ownerIDs, err := tp.owners(tx, id)
+	if err != nil {
+		return entities.Arg{}, err
+	}
+	if len(ownerIDs) == 0 {
+		_, err = tx.Exec("UPDATE expressions_root SET task_id = ? WHERE task_id = ?", chosenID, id)
+		if err != nil {
+			return entities.Arg{}, err
+		}
+	}
+	if err = tp.mergeBindings(tx, chosenID, bindingsBytes); err != nil {
+		return entities.Arg{}, err
+	}
+	for _, ownerID := range ownerIDs {
+		if err = tp.replaceArg(tx, ownerID, id, chosenID); err != nil {
+			return entities.Arg{}, err
+		}
+		_, err = tx.Exec("INSERT OR IGNORE INTO task_owners (child_id, parent_id) VALUES (?, ?)", chosenID, ownerID)
+		if err != nil {
+			return entities.Arg{}, err
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM tasks WHERE id = ?", id)
+	if err != nil {
+		return entities.Arg{}, err
+	}
+	_, err = tx.Exec("DELETE FROM sent_tasks WHERE task_id = ?", id)
+	if err != nil {
+		return entities.Arg{}, err
+	}
+	_, err = tx.Exec("DELETE FROM task_owners WHERE child_id = ? OR parent_id = ?", id, id)
+	if err != nil {
+		return entities.Arg{}, err
+	}
+
+	return chosen, tx.Commit()
+}
+
+// replaceArg makes the argument of the owner task that refers to the task oldID refer to newID.
+func (tp *TaskPool) replaceArg(tx *sql.Tx, ownerID, oldID, newID string) error {
+	for _, column := range []string{"arg_left", "arg_right", "arg_then", "arg_else"} {
+		var argBytes []byte
+		err := tx.QueryRow(fmt.Sprintf("SELECT %s FROM tasks WHERE id = ?", column), ownerID).Scan(&argBytes)
+		if err != nil {
+			return err
+		}
+
+		var arg entities.Arg
+		json.Unmarshal(argBytes, &arg)
+		if arg.ArgType != entities.IsTask || arg.ArgTask == nil || arg.ArgTask.ID != oldID {
+			continue
+		}
+
+		arg.ArgTask = &entities.Task{ID: newID}
+		updatedArg, err := json.Marshal(arg)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(fmt.Sprintf("UPDATE tasks SET %s = ? WHERE id = ?", column), updatedArg, ownerID)
+		return err
+	}
+	return fmt.Errorf("task %s owner not found", oldID)
+}
+
+// mergeBindings adds the names of the JSON list bindingsBytes to the names assigned to the task id.
+func (tp *TaskPool) mergeBindings(tx *sql.Tx, id string, bindingsBytes []byte) error {
+	var added []string
+	json.Unmarshal(bindingsBytes, &added)
+	if len(added) == 0 {
+		return nil
+	}
+
+	var currentBytes []byte
+	if err := tx.QueryRow("SELECT bindings FROM tasks WHERE id = ?", id).Scan(&currentBytes); err != nil {
+		return err
+	}
+	var bindings []string
+	json.Unmarshal(currentBytes, &bindings)
+	bindings = append(bindings, added...)
+	slices.Sort(bindings)
+
+	updated, err := json.Marshal(bindings)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE tasks SET bindings = ? WHERE id = ?", updated, id)
+	return err
+}
+
+// marshalAll encodes the values as JSON. A number that is not finite, like
+// the result of an overflow, cannot be encoded and fails instead of being
+// stored as null.
+func marshalAll(values ...any) ([][]byte, error) {
+	encoded := make([][]byte, len(values))
+	for i, value := range values {
+		var err error
+		if encoded[i], err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+	return encoded, nil
+}
+
+// release records that the task ownerID no longer uses the task id. The task
+// and the tasks it depends on are deleted when no other task uses them,
+// unless the task computes the value of an assigned name.
+func (tp *TaskPool) release(tx *sql.Tx, id, ownerID string) error {
+	_, err := tx.Exec("DELETE FROM task_owners WHERE child_id = ? AND parent_id = ?", id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	var argLeft, argRight, argThen, argElse, bindingsBytes []byte
+	err = tx.QueryRow("SELECT arg_left, arg_right, arg_then, arg_else, bindings FROM tasks WHERE id = ?", id).
+		Scan(&argLeft, &argRight, &argThen, &argElse, &bindingsBytes)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var owners int
+	if err = tx.QueryRow("SELECT COUNT(*) FROM task_owners WHERE child_id = ?", id).Scan(&owners); err != nil {
+		return err
+	}
+	var bindings []string
+	json.Unmarshal(bindingsBytes, &bindings)
+	if owners > 0 || len(bindings) > 0 {
+		return nil
+	}
+
+	for _, argBytes := range [][]byte{argLeft, argRight, argThen, argElse} {
+		var arg entities.Arg
+		json.Unmarshal(argBytes, &arg)
+		if arg.ArgType != entities.IsTask || arg.ArgTask == nil {
+			continue
+		}
+		if err = tp.release(tx, arg.ArgTask.ID, id); err != nil {
+			return err
+		}
+	}
+
+	for _, query := range []string{
+		"DELETE FROM tasks WHERE id = ?",
+		"DELETE FROM sent_tasks WHERE task_id = ?",
+		"DELETE FROM task_owners WHERE parent_id = ?",
+	} {
+		if _, err = tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,481 @@
+package sqlite_task_storage
+
+import (
+	"calculator/internal/orchestrator/impl/sqlite"
+	"calculator/internal/orchestrator/impl/tasktest"
+	"calculator/internal/orchestrator/use_cases/scheduler"
+	"calculator/internal/shared/entities"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTaskPool(t *testing.T) *TaskPool {
+	t.Helper()
+	db, err := sqlite.NewSQLiteDB(filepath.Join(t.TempDir(), "calculator.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteDB returned error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewTaskPool(db)
+}
+
+// getTask reads the arguments of the stored task, ok is false if there is no such task.
+func getTask(t *testing.T, tp *TaskPool, id string) (task entities.Task, ok bool) {
+	t.Helper()
+	var argLeft, argRight []byte
+	err := tp.db.QueryRow("SELECT arg_left, arg_right FROM tasks WHERE id = ?", id).Scan(&argLeft, &argRight)
+	if err == sql.ErrNoRows {
+		return entities.Task{}, false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	task.ID = id
+	json.Unmarshal(argLeft, &task.ArgLeft)
+	json.Unmarshal(argRight, &task.ArgRight)
+	return task, true
+}
+
+// getOwners returns the sorted IDs of the tasks that use the result of the task id.
+func getOwners(t *testing.T, tp *TaskPool, id string) []string {
+	t.Helper()
+	tx, err := tp.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	owners, err := tp.owners(tx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(owners)
+	return owners
+}
+
+// count returns the number of rows of the table that match the condition.
+func count(t *testing.T, tp *TaskPool, table, condition string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := tp.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE "+condition, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSetTaskResultAfterComputeShared(t *testing.T) {
+	// (a*b + c) / (a*b - c), the product feeds both the sum and the difference and is squared
+	product := entities.Task{ID: "product", ExprID: "expr", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3}}
+	sum := entities.Task{ID: "sum", ExprID: "expr", Operation: "+",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}}
+	difference := entities.Task{ID: "difference", ExprID: "expr", Operation: "-",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}}
+	square := entities.Task{ID: "square", ExprID: "expr", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &product}}
+	quotient := entities.Task{ID: "quotient", ExprID: "expr", Operation: "/",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &sum},
+		ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &difference}}
+
+	tp := newTaskPool(t)
+	if err := tp.AddTasks([]entities.Task{quotient, product, sum, difference, square}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+	if owners := getOwners(t, tp, "product"); !slices.Equal(owners, []string{"difference", "square", "sum"}) {
+		t.Fatalf("Expected the product to have three owners, got %v", owners)
+	}
+
+	if err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "product", Result: 6}); err != nil {
+		t.Fatalf("SetTaskResultAfterCompute returned error: %v", err)
+	}
+	for _, id := range []string{"sum", "difference", "square"} {
+		task, _ := getTask(t, tp, id)
+		if task.ArgLeft.ArgType != entities.IsNumber || task.ArgLeft.ArgFloat != 6 {
+			t.Errorf("Expected the result to be passed to %s, got %v", id, task.ArgLeft)
+		}
+	}
+	if task, _ := getTask(t, tp, "square"); task.ArgRight.ArgType != entities.IsNumber || task.ArgRight.ArgFloat != 6 {
+		t.Errorf("Expected the result to be passed to both arguments, got %v", task.ArgRight)
+	}
+}
+
+func TestChooseBranchShared(t *testing.T) {
+	// if(1, 2 * 3, 2 * 3 + 1) * (2 * 3), the product is shared by both branches and the root
+	product := entities.Task{ID: "product", ExprID: "expr", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3}}
+	increment := entities.Task{ID: "else", ExprID: "expr", Guard: "if", Operation: "+",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}}
+	conditional := entities.Task{ID: "if", ExprID: "expr", Kind: entities.TaskKindConditional, Operation: "if",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1},
+		ArgRight: entities.Arg{ArgType: entities.IsEmpty},
+		ArgThen:  entities.Arg{ArgType: entities.IsTask, ArgTask: &product},
+		ArgElse:  entities.Arg{ArgType: entities.IsTask, ArgTask: &increment}}
+	root := entities.Task{ID: "root", ExprID: "expr", Operation: "*",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &conditional},
+		ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &product}}
+
+	tp := newTaskPool(t)
+	if err := tp.AddTasks([]entities.Task{root, conditional, product, increment}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+
+	chosen, err := tp.ChooseBranch("if", true)
+	if err != nil {
+		t.Fatalf("ChooseBranch returned error: %v", err)
+	}
+	if chosen.ArgType != entities.IsTask || chosen.ArgTask.ID != "product" {
+		t.Errorf("Expected the then branch to be chosen, got %v", chosen)
+	}
+	for _, id := range []string{"if", "else"} {
+		if _, ok := getTask(t, tp, id); ok {
+			t.Errorf("Expected task %s to be deleted", id)
+		}
+	}
+	// the product is kept, the deleted branch no longer uses it
+	if owners := getOwners(t, tp, "product"); !slices.Equal(owners, []string{"root"}) {
+		t.Errorf("Expected the root task to be the only owner of the product, got %v", owners)
+	}
+
+	task, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil || task.ID != "product" {
+		t.Fatalf("Expected the product to be sent, got %+v, %v", task, err)
+	}
+	if err = tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "product", Result: 6}); err != nil {
+		t.Fatalf("SetTaskResultAfterCompute returned error: %v", err)
+	}
+	if task, _ := getTask(t, tp, "root"); task.ArgLeft.ArgFloat != 6 || task.ArgRight.ArgFloat != 6 {
+		t.Errorf("Expected the root task to get the product twice, got %+v", task)
+	}
+}
+
+func TestExpressionResult(t *testing.T) {
+	// a = 1 + 2; b = a * 4; a, the result is computed before the value of b
+	root := entities.Task{ID: "a", ExprID: "expr", Operation: "+", Bindings: []string{"a"},
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}}
+	unused := entities.Task{ID: "b", ExprID: "expr", Operation: "*", Bindings: []string{"b"},
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &root},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 4}}
+
+	tp := newTaskPool(t)
+	if err := tp.AddTasks([]entities.Task{root, unused}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+	for _, result := range []entities.TaskResult{{ID: "a", Result: 3}, {ID: "b", Result: 12}} {
+		if _, computed, err := tp.ExpressionResult("expr"); err != nil || computed {
+			t.Fatalf("Expected the expression not to be computed before task %s, got %v, %v", result.ID, computed, err)
+		}
+		if err := tp.SetTaskResultAfterCompute(result); err != nil {
+			t.Fatalf("SetTaskResultAfterCompute returned error: %v", err)
+		}
+		if err := tp.DeleteTask(result.ID); err != nil {
+			t.Fatalf("DeleteTask returned error: %v", err)
+		}
+	}
+
+	result, computed, err := tp.ExpressionResult("expr")
+	if err != nil || !computed || result.Result != 3 {
+		t.Errorf("Expected the expression computed with the result of the root task, got %+v, %v, %v", result, computed, err)
+	}
+	if err = tp.DeleteExpression("expr"); err != nil {
+		t.Fatalf("DeleteExpression returned error: %v", err)
+	}
+	if _, computed, _ = tp.ExpressionResult("expr"); computed {
+		t.Errorf("Expected the result of a deleted expression to be forgotten")
+	}
+}
+
+// TestSetTaskResultAfterComputeNotFinite checks that a result that cannot be
+// stored is rejected instead of being passed on as 0.
+func TestSetTaskResultAfterComputeNotFinite(t *testing.T) {
+	left := entities.Task{ID: "left", ExprID: "expr", Operation: "^",
+		ArgLeft:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1024}}
+	root := entities.Task{ID: "root", ExprID: "expr", Operation: "+",
+		ArgLeft:  entities.Arg{ArgType: entities.IsTask, ArgTask: &left},
+		ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}}
+
+	tp := newTaskPool(t)
+	if err := tp.AddTasks([]entities.Task{root, left}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+	if err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "left", Result: math.Inf(1)}); err == nil {
+		t.Errorf("Expected error for an infinite result")
+	}
+	if task, _ := getTask(t, tp, "root"); task.ArgLeft.ArgType != entities.IsTask {
+		t.Errorf("Expected the root task to still wait for its argument, got %+v", task.ArgLeft)
+	}
+
+	if err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "root", Result: math.NaN()}); err == nil {
+		t.Errorf("Expected error for a result that is not a number")
+	}
+	if n := count(t, tp, "expression_results", "expr_id = ?", "expr"); n != 0 {
+		t.Errorf("Expected no result to be stored, got %d rows", n)
+	}
+}
+
+func TestLeases(t *testing.T) {
+	tp := newTaskPool(t)
+	task := entities.Task{
+		ID:        "task",
+		ExprID:    "expr",
+		ArgLeft:   entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1},
+		ArgRight:  entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2},
+		Operation: "+",
+	}
+	if err := tp.AddTasks([]entities.Task{task}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+
+	now := time.Now()
+	first, err := tp.GetTaskToCompute(0, func(entities.Task) time.Time { return now.Add(time.Second) })
+	if err != nil || first.Lease != 1 {
+		t.Fatalf("Expected the first lease, got %+v, %v", first, err)
+	}
+	if _, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err == nil {
+		t.Errorf("Expected the leased task not to be sent again")
+	}
+
+	// the lease has not ended yet
+	if expired, err := tp.ExpireLeases(now); err != nil || expired != 0 {
+		t.Errorf("Expected no expired leases, got %d, %v", expired, err)
+	}
+	if expired, err := tp.ExpireLeases(now.Add(2 * time.Second)); err != nil || expired != 1 {
+		t.Errorf("Expected 1 expired lease, got %d, %v", expired, err)
+	}
+	if held, err := tp.HoldsLease("task", first.Lease); err != nil || held {
+		t.Errorf("Expected the expired lease not to be held, got %v, %v", held, err)
+	}
+
+	second, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil || second.Lease != 2 {
+		t.Fatalf("Expected the second lease, got %+v, %v", second, err)
+	}
+	if held, _ := tp.HoldsLease("task", first.Lease); held {
+		t.Errorf("Expected the first lease to be fenced off by the second")
+	}
+	if held, _ := tp.HoldsLease("task", second.Lease); !held {
+		t.Errorf("Expected the second lease to be held")
+	}
+	if _, err := tp.HoldsLease("unknown", 1); err == nil {
+		t.Errorf("Expected error for an unknown task")
+	}
+}
+
+func TestGetProgress(t *testing.T) {
+	tp := newTaskPool(t)
+	tasks := []entities.Task{
+		{ID: "root", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "left"}}, ArgRight: entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "right"}}, Operation: "+"},
+		{ID: "left", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "*"},
+		{ID: "right", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 4}, Operation: "*"},
+	}
+	if err := tp.AddTasks(tasks); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+
+	progress, started, err := tp.GetProgress("expr")
+	if err != nil || progress != (entities.Progress{Total: 3, Ready: 2}) || !started.IsZero() {
+		t.Errorf("Expected 2 ready of 3 tasks, not started, got %+v, %v, %v", progress, started, err)
+	}
+
+	// the start is stored in milliseconds
+	before := time.Now().Truncate(time.Millisecond)
+	leased, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil {
+		t.Fatalf("GetTaskToCompute returned error: %v", err)
+	}
+	progress, started, _ = tp.GetProgress("expr")
+	if progress != (entities.Progress{Total: 3, Ready: 1, InFlight: 1}) || started.Before(before) {
+		t.Errorf("Expected 1 ready and 1 leased task, started after %v, got %+v, %v", before, progress, started)
+	}
+
+	// the start does not move when the next task is leased
+	if _, err = tp.GetTaskToCompute(0, tasktest.LeaseForHour); err != nil {
+		t.Fatalf("GetTaskToCompute returned error: %v", err)
+	}
+	if _, again, _ := tp.GetProgress("expr"); !again.Equal(started) {
+		t.Errorf("Expected the start %v to be kept, got %v", started, again)
+	}
+
+	if err = tp.SetTaskResultAfterCompute(entities.TaskResult{ID: leased.ID, Result: 2}); err != nil {
+		t.Fatalf("SetTaskResultAfterCompute returned error: %v", err)
+	}
+	if err = tp.DeleteTask(leased.ID); err != nil {
+		t.Fatalf("DeleteTask returned error: %v", err)
+	}
+	progress, _, _ = tp.GetProgress("expr")
+	if progress != (entities.Progress{Total: 3, InFlight: 1, Completed: 1}) {
+		t.Errorf("Expected 1 leased and 1 completed task, got %+v", progress)
+	}
+
+	if progress, _, _ = tp.GetProgress("unknown"); progress != (entities.Progress{}) {
+		t.Errorf("Expected no tasks of an unknown expression, got %+v", progress)
+	}
+
+	// the progress of a deleted expression is forgotten
+	if err = tp.DeleteExpression("expr"); err != nil {
+		t.Fatalf("DeleteExpression returned error: %v", err)
+	}
+	if n := count(t, tp, "expression_progress", "expr_id = ?", "expr"); n != 0 {
+		t.Errorf("Expected the progress of the deleted expression to be forgotten, got %d rows", n)
+	}
+}
+
+// TestForgetProgress checks that the progress of a completed expression, which
+// is deleted, and of a cancelled or failed one, which is cancelled, is forgotten.
+func TestForgetProgress(t *testing.T) {
+	for name, finish := range map[string]func(tp *TaskPool) error{
+		"completed": func(tp *TaskPool) error { return tp.DeleteExpression("expr") },
+		"cancelled": func(tp *TaskPool) error { return tp.CancelExpression("expr") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			tp := newTaskPool(t)
+			tasks := []entities.Task{
+				{ID: "root", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "left"}}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3}, Operation: "*"},
+				{ID: "left", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "+"},
+			}
+			if err := tp.AddTasks(tasks); err != nil {
+				t.Fatalf("AddTasks returned error: %v", err)
+			}
+			if _, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err != nil {
+				t.Fatalf("GetTaskToCompute returned error: %v", err)
+			}
+			if err := tp.SetTaskResultAfterCompute(entities.TaskResult{ID: "left", Result: 3}); err != nil {
+				t.Fatalf("SetTaskResultAfterCompute returned error: %v", err)
+			}
+			if err := tp.DeleteTask("left"); err != nil {
+				t.Fatalf("DeleteTask returned error: %v", err)
+			}
+
+			if err := finish(tp); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if n := count(t, tp, "expression_progress", "expr_id = ?", "expr"); n != 0 {
+				t.Errorf("Expected the progress to be forgotten, got %d rows", n)
+			}
+		})
+	}
+}
+
+func TestCancelExpression(t *testing.T) {
+	tp := newTaskPool(t)
+	tasks := []entities.Task{
+		{ID: "root", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsTask, ArgTask: &entities.Task{ID: "left"}}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 3}, Operation: "*"},
+		{ID: "left", ExprID: "expr", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 0}, Operation: "/"},
+	}
+	other := entities.Task{ID: "other", ExprID: "other", ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "+"}
+	if err := tp.AddTasks(tasks); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+	if err := tp.AddTasks([]entities.Task{other}); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+	for range 2 {
+		if _, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err != nil {
+			t.Fatalf("GetTaskToCompute returned error: %v", err)
+		}
+	}
+
+	if err := tp.CancelExpression("expr"); err != nil {
+		t.Fatalf("CancelExpression returned error: %v", err)
+	}
+	if n := count(t, tp, "tasks", "1"); n != 1 {
+		t.Errorf("Expected only the task of the other expression, got %d tasks", n)
+	}
+	if sent, owners, roots := count(t, tp, "sent_tasks", "1"), count(t, tp, "task_owners", "1"), count(t, tp, "expressions_root", "1"); sent != 1 || owners != 0 || roots != 1 {
+		t.Errorf("Expected the 2 cancelled tasks to be forgotten, got %d sent, %d owners, %d roots", sent, owners, roots)
+	}
+	if _, err := tp.HoldsLease("left", 1); err == nil {
+		t.Errorf("Expected error for a cancelled task")
+	}
+	// only the leased task may still be returned by an agent
+	for id, expected := range map[string]bool{"root": false, "left": true, "other": false} {
+		if cancelled, err := tp.IsCancelled(id); err != nil || cancelled != expected {
+			t.Errorf("Expected task %s cancelled %v, got %v, %v", id, expected, cancelled, err)
+		}
+	}
+
+	// the cancelled task is remembered after its lease ends
+	if _, err := tp.ExpireLeases(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatalf("ExpireLeases returned error: %v", err)
+	}
+	if cancelled, err := tp.IsCancelled("left"); err != nil || !cancelled {
+		t.Errorf("Expected the cancelled task to be remembered, got %v, %v", cancelled, err)
+	}
+}
+
+func TestPauseExpression(t *testing.T) {
+	tp := newTaskPool(t)
+	for _, exprID := range []string{"paused", "other", "cancelled"} {
+		task := entities.Task{ID: exprID, ExprID: exprID, ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "+"}
+		if err := tp.AddTasks([]entities.Task{task}); err != nil {
+			t.Fatalf("AddTasks returned error: %v", err)
+		}
+	}
+
+	for _, exprID := range []string{"paused", "cancelled"} {
+		if err := tp.PauseExpression(exprID); err != nil {
+			t.Fatalf("PauseExpression returned error: %v", err)
+		}
+	}
+	// pausing twice is the same as pausing once
+	if err := tp.PauseExpression("paused"); err != nil {
+		t.Fatalf("PauseExpression returned error: %v", err)
+	}
+	task, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour)
+	if err != nil || task.ID != "other" {
+		t.Fatalf("Expected the task of the other expression, got %+v, %v", task, err)
+	}
+	if task, err := tp.GetTaskToCompute(0, tasktest.LeaseForHour); err == nil {
+		t.Errorf("Expected the tasks of the paused expressions not to be sent, got %+v", task)
+	}
+	// the task is still ready, it waits for the expression to be resumed
+	if progress, _, _ := tp.GetProgress("paused"); progress != (entities.Progress{Total: 1, Ready: 1}) {
+		t.Errorf("Expected 1 ready task, got %+v", progress)
+	}
+
+	if err := tp.ResumeExpression("paused"); err != nil {
+		t.Fatalf("ResumeExpression returned error: %v", err)
+	}
+	if task, err = tp.GetTaskToCompute(0, tasktest.LeaseForHour); err != nil || task.ID != "paused" {
+		t.Errorf("Expected the task of the resumed expression, got %+v, %v", task, err)
+	}
+
+	// cancelling a paused expression forgets that it is paused
+	if err = tp.CancelExpression("cancelled"); err != nil {
+		t.Fatalf("CancelExpression returned error: %v", err)
+	}
+	if n := count(t, tp, "paused_expressions", "expr_id = ?", "cancelled"); n != 0 {
+		t.Errorf("Expected the cancelled expression not to be paused, got %d rows", n)
+	}
+
+	// an expression completed while it is paused is forgotten
+	if err = tp.PauseExpression("other"); err != nil {
+		t.Fatalf("PauseExpression returned error: %v", err)
+	}
+	if err = tp.DeleteExpression("other"); err != nil {
+		t.Fatalf("DeleteExpression returned error: %v", err)
+	}
+	if n := count(t, tp, "paused_expressions", "1"); n != 0 {
+		t.Errorf("Expected no paused expressions, got %d rows", n)
+	}
+}
+
+func TestGetTaskToComputeOrder(t *testing.T) {
+	tasktest.TestGetTaskToComputeOrder(t, func(t *testing.T) scheduler.TaskService { return newTaskPool(t) },
+		func(t *testing.T, ts scheduler.TaskService, exprID string, since time.Time) {
+			if _, err := ts.(*TaskPool).db.Exec("UPDATE expression_queue SET waiting_since = ? WHERE expr_id = ?", since.UnixMilli(), exprID); err != nil {
+				t.Fatal(err)
+			}
+		})
+}
@@ -0,0 +1,115 @@
+// Package tasktest holds the tests shared by the implementations of the task service.
+package tasktest
+
+import (
+	"calculator/internal/orchestrator/use_cases/scheduler"
+	"calculator/internal/shared/entities"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// LeaseForHour leases the tasks of the tests for an hour.
+func LeaseForHour(entities.Task) time.Time {
+	return time.Now().Add(time.Hour)
+}
+
+// AddReadyTasks adds an expression of n ready tasks named after it, like a1 and a2.
+func AddReadyTasks(t *testing.T, ts scheduler.TaskService, exprID string, priority, n int) {
+	t.Helper()
+	var tasks []entities.Task
+	for i := 1; i <= n; i++ {
+		tasks = append(tasks, entities.Task{ID: fmt.Sprintf("%s%d", exprID, i), ExprID: exprID, Priority: priority,
+			ArgLeft: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 1}, ArgRight: entities.Arg{ArgType: entities.IsNumber, ArgFloat: 2}, Operation: "+"})
+	}
+	if err := ts.AddTasks(tasks); err != nil {
+		t.Fatalf("AddTasks returned error: %v", err)
+	}
+}
+
+// ready is an expression of n ready tasks for AddReadyTasks.
+type ready struct {
+	exprID   string
+	priority int
+	n        int
+}
+
+// leaseAll leases the tasks until none is left and returns their IDs in order.
+func leaseAll(ts scheduler.TaskService, aging time.Duration) []string {
+	var ids []string
+	for {
+		task, err := ts.GetTaskToCompute(aging, LeaseForHour)
+		if err != nil {
+			return ids
+		}
+		ids = append(ids, task.ID)
+	}
+}
+
+// TestGetTaskToComputeOrder tests the order the tasks of several expressions
+// are leased in. newTaskService returns an empty task service, setWaitingSince
+// sets the time an expression of it started waiting for its next task to be leased.
+func TestGetTaskToComputeOrder(t *testing.T, newTaskService func(t *testing.T) scheduler.TaskService,
+	setWaitingSince func(t *testing.T, ts scheduler.TaskService, exprID string, since time.Time)) {
+	tests := []struct {
+		name        string
+		expressions []ready
+		aging       time.Duration
+		expected    []string
+	}{
+		{"priority", []ready{{"low", -1, 2}, {"default", 0, 2}, {"high", 5, 2}}, 0,
+			[]string{"high1", "high2", "default1", "default2", "low1", "low2"}},
+		// the large expression a does not hold back the small ones
+		{"round robin", []ready{{"a", 0, 4}, {"b", 0, 2}, {"c", 0, 1}}, time.Hour,
+			[]string{"a1", "b1", "c1", "a2", "b2", "a3", "a4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTaskService(t)
+			for _, expr := range tt.expressions {
+				AddReadyTasks(t, ts, expr.exprID, expr.priority, expr.n)
+			}
+			if ids := leaseAll(ts, tt.aging); !slices.Equal(ids, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+
+	t.Run("aging", func(t *testing.T) {
+		ts := newTaskService(t)
+		AddReadyTasks(t, ts, "low", 0, 2)
+		AddReadyTasks(t, ts, "high", 3, 3)
+
+		// without aging the high priority expression goes first
+		task, err := ts.GetTaskToCompute(0, LeaseForHour)
+		if err != nil || task.ID != "high1" {
+			t.Fatalf("Expected high1, got %+v, %v", task, err)
+		}
+
+		// the low priority expression waited long enough to rise above it
+		setWaitingSince(t, ts, "low", time.Now().Add(-4*time.Second))
+		if task, err = ts.GetTaskToCompute(time.Second, LeaseForHour); err != nil || task.ID != "low1" {
+			t.Fatalf("Expected low1 after aging, got %+v, %v", task, err)
+		}
+		// its wait starts over after its task is leased
+		expected := []string{"high2", "high3", "low2"}
+		if ids := leaseAll(ts, time.Second); !slices.Equal(ids, expected) {
+			t.Errorf("Expected %v, got %v", expected, ids)
+		}
+
+		// a paused expression does not rise while it is paused
+		AddReadyTasks(t, ts, "paused", 0, 1)
+		AddReadyTasks(t, ts, "urgent", 1, 1)
+		if err = ts.PauseExpression("paused"); err != nil {
+			t.Fatalf("PauseExpression returned error: %v", err)
+		}
+		setWaitingSince(t, ts, "paused", time.Now().Add(-time.Hour))
+		if err = ts.ResumeExpression("paused"); err != nil {
+			t.Fatalf("ResumeExpression returned error: %v", err)
+		}
+		if task, err = ts.GetTaskToCompute(time.Second, LeaseForHour); err != nil || task.ID != "urgent1" {
+			t.Errorf("Expected urgent1, got %+v, %v", task, err)
+		}
+	})
+}
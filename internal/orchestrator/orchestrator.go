@@ -9,6 +9,7 @@ import (
 	"calculator/internal/orchestrator/use_cases/scheduler"
 	"calculator/internal/orchestrator/web"
 	"calculator/internal/shared/configs"
+	"calculator/internal/shared/units"
 	"calculator/pkg/logger"
 	"calculator/pkg/metrics/entities"
 	"calculator/pkg/metrics/healthz"
@@ -38,6 +39,9 @@ type App struct {
 	grpcServer *grpc.Server
 	httpServer *http.Server
 	conf       *configs.Config
+	scheduler  *scheduler.Scheduler
+	// stopReaper stops returning the tasks of expired leases to agents.
+	stopReaper context.CancelFunc
 }
 
 // NewOrchestrator creates a new instance of the Orchestrator.
@@ -50,6 +54,10 @@ func New(conf *configs.Config) (*App, error) {
 	app := new(App)
 	app.conf = conf
 
+	if _, err := units.New(conf.Units); err != nil {
+		return nil, fmt.Errorf("invalid unit catalog: %v", err)
+	}
+
 	db, err := sqlite.NewSQLiteDB("calculator.db")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SQLite database: %v", err)
@@ -60,6 +68,7 @@ func New(conf *configs.Config) (*App, error) {
 	taskStorage := sqlite_task_storage.NewTaskPool(db)
 
 	scheduler := scheduler.NewScheduler(expressionStorage, taskStorage, app.conf)
+	app.scheduler = scheduler
 
 	// Setup HTTP server
 	httpHandler := handler.NewHandler(scheduler)
@@ -87,6 +96,11 @@ func New(conf *configs.Config) (*App, error) {
 }
 
 func (a *App) Run() error {
+	// Return the tasks of the agents that did not answer in time
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	a.stopReaper = stopReaper
+	go a.scheduler.ReapLeases(reaperCtx)
+
 	// Start HTTP server
 	go func() {
 		logger.Info("starting http server...")
@@ -106,6 +120,9 @@ func (a *App) Run() error {
 
 func (a *App) stop(ctx context.Context) error {
 	logger.Info("shutdowning server...")
+	if a.stopReaper != nil {
+		a.stopReaper()
+	}
 	err := a.httpServer.Shutdown(ctx)
 	if err != nil {
 		return fmt.Errorf("server was shutdown with error: %w", err)
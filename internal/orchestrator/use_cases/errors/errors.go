@@ -7,4 +7,21 @@ var (
 	ErrNoTasksAvailable   = errors.New("no tasks available")
 	ErrTaskNotFound       = errors.New("task not found")
 	ErrExpressionExists   = errors.New("expression already exists")
+	ErrInvalidAngleUnit   = errors.New("invalid angle unit")
+	ErrUnboundVariables   = errors.New("unbound variables")
+	ErrInvalidPrecision   = errors.New("invalid precision mode or scale")
+	ErrInvalidUnit        = errors.New("invalid unit")
+	ErrInvalidDialect     = errors.New("invalid dialect")
+	ErrInvalidNotation    = errors.New("invalid notation")
+	ErrInvalidPriority    = errors.New("invalid priority")
+	// ErrExpressionTooLarge and ErrExpressionTooComplex report an expression
+	// exceeding the configured limits.
+	ErrExpressionTooLarge   = errors.New("expression is too large")
+	ErrExpressionTooComplex = errors.New("expression is too complex")
+	// ErrLeaseExpired reports a result of a task whose lease ended, the task
+	// was sent to another agent.
+	ErrLeaseExpired = errors.New("task lease expired")
+	// ErrInvalidStatus reports an expression that cannot be cancelled, paused
+	// or resumed in its status, like a completed one.
+	ErrInvalidStatus = errors.New("invalid expression status")
 )
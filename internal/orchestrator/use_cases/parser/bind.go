@@ -0,0 +1,69 @@
+package parser
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"strconv"
+)
+
+// Bind replaces the variables of the expression tree with their values.
+// Names assigned in the script are left as variables and take precedence over
+// the given values. Every occurrence of a variable without a value is reported
+// as a SyntaxError that matches use_cases_errors.ErrUnboundVariables.
+func Bind(root *Node, variables map[string]float64) error {
+	var errs SyntaxErrors
+	bind(root, variables, nil, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// binding is a name assigned in the script.
+type binding struct {
+	name  string
+	outer *binding
+}
+
+func (b *binding) lookup(name string) *binding {
+	for ; b != nil; b = b.outer {
+		if b.name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+func bind(node *Node, variables map[string]float64, bound *binding, errs *SyntaxErrors) {
+	if node == nil {
+		return
+	}
+
+	if node.Token.Type == Variable {
+		if bound.lookup(node.Token.Value) != nil {
+			return
+		}
+		value, ok := variables[node.Token.Value]
+		if !ok {
+			*errs = append(*errs, &SyntaxError{
+				Offset:  node.Token.Pos,
+				Length:  len(node.Token.Value),
+				Message: "unbound variable: " + node.Token.Value,
+				Err:     use_cases_errors.ErrUnboundVariables,
+			})
+			return
+		}
+		node.Token = Token{Type: Number, Value: strconv.FormatFloat(value, 'g', -1, 64), Pos: node.Token.Pos}
+		node.Value = value
+		node.Parsed = true
+		return
+	}
+
+	if node.Token.Type == Let {
+		bind(node.Left, variables, bound, errs)
+		bind(node.Right, variables, &binding{name: node.Token.Value, outer: bound}, errs)
+		return
+	}
+
+	bind(node.Left, variables, bound, errs)
+	bind(node.Right, variables, bound, errs)
+}
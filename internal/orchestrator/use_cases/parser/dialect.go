@@ -0,0 +1,192 @@
+package parser
+
+import "strings"
+
+// Dialect describes the notation of an expression beyond the strict syntax
+// accepted by Parse and the limits of its size.
+type Dialect struct {
+	// Math accepts formulas as they are written by hand: the Unicode operators
+	// like × and −, the superscripts ² and ³ and implicit multiplication like
+	// 2(3+4), (a+b)(a-b) or 3x.
+	Math bool
+	// DecimalComma reads a comma between digits as a decimal separator, like
+	// in 1,5. Arguments are then separated by a comma and a space, like f(1, 2),
+	// and a comma between digits in the arguments, like f(1,5), is an error.
+	DecimalComma bool
+	// IsUnit reports whether the name after a number is a unit, like in 5 m.
+	// Otherwise the Math dialect multiplies the number by the name. It should
+	// be false for the variables of the expression.
+	IsUnit func(name string) bool
+	// Limits bounds the size of the expression.
+	Limits Limits
+}
+
+// mathSymbols maps the Unicode symbols of the Math dialect to the operators
+// they stand for. The replacement is as long as the symbol in bytes, so the
+// positions of syntax errors point to the expression as it was written.
+var mathSymbols = strings.NewReplacer(
+	"×", "* ",
+	"·", "* ",
+	"⋅", "*  ",
+	"∗", "*  ",
+	"÷", "/ ",
+	"−", "-  ",
+	"≤", "<= ",
+	"≥", ">= ",
+	"≠", "!= ",
+	"²", "^2",
+	"³", "^3",
+	"\u00a0", "  ", // no-break space
+	"\u2009", "   ", // thin space
+	"\u202f", "   ", // narrow no-break space
+)
+
+// ParseDialect parses the expression written in the dialect.
+// Without any option of the dialect it is the same as Parse. An expression
+// exceeding the limits of the dialect is rejected with a LimitError before
+// it is parsed.
+func ParseDialect(expr string, dialect Dialect) (*Node, error) {
+	tokens, err := dialect.tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	if dialect.Math {
+		tokens = dialect.implicitMultiplication(tokens)
+	}
+	return parseTokens(tokens)
+}
+
+// tokenize splits the expression written in the dialect into tokens within its limits.
+func (d Dialect) tokenize(expr string) ([]Token, error) {
+	expr, err := d.normalize(expr)
+	if err != nil {
+		return nil, err
+	}
+	return d.Limits.tokenize(expr)
+}
+
+// normalize replaces the symbols of the dialect with the ones of the strict syntax.
+func (d Dialect) normalize(expr string) (string, error) {
+	if d.Math {
+		expr = mathSymbols.Replace(expr)
+	}
+	if d.DecimalComma {
+		return decimalCommas(expr)
+	}
+	return expr, nil
+}
+
+// decimalCommas replaces the commas between the digits of numbers with points.
+// The digits of a name like x1 are not a number, so f(x1,2) keeps two arguments.
+// In the arguments of a function f(1,5) may be one number or two, so such a
+// comma is rejected instead of guessed.
+func decimalCommas(expr string) (string, error) {
+	b := []byte(expr)
+	// calls tells for every open parenthesis whether it starts the arguments of a function
+	var calls []bool
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '(':
+			calls = append(calls, followsName(b[:i]))
+			continue
+		case ')':
+			if len(calls) > 0 {
+				calls = calls[:len(calls)-1]
+			}
+			continue
+		}
+		if b[i] != ',' || i == 0 || i+1 == len(b) || !isDigit(b[i-1]) || !isDigit(b[i+1]) {
+			continue
+		}
+		start := i - 1
+		for start > 0 && (isDigit(b[start-1]) || b[start-1] == '_') {
+			start--
+		}
+		if start > 0 && (isLetter(b[start-1]) || b[start-1] == '.') {
+			continue
+		}
+		if len(calls) > 0 && calls[len(calls)-1] {
+			return "", SyntaxErrors{{
+				Offset:  i,
+				Length:  1,
+				Message: "ambiguous comma in function arguments, write the number with a point or put a space after the comma",
+			}}
+		}
+		b[i] = '.'
+	}
+	return string(b), nil
+}
+
+// followsName reports whether the expression ends with a name, spaces aside.
+func followsName(b []byte) bool {
+	end := len(b)
+	for end > 0 && b[end-1] == ' ' {
+		end--
+	}
+	start := end
+	for start > 0 && (isLetter(b[start-1]) || isDigit(b[start-1])) {
+		start--
+	}
+	return start < end && isLetter(b[start])
+}
+
+// implicitMultiplication inserts the multiplications written by juxtaposition.
+// An operand followed by a name or a function call is a product, like 3x,
+// x y or 2 sqrt(2). So is a name or a closing parenthesis followed by a number
+// and a number or a closing parenthesis followed by an opening one, like
+// 2(3+4) or (a+b)(a-b). A name followed by an opening parenthesis stays a
+// function call, two numbers are not multiplied and a number followed by a
+// unit stays a quantity. The inserted multiplication binds like the written
+// one, so 1/2x is x/2. A name assigned in the script or a parameter of its
+// functions is never a unit, so t = 3; 2 t is 6 even though t is a unit.
+func (d Dialect) implicitMultiplication(tokens []Token) []Token {
+	bound := boundNames(tokens)
+	result := make([]Token, 0, len(tokens))
+	for i, token := range tokens {
+		// the last token is End, it is never juxtaposed
+		if i > 0 && token.Type != End && d.juxtaposed(tokens[i-1], token, tokens[i+1], bound) {
+			result = append(result, Token{Type: Multiply, Value: "*", Pos: token.Pos})
+		}
+		result = append(result, token)
+	}
+	return result
+}
+
+// juxtaposed reports whether the operand ending with left is multiplied by
+// the operand starting with right, next is the token after right and bound
+// holds the names that are not units.
+func (d Dialect) juxtaposed(left, right, next Token, bound map[string]bool) bool {
+	switch left.Type {
+	case Number:
+		if right.Type == Identifier {
+			return next.Type == LeftParen || bound[right.Value] || d.IsUnit == nil || !d.IsUnit(right.Value)
+		}
+		return right.Type == LeftParen
+	case Identifier:
+		return right.Type == Identifier || right.Type == Number
+	case RightParen:
+		return right.Type == Identifier || right.Type == Number || right.Type == LeftParen
+	}
+	return false
+}
+
+// boundNames returns the names assigned in the script and the parameters of
+// the functions it defines.
+func boundNames(tokens []Token) map[string]bool {
+	bound := make(map[string]bool)
+	for i, token := range tokens {
+		if token.Type != Identifier {
+			continue
+		}
+		// the last token is End, so the next one always exists
+		if tokens[i+1].Type == Assign {
+			bound[token.Value] = true
+		}
+		if isDefinition(tokens, i) {
+			for j := i + 2; tokens[j].Type == Identifier; j += 2 {
+				bound[tokens[j].Value] = true
+			}
+		}
+	}
+	return bound
+}
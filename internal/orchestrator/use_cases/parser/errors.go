@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError describes a problem at a specific place of an expression.
+type SyntaxError struct {
+	// Offset is the byte offset of the offending part of the expression.
+	Offset int `json:"offset"`
+	// Length is the byte length of the offending part, it is zero at the end of the expression.
+	Length int `json:"length"`
+	// Expected lists the tokens that would have been valid at Offset.
+	Expected []string `json:"expected,omitempty"`
+	Message  string   `json:"message"`
+	// Err is an optional sentinel error the syntax error can be matched with.
+	Err error `json:"-"`
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Offset)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// SyntaxErrors is a list of syntax errors found in an expression.
+type SyntaxErrors []*SyntaxError
+
+func (e SyntaxErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e SyntaxErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// errorAt creates a syntax error that points to the token.
+func errorAt(token Token, message string, expected ...string) SyntaxErrors {
+	length := len(token.Value)
+	if token.Type == End {
+		length = 0
+	}
+	return SyntaxErrors{{
+		Offset:   token.Pos,
+		Length:   length,
+		Expected: expected,
+		Message:  message,
+	}}
+}
@@ -0,0 +1,321 @@
+package parser
+
+import (
+	"calculator/internal/shared/functions"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Definition is a function defined by the user, like f(x, y) = x^2 + y.
+// Its body uses no names but its parameters.
+type Definition struct {
+	Name   string
+	Params []string
+	Body   *Node
+}
+
+// ParseDefinition parses the definition of a function like f(x, y) = x^2 + y.
+// The returned error is SyntaxErrors, which lists every problem with its
+// position, or a LimitError if the definition exceeds the limits.
+func ParseDefinition(definition string, limits Limits) (*Definition, error) {
+	tokens, err := limits.tokenize(definition)
+	if err != nil {
+		return nil, err
+	}
+	if !isDefinition(tokens, 0) {
+		return nil, errorAt(tokens[0], "expected a function definition like f(x) = x^2")
+	}
+
+	lambda, remaining, err := parseDefinition(tokens, 0)
+	if err != nil {
+		return nil, err
+	}
+	if remaining[0].Type == Semicolon {
+		remaining = remaining[1:]
+	}
+	if remaining[0].Type != End {
+		return nil, errorAt(remaining[0], "unexpected token in expression", expectedOperator...)
+	}
+	return lambdaDefinition(lambda), nil
+}
+
+// ScriptDefinitions returns the functions defined in the script, in the order of their definitions.
+func ScriptDefinitions(root *Node) []*Definition {
+	var defs []*Definition
+	for node := root; node != nil && (node.Token.Type == Let || node.Token.Type == Define); node = node.Right {
+		if node.Token.Type == Define {
+			defs = append(defs, lambdaDefinition(node.Left))
+		}
+	}
+	return defs
+}
+
+// isDefinition reports whether the tokens at start begin a function
+// definition: a name, a parenthesized list of names and =.
+func isDefinition(tokens []Token, start int) bool {
+	if tokens[start].Type != Identifier || tokens[start+1].Type != LeftParen {
+		return false
+	}
+	i := start + 2
+	for tokens[i].Type == Identifier {
+		i++
+		if tokens[i].Type != Comma {
+			break
+		}
+		i++
+	}
+	return tokens[i].Type == RightParen && tokens[i+1].Type == Assign
+}
+
+// parseDefinition parses the function definition at start into a Lambda node.
+// The tokens must satisfy isDefinition.
+func parseDefinition(tokens []Token, start int) (*Node, []Token, error) {
+	nameToken := tokens[start]
+	if nameToken.Value == "if" || functions.IsUnary(nameToken.Value) || functions.IsAggregate(nameToken.Value) {
+		return nil, nil, errorAt(nameToken, "cannot redefine built-in function: "+nameToken.Value)
+	}
+
+	var params []*Node
+	var names []string
+	var errs SyntaxErrors
+	i := start + 2
+	for ; tokens[i].Type != RightParen; i++ {
+		if tokens[i].Type == Comma {
+			continue
+		}
+		if slices.Contains(names, tokens[i].Value) {
+			errs = append(errs, errorAt(tokens[i], "duplicate parameter: "+tokens[i].Value)...)
+		}
+		names = append(names, tokens[i].Value)
+		params = append(params, &Node{Token: Token{Type: Variable, Value: tokens[i].Value, Pos: tokens[i].Pos}})
+	}
+
+	body, remaining, err := parseExpression(tokens, i+2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		if node == nil {
+			return
+		}
+		if node.Token.Type == Variable && !slices.Contains(names, node.Token.Value) {
+			errs = append(errs, &SyntaxError{
+				Offset:  node.Token.Pos,
+				Length:  len(node.Token.Value),
+				Message: fmt.Sprintf("function %s has no parameter %s", nameToken.Value, node.Token.Value),
+			})
+		}
+		walk(node.Left)
+		walk(node.Right)
+	}
+	walk(body)
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	lambda := Token{Type: Lambda, Value: nameToken.Value, Pos: nameToken.Pos}
+	return &Node{Token: lambda, Left: argumentList(params), Right: body}, remaining, nil
+}
+
+// lambdaDefinition returns the function of the Lambda node.
+func lambdaDefinition(lambda *Node) *Definition {
+	fn := &Definition{Name: lambda.Token.Value, Body: lambda.Right}
+	for _, param := range argumentNodes(lambda.Left) {
+		fn.Params = append(fn.Params, param.Token.Value)
+	}
+	return fn
+}
+
+// argumentList links the nodes into a list of Arguments nodes.
+func argumentList(nodes []*Node) *Node {
+	var list *Node
+	for i := len(nodes) - 1; i >= 0; i-- {
+		list = &Node{Token: Token{Type: Arguments, Pos: nodes[i].Token.Pos}, Left: nodes[i], Right: list}
+	}
+	return list
+}
+
+// argumentNodes returns the nodes of the list of Arguments nodes.
+func argumentNodes(list *Node) []*Node {
+	var nodes []*Node
+	for ; list != nil; list = list.Right {
+		nodes = append(nodes, list.Left)
+	}
+	return nodes
+}
+
+// defined are the functions defined in a script, the last one first.
+type defined struct {
+	fn    *Definition
+	outer *defined
+}
+
+// expander inlines the calls of user-defined functions.
+type expander struct {
+	functions map[string]*Definition
+	limit     int
+	// nodes is the number of nodes the inlined calls have created so far.
+	nodes int
+}
+
+// Expand inlines the calls of user-defined functions into the tree: a call
+// is replaced by the body of the function whose parameters are replaced by
+// the arguments. The functions defined in the script take precedence over
+// the given ones. The calls may create at most limit nodes, a zero limit is
+// no limit.
+//
+// A call of an unknown function, a call with a wrong number of arguments, a
+// recursive call and a call over the limit are reported as SyntaxErrors.
+func Expand(root *Node, functions map[string]*Definition, limit int) (*Node, error) {
+	e := &expander{functions: functions, limit: limit}
+	return e.expand(root, nil, nil)
+}
+
+// CheckDefinition reports the errors Expand would report for a call of the
+// function. The function may call itself and the given functions.
+func CheckDefinition(fn *Definition, functions map[string]*Definition, limit int) error {
+	e := &expander{functions: functions, limit: limit}
+	_, err := e.expand(clone(fn.Body), &defined{fn: fn}, []string{fn.Name})
+	return err
+}
+
+// expand expands the calls of the node. scope holds the functions defined
+// around the node and stack the names of the functions being expanded.
+func (e *expander) expand(node *Node, scope *defined, stack []string) (*Node, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	switch node.Token.Type {
+	case Define:
+		fn := lambdaDefinition(node.Left)
+		inner := &defined{fn: fn, outer: scope}
+		// a function defined but never called is checked too
+		check := &expander{functions: e.functions, limit: e.limit}
+		if _, err := check.expand(clone(fn.Body), inner, []string{fn.Name}); err != nil {
+			return nil, err
+		}
+		return e.expand(node.Right, inner, stack)
+	case Call:
+		return e.expandCall(node, scope, stack)
+	}
+
+	var err error
+	if node.Left, err = e.expand(node.Left, scope, stack); err != nil {
+		return nil, err
+	}
+	if node.Right, err = e.expand(node.Right, scope, stack); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (e *expander) expandCall(call *Node, scope *defined, stack []string) (*Node, error) {
+	name := call.Token.Value
+	fn, fnScope := e.lookup(name, scope)
+	if fn == nil {
+		return nil, errorAt(call.Token, "unknown function: "+name)
+	}
+	if slices.Contains(stack, name) {
+		cycle := strings.Join(slices.Concat(stack[slices.Index(stack, name):], []string{name}), " -> ")
+		return nil, errorAt(call.Token, fmt.Sprintf("recursive call of function %s: %s", name, cycle))
+	}
+
+	args := argumentNodes(call.Left)
+	if len(args) != len(fn.Params) {
+		return nil, errorAt(call.Token, fmt.Sprintf("function %s expects %d arguments, got %d", name, len(fn.Params), len(args)))
+	}
+	for i := range args {
+		arg, err := e.expand(args[i], scope, stack)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+
+	body, err := e.expand(clone(fn.Body), fnScope, append(slices.Clip(stack), name))
+	if err != nil {
+		return nil, err
+	}
+
+	// the size is known before the arguments are copied,
+	// so a call over the limit is never built
+	nodes := size(body)
+	for i, param := range fn.Params {
+		nodes += uses(body, param) * (size(args[i]) - 1)
+	}
+	e.nodes += nodes
+	if e.limit > 0 && e.nodes > e.limit {
+		return nil, errorAt(call.Token, fmt.Sprintf("calls of user-defined functions expand to more than %d nodes", e.limit))
+	}
+
+	return substitute(body, fn.Params, args, call.Token.Pos), nil
+}
+
+// lookup returns the function called name with the scope its body is
+// expanded in. The functions defined in the script see each other, the
+// given functions see only the given functions.
+func (e *expander) lookup(name string, scope *defined) (*Definition, *defined) {
+	for d := scope; d != nil; d = d.outer {
+		if d.fn.Name == name {
+			return d.fn, d
+		}
+	}
+	return e.functions[name], nil
+}
+
+// substitute replaces the parameters in the body with copies of the
+// arguments. The nodes of the body take the position of the call.
+func substitute(body *Node, params []string, args []*Node, pos int) *Node {
+	if body == nil {
+		return nil
+	}
+	if body.Token.Type == Variable {
+		if i := slices.Index(params, body.Token.Value); i >= 0 {
+			return clone(args[i])
+		}
+	}
+	body.Token.Pos = pos
+	body.Left = substitute(body.Left, params, args, pos)
+	body.Right = substitute(body.Right, params, args, pos)
+	return body
+}
+
+// Clone returns a deep copy of the tree.
+func (n *Node) Clone() *Node {
+	return clone(n)
+}
+
+// clone returns a deep copy of the tree.
+func clone(node *Node) *Node {
+	if node == nil {
+		return nil
+	}
+	c := *node
+	c.Left = clone(node.Left)
+	c.Right = clone(node.Right)
+	return &c
+}
+
+// size returns the number of nodes of the tree.
+func size(node *Node) int {
+	if node == nil {
+		return 0
+	}
+	return 1 + size(node.Left) + size(node.Right)
+}
+
+// uses returns the number of references to the name in the tree.
+func uses(node *Node, name string) int {
+	if node == nil {
+		return 0
+	}
+	n := uses(node.Left, name) + uses(node.Right, name)
+	if node.Token.Type == Variable && node.Token.Value == name {
+		n++
+	}
+	return n
+}
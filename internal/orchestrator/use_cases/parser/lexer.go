@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// keywords maps the operators written as words to their token types.
+var keywords = map[string]TokenType{
+	"and": And,
+	"or":  Or,
+	"not": Not,
+}
+
+// tokenize splits the expression into tokens and terminates them with an End token.
+// It does not stop at the first invalid character or literal, so all
+// lexical errors of the expression are reported at once.
+func tokenize(expr string) ([]Token, error) {
+	var tokens []Token
+	var errs SyntaxErrors
+	rest := expr
+	for len(rest) > 0 {
+		pos := len(expr) - len(rest)
+		token, remainder, err := nextToken(rest)
+		if err != nil {
+			errs = append(errs, &SyntaxError{
+				Offset:  pos,
+				Length:  len(rest) - len(remainder),
+				Message: err.Error(),
+			})
+		} else if token.Type != Empty {
+			token.Pos = pos
+			tokens = append(tokens, token)
+		}
+		rest = remainder
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return append(tokens, Token{Type: End, Pos: len(expr)}), nil
+}
+
+// nextToken returns the first token of expr and the rest of it.
+// On error the rest starts right after the invalid part.
+func nextToken(expr string) (Token, string, error) {
+	if len(expr) == 0 {
+		return Token{}, "", nil
+	}
+
+	switch expr[0] {
+	case '+':
+		return Token{Type: Plus, Value: "+"}, expr[1:], nil
+	case '-':
+		return Token{Type: Minus, Value: "-"}, expr[1:], nil
+	case '*':
+		return Token{Type: Multiply, Value: "*"}, expr[1:], nil
+	case '/':
+		if len(expr) > 1 && expr[1] == '/' {
+			return Token{Type: FloorDivide, Value: "//"}, expr[2:], nil
+		}
+		return Token{Type: Divide, Value: "/"}, expr[1:], nil
+	case '%':
+		return Token{Type: Modulo, Value: "%"}, expr[1:], nil
+	case '^':
+		return Token{Type: Power, Value: "^"}, expr[1:], nil
+	case '(':
+		return Token{Type: LeftParen, Value: "("}, expr[1:], nil
+	case ')':
+		return Token{Type: RightParen, Value: ")"}, expr[1:], nil
+	case ',':
+		return Token{Type: Comma, Value: ","}, expr[1:], nil
+	case ';':
+		return Token{Type: Semicolon, Value: ";"}, expr[1:], nil
+	case '<':
+		if len(expr) > 1 && expr[1] == '=' {
+			return Token{Type: LessEqual, Value: "<="}, expr[2:], nil
+		}
+		return Token{Type: Less, Value: "<"}, expr[1:], nil
+	case '>':
+		if len(expr) > 1 && expr[1] == '=' {
+			return Token{Type: GreaterEqual, Value: ">="}, expr[2:], nil
+		}
+		return Token{Type: Greater, Value: ">"}, expr[1:], nil
+	case '=':
+		if len(expr) > 1 && expr[1] == '=' {
+			return Token{Type: Equal, Value: "=="}, expr[2:], nil
+		}
+		return Token{Type: Assign, Value: "="}, expr[1:], nil
+	case '!':
+		if len(expr) > 1 && expr[1] == '=' {
+			return Token{Type: NotEqual, Value: "!="}, expr[2:], nil
+		}
+		return Token{}, expr[1:], fmt.Errorf("unexpected character: !")
+	case ' ':
+		return Token{Type: Empty, Value: ""}, expr[1:], nil
+	case '\t':
+		return Token{Type: Empty, Value: ""}, expr[1:], nil
+	default:
+		if isDigit(expr[0]) || expr[0] == '.' {
+			num, end, err := extractNumber(expr)
+			if err != nil {
+				return Token{}, expr[end:], err
+			}
+			return Token{Type: Number, Value: num}, expr[end:], nil
+		}
+		if isLetter(expr[0]) {
+			name, end := extractIdentifier(expr)
+			if tokenType, ok := keywords[name]; ok {
+				return Token{Type: tokenType, Value: name}, expr[end:], nil
+			}
+			return Token{Type: Identifier, Value: name}, expr[end:], nil
+		}
+		r, size := utf8.DecodeRuneInString(expr)
+		return Token{}, expr[size:], fmt.Errorf("unexpected character: %c", r)
+	}
+}
+
+// isImaginary reports whether the token is a number with the suffix i.
+func isImaginary(token Token) bool {
+	return token.Type == Number && strings.HasSuffix(token.Value, "i")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// extractNumber scans the number literal at the start of s:
+//
+//	number   = mantissa [ exponent ] [ "i" ]
+//	mantissa = digits [ "." [ digits ] ] | "." digits
+//	exponent = ( "e" | "E" ) [ "+" | "-" ] digits
+//	digits   = digit { [ "_" ] digit }
+//
+// A number with the suffix i is imaginary.
+// A literal that does not follow the grammar or does not fit into float64 is rejected,
+// the returned length then covers the whole malformed literal.
+func extractNumber(s string) (string, int, error) {
+	i := scanDigits(s, 0)
+	if i < len(s) && s[i] == '.' {
+		j := scanDigits(s, i+1)
+		if i == 0 && j == i+1 {
+			return "", malformedLength(s), malformedNumber(s)
+		}
+		i = j
+	}
+
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		k := scanDigits(s, j)
+		if k == j {
+			return "", malformedLength(s), malformedNumber(s)
+		}
+		i = k
+	}
+
+	if i < len(s) && (isDigit(s[i]) || s[i] == '.' || s[i] == '_') {
+		return "", malformedLength(s), malformedNumber(s)
+	}
+
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(s[:i], "_", ""), 64); err != nil {
+		return "", i, fmt.Errorf("number out of range: %s", s[:i])
+	}
+
+	if i < len(s) && s[i] == 'i' && (i+1 == len(s) || !isLetter(s[i+1]) && !isDigit(s[i+1])) {
+		i++
+	}
+	return s[:i], i, nil
+}
+
+// scanDigits returns the end of the digit sequence starting at i.
+// A digit separator is only allowed between two digits.
+func scanDigits(s string, i int) int {
+	for i < len(s) {
+		if isDigit(s[i]) {
+			i++
+		} else if s[i] == '_' && i+1 < len(s) && isDigit(s[i+1]) && i > 0 && isDigit(s[i-1]) {
+			i++
+		} else {
+			break
+		}
+	}
+	return i
+}
+
+// malformedLength returns the length of the literal at the start of s, up to
+// the first character that can not be a part of a number.
+func malformedLength(s string) int {
+	var i int
+	for i < len(s) && (isDigit(s[i]) || isLetter(s[i]) || s[i] == '.' || s[i] == '_') {
+		i++
+	}
+	return i
+}
+
+func malformedNumber(s string) error {
+	return fmt.Errorf("malformed number: %s", s[:malformedLength(s)])
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func extractIdentifier(s string) (string, int) {
+	var i int
+	for i < len(s) && (isLetter(s[i]) || s[i] >= '0' && s[i] <= '9') {
+		i++
+	}
+	return s[:i], i
+}
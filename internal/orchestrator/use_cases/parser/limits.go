@@ -0,0 +1,109 @@
+package parser
+
+import "fmt"
+
+// Limits bounds the size of the expressions the parser accepts, so that a
+// hostile expression cannot exhaust the memory or the stack. A zero limit
+// is no limit.
+type Limits struct {
+	// MaxLength is the maximum length of the expression in bytes.
+	MaxLength int
+	// MaxTokens is the maximum number of tokens of the expression.
+	MaxTokens int
+	// MaxDepth is the maximum nesting of parentheses, unary operators and
+	// exponents, like in -(2^-(3)).
+	MaxDepth int
+}
+
+// Limit names one of the Limits.
+type Limit string
+
+const (
+	LimitLength Limit = "length"
+	LimitTokens Limit = "tokens"
+	LimitDepth  Limit = "depth"
+)
+
+// LimitError reports an expression that exceeds one of the Limits.
+type LimitError struct {
+	Limit Limit
+	Max   int
+	// Offset is the byte offset in the expression where the limit is exceeded.
+	Offset int
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitLength:
+		return fmt.Sprintf("expression is longer than %d bytes", e.Max)
+	case LimitTokens:
+		return fmt.Sprintf("expression has more than %d tokens", e.Max)
+	}
+	return fmt.Sprintf("expression is nested deeper than %d levels at position %d", e.Max, e.Offset)
+}
+
+// tokenize splits the expression into tokens within the limits.
+func (l Limits) tokenize(expr string) ([]Token, error) {
+	if l.MaxLength > 0 && len(expr) > l.MaxLength {
+		return nil, &LimitError{Limit: LimitLength, Max: l.MaxLength, Offset: l.MaxLength}
+	}
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	// the last token is End
+	if l.MaxTokens > 0 && len(tokens)-1 > l.MaxTokens {
+		return nil, &LimitError{Limit: LimitTokens, Max: l.MaxTokens, Offset: tokens[l.MaxTokens].Pos}
+	}
+	if l.MaxDepth > 0 {
+		if token, ok := tooDeep(tokens, l.MaxDepth); ok {
+			return nil, &LimitError{Limit: LimitDepth, Max: l.MaxDepth, Offset: token.Pos}
+		}
+	}
+	return tokens, nil
+}
+
+// tooDeep returns the first token nested deeper than max. The parser
+// recurses once for every parenthesis, unary operator and exponent of an
+// operand, so their nesting bounds the depth of its stack before it reads
+// a single token.
+func tooDeep(tokens []Token, max int) (Token, bool) {
+	// outer holds the depths of the tokens before the open parentheses
+	depth, outer := 0, []int{}
+	for i, token := range tokens {
+		switch {
+		case token.Type == LeftParen:
+			outer = append(outer, depth)
+			depth++
+		case token.Type == RightParen:
+			if len(outer) > 0 {
+				depth, outer = outer[len(outer)-1], outer[:len(outer)-1]
+			}
+		case token.Type == Not || token.Type == Power || isPrefixSign(tokens, i):
+			depth++
+		case token.Type != Number && token.Type != Identifier:
+			// any other operator ends the operand, its operands nest
+			// no deeper than the innermost parenthesis
+			depth = 0
+			if len(outer) > 0 {
+				depth = outer[len(outer)-1] + 1
+			}
+		}
+		if depth > max {
+			return token, true
+		}
+	}
+	return Token{}, false
+}
+
+// isPrefixSign reports whether the token is a unary plus or minus.
+func isPrefixSign(tokens []Token, i int) bool {
+	if tokens[i].Type != Plus && tokens[i].Type != Minus {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	previous := tokens[i-1].Type
+	return previous != Number && previous != Identifier && previous != RightParen
+}
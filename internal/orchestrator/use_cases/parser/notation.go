@@ -0,0 +1,308 @@
+package parser
+
+import (
+	"calculator/internal/shared/functions"
+	"fmt"
+	"strings"
+)
+
+// ParseRPN parses an expression written in Reverse Polish notation, like
+// 3 4 + 2 *, into the same tree Parse makes of (3 + 4) * 2. The functions
+// take their arguments from the stack, like x 2 ^ sqrt or c a b if.
+// A minus written right before a number makes it negative, like 3 -4 *.
+func ParseRPN(expr string, dialect Dialect) (*Node, error) {
+	tokens, err := dialect.tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var stack []*Node
+	for i := 0; tokens[i].Type != End; i++ {
+		token := tokens[i]
+		if isNegativeNumber(tokens, i) {
+			number, err := negativeNumber(tokens, i)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, number)
+			i++
+			continue
+		}
+
+		n := arity(token)
+		if n < 0 {
+			return nil, errorAt(token, "unexpected token: "+token.Value, "number", "variable", "operator", "function")
+		}
+		if len(stack) < n {
+			return nil, errorAt(token, fmt.Sprintf("%s expects %s, got %d", token.Value, operands(n), len(stack)))
+		}
+		node, err := operation(token, stack[len(stack)-n:])
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack[:len(stack)-n], node)
+	}
+
+	end := tokens[len(tokens)-1]
+	switch len(stack) {
+	case 0:
+		return nil, errorAt(end, "unexpected end of expression", "number", "variable")
+	case 1:
+		return stack[0], nil
+	}
+	return nil, errorAt(end, fmt.Sprintf("missing operator, %d operands are left", len(stack)), "operator", "function")
+}
+
+// ParsePrefix parses an expression written in prefix notation, like
+// * + 3 4 2, or as an S-expression, like (* (+ 3 4) 2). In parentheses an
+// operator takes any number of operands, (+ 1 2 3) is 1 + 2 + 3 and (- x) is
+// -x, and so do the functions like sum, (sum 1 2 3). A name that is not a
+// known function is a call of a user-defined one.
+func ParsePrefix(expr string, dialect Dialect) (*Node, error) {
+	tokens, err := dialect.tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	root, remaining, err := parsePrefix(tokens, 0)
+	if err != nil {
+		return nil, err
+	}
+	if remaining[0].Type != End {
+		return nil, errorAt(remaining[0], "unexpected token in expression", "end of expression")
+	}
+	return root, nil
+}
+
+func parsePrefix(tokens []Token, start int) (*Node, []Token, error) {
+	token := tokens[start]
+	if isNegativeNumber(tokens, start) {
+		number, err := negativeNumber(tokens, start)
+		return number, tokens[start+2:], err
+	}
+	if token.Type == LeftParen {
+		return parseSExpression(tokens, start+1)
+	}
+	if token.Type == End {
+		return nil, nil, errorAt(token, "unexpected end of expression", "number", "variable", "operator", "function", "(")
+	}
+
+	n := arity(token)
+	if n < 0 {
+		return nil, nil, errorAt(token, "unexpected token: "+token.Value, "number", "variable", "operator", "function", "(")
+	}
+	args := make([]*Node, n)
+	remaining := tokens[start+1:]
+	for i := range args {
+		var err error
+		if args[i], remaining, err = parsePrefix(remaining, 0); err != nil {
+			return nil, nil, err
+		}
+	}
+	node, err := operation(token, args)
+	return node, remaining, err
+}
+
+// parseSExpression parses the S-expression after its opening parenthesis.
+func parseSExpression(tokens []Token, start int) (*Node, []Token, error) {
+	head := tokens[start]
+	if head.Type == LeftParen || head.Type == Number {
+		// a parenthesized operand like ((+ 1 2)) or (2)
+		node, remaining, err := parsePrefix(tokens, start)
+		if err != nil {
+			return nil, nil, err
+		}
+		if remaining[0].Type != RightParen {
+			return nil, nil, errorAt(remaining[0], "missing closing parenthesis", ")")
+		}
+		return node, remaining[1:], nil
+	}
+	aggregate := head.Type == Identifier && functions.IsAggregate(head.Value)
+	if arity(head) < 0 && !aggregate {
+		return nil, nil, errorAt(head, "unexpected token: "+head.Value, "operator", "function")
+	}
+
+	var args []*Node
+	remaining := tokens[start+1:]
+	for remaining[0].Type != RightParen {
+		if remaining[0].Type == End {
+			return nil, nil, errorAt(remaining[0], "missing closing parenthesis", ")")
+		}
+		arg, remaining2, err := parsePrefix(remaining, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, arg)
+		remaining = remaining2
+	}
+	remaining = remaining[1:]
+
+	switch n := arity(head); {
+	case aggregate && len(args) == 0:
+		return nil, nil, errorAt(head, fmt.Sprintf("function %s expects at least 1 argument", head.Value))
+	case aggregate:
+		return &Node{Token: Token{Type: Aggregate, Value: head.Value, Pos: head.Pos}, Left: argumentList(args)}, remaining, nil
+	case head.Type == Identifier && n == 0:
+		// a call of a user-defined function
+		return &Node{Token: Token{Type: Call, Value: head.Value, Pos: head.Pos}, Left: argumentList(args)}, remaining, nil
+	case n == 2 && len(args) == 1 && (head.Type == Plus || head.Type == Minus):
+		// a unary sign is parsed like in infix notation
+		zero := &Node{Token{Type: Number, Value: "0", Pos: head.Pos}, nil, nil, 0, true}
+		return &Node{Token: head, Left: zero, Right: args[0]}, remaining, nil
+	case n == 2 && len(args) > 2:
+		node := args[0]
+		for _, arg := range args[1:] {
+			node = &Node{Token: head, Left: node, Right: arg}
+		}
+		return node, remaining, nil
+	case len(args) != n:
+		return nil, nil, errorAt(head, fmt.Sprintf("%s expects %s, got %d", head.Value, operands(n), len(args)))
+	}
+	node, err := operation(head, args)
+	return node, remaining, err
+}
+
+func operands(n int) string {
+	if n == 1 {
+		return "1 operand"
+	}
+	return fmt.Sprintf("%d operands", n)
+}
+
+// isNegativeNumber reports whether the token is a minus written right before a number.
+func isNegativeNumber(tokens []Token, i int) bool {
+	return tokens[i].Type == Minus && tokens[i+1].Type == Number && adjacent(tokens, i+1)
+}
+
+// negativeNumber makes the negative number of a minus and the number after it.
+// It is a subtraction from zero, like a negative number in infix notation.
+func negativeNumber(tokens []Token, i int) (*Node, error) {
+	number, err := literal(tokens[i+1])
+	if err != nil {
+		return nil, err
+	}
+	zero := &Node{Token{Type: Number, Value: "0", Pos: tokens[i].Pos}, nil, nil, 0, true}
+	return &Node{Token: tokens[i], Left: zero, Right: number}, nil
+}
+
+// arity returns the number of operands the token takes in the notations
+// without infix operators: 0 for a number or a name, -1 for a token that is
+// not an operand or an operator and for a function of any number of
+// arguments, which is only written in an S-expression.
+func arity(token Token) int {
+	switch token.Type {
+	case Number:
+		return 0
+	case Not:
+		return 1
+	case Identifier:
+		if token.Value == "if" {
+			return 3
+		}
+		if functions.IsAggregate(token.Value) {
+			return -1
+		}
+		if functions.IsUnary(token.Value) {
+			return 1
+		}
+		return 0
+	case Plus, Minus, Multiply, Divide, Modulo, FloorDivide, Power,
+		Less, LessEqual, Greater, GreaterEqual, Equal, NotEqual, And, Or:
+		return 2
+	}
+	return -1
+}
+
+// operation makes the node of the token applied to its operands.
+func operation(token Token, args []*Node) (*Node, error) {
+	switch {
+	case token.Type == Number:
+		return literal(token)
+	case token.Type == Identifier && token.Value == "if":
+		branches := &Node{Token: Token{Type: Branches, Pos: token.Pos}, Left: args[1], Right: args[2]}
+		return &Node{Token: Token{Type: If, Value: token.Value, Pos: token.Pos}, Left: args[0], Right: branches}, nil
+	case token.Type == Identifier && len(args) == 1:
+		return &Node{Token: Token{Type: Function, Value: token.Value, Pos: token.Pos}, Left: args[0]}, nil
+	case token.Type == Identifier:
+		return &Node{Token: Token{Type: Variable, Value: token.Value, Pos: token.Pos}}, nil
+	case token.Type == Not:
+		return &Node{Token: token, Left: args[0]}, nil
+	}
+	return &Node{Token: token, Left: args[0], Right: args[1]}, nil
+}
+
+// FormatRPN writes the expression tree in Reverse Polish notation, like
+// 3 4 + 2 *. Scripts, quantities with units, calls of user-defined functions
+// and of the functions of any number of arguments, whose number of arguments
+// is not known from the notation, cannot be written so.
+func (n *Node) FormatRPN() (string, error) {
+	var b strings.Builder
+	if err := n.formatRPN(&b); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func (n *Node) formatRPN(b *strings.Builder) error {
+	switch n.Token.Type {
+	case Number, Variable:
+		b.WriteString(n.Token.Value + " ")
+		return nil
+	case Call, Aggregate:
+		return fmt.Errorf("call of function %s cannot be written in RPN", n.Token.Value)
+	case Let, Define, Lambda, Unit:
+		return notationError(n, "RPN")
+	}
+
+	operands := []*Node{n.Left, n.Right}
+	switch n.Token.Type {
+	case If:
+		operands = []*Node{n.Left, n.Right.Left, n.Right.Right}
+	case Function, Not:
+		operands = operands[:1]
+	}
+	for _, operand := range operands {
+		if err := operand.formatRPN(b); err != nil {
+			return err
+		}
+	}
+	b.WriteString(n.Token.Value + " ")
+	return nil
+}
+
+// FormatPrefix writes the expression tree as an S-expression, like
+// (* (+ 3 4) 2). Scripts and quantities with units cannot be written so.
+func (n *Node) FormatPrefix() (string, error) {
+	switch n.Token.Type {
+	case Number, Variable:
+		return n.Token.Value, nil
+	case Let, Define, Lambda, Unit:
+		return "", notationError(n, "prefix notation")
+	}
+
+	operands := []*Node{n.Left, n.Right}
+	switch n.Token.Type {
+	case If:
+		operands = []*Node{n.Left, n.Right.Left, n.Right.Right}
+	case Function, Not:
+		operands = operands[:1]
+	case Call, Aggregate:
+		operands = argumentNodes(n.Left)
+	}
+	words := []string{n.Token.Value}
+	for _, operand := range operands {
+		word, err := operand.FormatPrefix()
+		if err != nil {
+			return "", err
+		}
+		words = append(words, word)
+	}
+	return "(" + strings.Join(words, " ") + ")", nil
+}
+
+func notationError(n *Node, notation string) error {
+	if n.Token.Type == Unit {
+		return fmt.Errorf("quantity %s cannot be written in %s", n, notation)
+	}
+	return fmt.Errorf("script cannot be written in %s", notation)
+}
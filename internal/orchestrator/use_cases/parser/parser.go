@@ -1,15 +1,20 @@
 package parser
 
 import (
+	"calculator/internal/shared/functions"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 )
 
 // Tocken is representing token in expression
 type Token struct {
 	Type  TokenType
 	Value string
+	// Pos is the byte offset of the token in the expression.
+	Pos int
 }
 
 // TokenType is type of token
@@ -21,9 +26,57 @@ const (
 	Minus
 	Multiply
 	Divide
+	Modulo
+	FloorDivide
+	Power
 	LeftParen
 	RightParen
+	Identifier
+	Function
+	Variable
+	Comma
 	Empty
+	End
+	Less
+	LessEqual
+	Greater
+	GreaterEqual
+	Equal
+	NotEqual
+	And
+	Or
+	Not
+	// If is a conditional, its Left is the condition and its Right is a
+	// Branches node holding the then branch in Left and the else branch in Right.
+	If
+	Branches
+	Assign
+	Semicolon
+	// Let binds the value in Left to the name in its Token.Value for the
+	// statements in Right. A reference to the name is a Variable node.
+	Let
+	// Call is a call of a user-defined function named by its Token.Value,
+	// its Left is the first of the Arguments nodes or nil.
+	Call
+	// Arguments node holding an argument in Left and the next Arguments node in Right.
+	Arguments
+	// Define defines the function of the Lambda node in Left for the statements in Right.
+	Define
+	// Lambda is the function named by its Token.Value. Its Left is the first
+	// of the Arguments nodes holding the parameters as Variable nodes, its
+	// Right is the body.
+	Lambda
+	// Unit is a quantity like 5 km/h, its Token.Value is the unit and its
+	// Left is the number. The scheduler converts it to a number in base units.
+	Unit
+	// Aggregate is a call of a function of any number of arguments like sum
+	// or max named by its Token.Value, its Left is the first of the Arguments
+	// nodes. The scheduler reduces it to binary operations.
+	Aggregate
+	// Min and Max are the binary minimum and maximum the aggregates min, max
+	// and median are reduced to, their Token.Value is min or max.
+	Min
+	Max
 )
 
 // Node represents node in binary tree
@@ -35,19 +88,42 @@ type Node struct {
 	Parsed bool
 }
 
-// Parse parses expression and returns root node of expression tree
+// IsImaginary reports whether the node is an imaginary number like 4i.
+// Its Value is not set, the number is read from the literal.
+func (n *Node) IsImaginary() bool {
+	return isImaginary(n.Token)
+}
+
+// expectedOperand lists the tokens an operand can start with.
+var expectedOperand = []string{"number", "variable", "function", "(", "+", "-", "not"}
+
+// expectedOperator lists the tokens that can follow an operand.
+var expectedOperator = []string{"+", "-", "*", "/", "%", "//", "^", "<", "<=", ">", ">=", "==", "!=", "and", "or", ")", "end of expression"}
+
+// Parse parses expression and returns root node of expression tree.
+// The expression may be a script of statements separated by semicolons,
+// like a = 2 + 3; b = a * 4; b - a. Its value is the value of the last
+// statement, which is the bound value if the statement is an assignment.
+// The returned error is SyntaxErrors, which lists every problem with its position.
 func Parse(expr string) (*Node, error) {
 	tokens, err := tokenize(expr)
 	if err != nil {
 		return nil, err
 	}
+	return parseTokens(tokens)
+}
 
-	root, remaining, err := parseExpression(tokens, 0)
+// parseTokens parses the tokens of a script terminated by an End token.
+func parseTokens(tokens []Token) (*Node, error) {
+	root, remaining, err := parseScript(tokens, 0)
 	if err != nil {
 		return nil, err
 	}
-	if len(remaining) > 0 {
-		return nil, errors.New("unexpected token in expression")
+	if remaining[0].Type == Assign {
+		return nil, errorAt(remaining[0], "only a name can be assigned")
+	}
+	if remaining[0].Type != End {
+		return nil, errorAt(remaining[0], "unexpected token in expression", expectedOperator...)
 	}
 
 	return root, nil
@@ -55,16 +131,127 @@ func Parse(expr string) (*Node, error) {
 
 // Evaluate evaluates expression and returns result
 func (n *Node) Evaluate() (float64, error) {
+	return n.evaluate(nil)
+}
+
+// scope holds the values bound by the Let nodes around a node.
+type scope struct {
+	name  string
+	value float64
+	outer *scope
+}
+
+func (s *scope) lookup(name string) (float64, bool) {
+	for ; s != nil; s = s.outer {
+		if s.name == name {
+			return s.value, true
+		}
+	}
+	return 0, false
+}
+
+func (n *Node) evaluate(env *scope) (float64, error) {
 	if n.Parsed {
 		return n.Value, nil
 	}
 
-	left, err := n.Left.Evaluate()
+	if n.Token.Type == Call {
+		return 0, fmt.Errorf("unknown function: %s", n.Token.Value)
+	}
+
+	if n.Token.Type == Number {
+		return 0, fmt.Errorf("imaginary number %s has no real value", n.Token.Value)
+	}
+
+	if n.Token.Type == Unit {
+		return 0, fmt.Errorf("quantity %s has no value without the unit catalog", n)
+	}
+
+	if n.Token.Type == Variable {
+		// a bound name is not cached, the node may be evaluated in another scope
+		if value, ok := env.lookup(n.Token.Value); ok {
+			return value, nil
+		}
+		return 0, fmt.Errorf("unbound variable: %s", n.Token.Value)
+	}
+
+	if n.Token.Type == Let {
+		value, err := n.Left.evaluate(env)
+		if err != nil {
+			return 0, err
+		}
+		n.Value, err = n.Right.evaluate(&scope{name: n.Token.Value, value: value, outer: env})
+		if err != nil {
+			return 0, err
+		}
+		n.Parsed = true
+		return n.Value, nil
+	}
+
+	if n.Token.Type == If {
+		condition, err := n.Left.evaluate(env)
+		if err != nil {
+			return 0, err
+		}
+		branch := n.Right.Right
+		if condition != 0 {
+			branch = n.Right.Left
+		}
+		n.Value, err = branch.evaluate(env)
+		if err != nil {
+			return 0, err
+		}
+		n.Parsed = true
+		return n.Value, nil
+	}
+
+	if n.Token.Type == Not {
+		arg, err := n.Left.evaluate(env)
+		if err != nil {
+			return 0, err
+		}
+		n.Value = functions.Not(arg)
+		n.Parsed = true
+		return n.Value, nil
+	}
+
+	if n.Token.Type == Aggregate {
+		var args []float64
+		for _, node := range argumentNodes(n.Left) {
+			arg, err := node.evaluate(env)
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, arg)
+		}
+		value, err := functions.ApplyAggregate(n.Token.Value, args)
+		if err != nil {
+			return 0, err
+		}
+		n.Value = value
+		n.Parsed = true
+		return n.Value, nil
+	}
+
+	if n.Token.Type == Function {
+		arg, err := n.Left.evaluate(env)
+		if err != nil {
+			return 0, err
+		}
+		n.Value, err = functions.ApplyUnary(n.Token.Value, arg)
+		if err != nil {
+			return 0, err
+		}
+		n.Parsed = true
+		return n.Value, nil
+	}
+
+	left, err := n.Left.evaluate(env)
 	if err != nil {
 		return 0, err
 	}
 
-	right, err := n.Right.Evaluate()
+	right, err := n.Right.evaluate(env)
 	if err != nil {
 		return 0, err
 	}
@@ -81,25 +268,96 @@ func (n *Node) Evaluate() (float64, error) {
 			return 0, errors.New("division by zero")
 		}
 		n.Value = left / right
+	case Modulo:
+		n.Value, err = functions.Modulo(left, right)
+	case FloorDivide:
+		n.Value, err = functions.FloorDivide(left, right)
+	case Power:
+		n.Value = math.Pow(left, right)
+	case Min:
+		n.Value = math.Min(left, right)
+	case Max:
+		n.Value = math.Max(left, right)
+	case Less, LessEqual, Greater, GreaterEqual, Equal, NotEqual, And, Or:
+		n.Value, err = functions.ApplyLogical(n.Token.Value, left, right)
 	default:
 		return 0, fmt.Errorf("unknown operator: %s", n.Token.Value)
 	}
+	if err != nil {
+		return 0, err
+	}
 
 	n.Parsed = true
 	return n.Value, nil
 }
 
+// parseScript parses the statements of a script. A statement is an
+// assignment name = expression, a function definition f(x, y) = expression
+// or an expression. Only the last statement can be an expression, the value
+// of any other one would be lost.
+func parseScript(tokens []Token, start int) (*Node, []Token, error) {
+	if isDefinition(tokens, start) {
+		lambda, remaining, err := parseDefinition(tokens, start)
+		if err != nil {
+			return nil, nil, err
+		}
+		if remaining[0].Type != Semicolon || remaining[1].Type == End {
+			return nil, nil, errorAt(remaining[0], "a script cannot end with a function definition", ";")
+		}
+		body, remaining, err := parseScript(remaining, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &Node{Token: Token{Type: Define, Value: lambda.Token.Value, Pos: lambda.Token.Pos}, Left: lambda, Right: body}, remaining, nil
+	}
+
+	name := tokens[start]
+	if name.Type != Identifier || tokens[start+1].Type != Assign {
+		expr, remaining, err := parseExpression(tokens, start)
+		if err != nil {
+			return nil, nil, err
+		}
+		if remaining[0].Type == Semicolon && remaining[1].Type != End {
+			return nil, nil, errorAt(remaining[0], "only the last statement can be an expression, assign the value to a name")
+		}
+		if remaining[0].Type == Semicolon {
+			remaining = remaining[1:]
+		}
+		return expr, remaining, nil
+	}
+
+	value, remaining, err := parseExpression(tokens, start+2)
+	if err != nil {
+		return nil, nil, err
+	}
+	let := Token{Type: Let, Value: name.Value, Pos: name.Pos}
+	if remaining[0].Type == Semicolon && remaining[1].Type == End {
+		remaining = remaining[1:]
+	}
+	if remaining[0].Type != Semicolon {
+		// the script ends with the assignment, its value is the bound value
+		return &Node{Token: let, Left: value, Right: &Node{Token: Token{Type: Variable, Value: name.Value, Pos: name.Pos}}}, remaining, nil
+	}
+
+	body, remaining, err := parseScript(remaining, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Node{Token: let, Left: value, Right: body}, remaining, nil
+}
+
+// parseExpression parses logical disjunctions, the loosest binding operators.
 func parseExpression(tokens []Token, start int) (*Node, []Token, error) {
-	left, remaining, err := parseTerm(tokens, start)
+	left, remaining, err := parseConjunction(tokens, start)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	for len(remaining) > 0 && (remaining[0].Type == Plus || remaining[0].Type == Minus) {
+	for remaining[0].Type == Or {
 		op := remaining[0]
 		remaining = remaining[1:]
 
-		right, remaining2, err := parseTerm(remaining, 0)
+		right, remaining2, err := parseConjunction(remaining, 0)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -111,62 +369,93 @@ func parseExpression(tokens []Token, start int) (*Node, []Token, error) {
 	return left, remaining, nil
 }
 
-func tokenize(expr string) ([]Token, error) {
-	var tokens []Token
-	for len(expr) > 0 {
-		token, remainder, err := nextToken(expr)
+func parseConjunction(tokens []Token, start int) (*Node, []Token, error) {
+	left, remaining, err := parseNegation(tokens, start)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for remaining[0].Type == And {
+		op := remaining[0]
+		remaining = remaining[1:]
+
+		right, remaining2, err := parseNegation(remaining, 0)
 		if err != nil {
-			return nil, err
-		}
-		if token.Type != Empty {
-			tokens = append(tokens, token)
+			return nil, nil, err
 		}
-		expr = remainder
+
+		left = &Node{op, left, right, 0, false}
+		remaining = remaining2
+	}
+
+	return left, remaining, nil
+}
+
+// parseNegation parses the logical not. It binds looser than comparisons,
+// so not a < b is parsed as not (a < b).
+func parseNegation(tokens []Token, start int) (*Node, []Token, error) {
+	token := tokens[start]
+	if token.Type != Not {
+		return parseComparison(tokens, start)
+	}
+
+	operand, remaining, err := parseNegation(tokens, start+1)
+	if err != nil {
+		return nil, nil, err
 	}
-	return tokens, nil
+	return &Node{Token: token, Left: operand}, remaining, nil
 }
 
-func nextToken(expr string) (Token, string, error) {
-	if len(expr) == 0 {
-		return Token{}, "", nil
+func parseComparison(tokens []Token, start int) (*Node, []Token, error) {
+	left, remaining, err := parseSum(tokens, start)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	switch expr[0] {
-	case '+':
-		return Token{Plus, "+"}, expr[1:], nil
-	case '-':
-		return Token{Minus, "-"}, expr[1:], nil
-	case '*':
-		return Token{Multiply, "*"}, expr[1:], nil
-	case '/':
-		return Token{Divide, "/"}, expr[1:], nil
-	case '(':
-		return Token{LeftParen, "("}, expr[1:], nil
-	case ')':
-		return Token{RightParen, ")"}, expr[1:], nil
-	case ' ':
-		return Token{Empty, ""}, expr[1:], nil
-	case '\t':
-		return Token{Empty, ""}, expr[1:], nil
-	default:
-		if isDigit(expr[0]) {
-			num, end := extractNumber(expr)
-			return Token{Number, num}, expr[end:], nil
+	for isComparison(remaining[0].Type) {
+		op := remaining[0]
+		remaining = remaining[1:]
+
+		right, remaining2, err := parseSum(remaining, 0)
+		if err != nil {
+			return nil, nil, err
 		}
-		return Token{}, "", fmt.Errorf("unexpected character: %c", expr[0])
+
+		left = &Node{op, left, right, 0, false}
+		remaining = remaining2
 	}
+
+	return left, remaining, nil
 }
 
-func isDigit(c byte) bool {
-	return c >= '0' && c <= '9' || c == '.'
+func isComparison(t TokenType) bool {
+	switch t {
+	case Less, LessEqual, Greater, GreaterEqual, Equal, NotEqual:
+		return true
+	}
+	return false
 }
 
-func extractNumber(s string) (string, int) {
-	var i int
-	for i < len(s) && isDigit(s[i]) {
-		i++
+func parseSum(tokens []Token, start int) (*Node, []Token, error) {
+	left, remaining, err := parseTerm(tokens, start)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for remaining[0].Type == Plus || remaining[0].Type == Minus {
+		op := remaining[0]
+		remaining = remaining[1:]
+
+		right, remaining2, err := parseTerm(remaining, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		left = &Node{op, left, right, 0, false}
+		remaining = remaining2
 	}
-	return s[:i], i
+
+	return left, remaining, nil
 }
 
 func parseTerm(tokens []Token, start int) (*Node, []Token, error) {
@@ -175,7 +464,7 @@ func parseTerm(tokens []Token, start int) (*Node, []Token, error) {
 		return nil, nil, err
 	}
 
-	for len(remaining) > 0 && (remaining[0].Type == Multiply || remaining[0].Type == Divide) {
+	for isMultiplicative(remaining[0].Type) {
 		op := remaining[0]
 		remaining = remaining[1:]
 
@@ -191,39 +480,193 @@ func parseTerm(tokens []Token, start int) (*Node, []Token, error) {
 	return left, remaining, nil
 }
 
-func parseFactor(tokens []Token, start int) (*Node, []Token, error) {
-	if start >= len(tokens) {
-		return nil, nil, errors.New("unexpected end of expression")
-	}
+func isMultiplicative(t TokenType) bool {
+	return t == Multiply || t == Divide || t == Modulo || t == FloorDivide
+}
 
+// parseFactor parses unary signs. They bind looser than exponentiation,
+// so -2^2 is parsed as -(2^2).
+func parseFactor(tokens []Token, start int) (*Node, []Token, error) {
 	token := tokens[start]
 	switch token.Type {
-	case Number:
-		value, _ := strconv.ParseFloat(token.Value, 64)
-		return &Node{token, nil, nil, value, true}, tokens[start+1:], nil
 	case Plus:
 		right, remaining, err := parseFactor(tokens, start+1)
 		if err != nil {
 			return nil, nil, err
 		}
-		return &Node{token, &Node{Token{Number, "0"}, nil, nil, 0, true}, right, 0, false}, remaining, nil
+		return &Node{token, &Node{Token{Type: Number, Value: "0", Pos: token.Pos}, nil, nil, 0, true}, right, 0, false}, remaining, nil
 	case Minus:
 		right, remaining, err := parseFactor(tokens, start+1)
 		if err != nil {
 			return nil, nil, err
 		}
-		negRight := &Node{Token{Minus, "-"}, &Node{Token{Number, "0"}, nil, nil, 0, true}, right, 0, false}
+		negRight := &Node{token, &Node{Token{Type: Number, Value: "0", Pos: token.Pos}, nil, nil, 0, true}, right, 0, false}
 		return negRight, remaining, nil
+	default:
+		return parsePower(tokens, start)
+	}
+}
+
+// parsePower parses right associative exponentiation, so 2^3^2 is parsed as 2^(3^2).
+// The exponent may carry its own unary sign, e.g. 2^-1.
+func parsePower(tokens []Token, start int) (*Node, []Token, error) {
+	base, remaining, err := parsePrimary(tokens, start)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if remaining[0].Type != Power {
+		return base, remaining, nil
+	}
+
+	op := remaining[0]
+	exponent, remaining, err := parseFactor(remaining, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Node{op, base, exponent, 0, false}, remaining, nil
+}
+
+func parsePrimary(tokens []Token, start int) (*Node, []Token, error) {
+	token := tokens[start]
+	switch token.Type {
+	case Number:
+		number, err := literal(token)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tokens[start+1].Type == Identifier && tokens[start+2].Type != LeftParen {
+			return parseUnit(number, tokens, start+1)
+		}
+		return number, tokens[start+1:], nil
 	case LeftParen:
 		expr, remaining, err := parseExpression(tokens[start+1:], 0)
 		if err != nil {
 			return nil, nil, err
 		}
-		if len(remaining) == 0 || remaining[0].Type != RightParen {
-			return nil, nil, errors.New("missing closing parenthesis")
+		if remaining[0].Type != RightParen {
+			return nil, nil, errorAt(remaining[0], "missing closing parenthesis", ")")
 		}
 		return expr, remaining[1:], nil
+	case Identifier:
+		if tokens[start+1].Type == LeftParen {
+			return parseCall(tokens, start)
+		}
+		return &Node{Token: Token{Type: Variable, Value: token.Value, Pos: token.Pos}}, tokens[start+1:], nil
+	case End:
+		return nil, nil, errorAt(token, "unexpected end of expression", expectedOperand...)
 	default:
-		return nil, nil, fmt.Errorf("unexpected token: %s", token.Value)
+		return nil, nil, errorAt(token, "unexpected token: "+token.Value, expectedOperand...)
+	}
+}
+
+// literal makes the node of a number token.
+func literal(token Token) (*Node, error) {
+	number := &Node{token, nil, nil, 0, false}
+	// the value of an imaginary number is read from its literal in complex mode
+	if !isImaginary(token) {
+		value, err := strconv.ParseFloat(strings.ReplaceAll(token.Value, "_", ""), 64)
+		if err != nil {
+			return nil, errorAt(token, "invalid number: "+token.Value)
+		}
+		number.Value, number.Parsed = value, true
+	}
+	return number, nil
+}
+
+// parseUnit parses the unit after a number, like km/h or m/s^2. The unit is
+// written without spaces, so 10 m / t is a division by the variable t.
+func parseUnit(number *Node, tokens []Token, start int) (*Node, []Token, error) {
+	end := start + 1
+	for {
+		if tokens[end].Type == Power && adjacent(tokens, end) {
+			exponent := end + 1
+			if tokens[exponent].Type == Minus && adjacent(tokens, exponent) {
+				exponent++
+			}
+			if tokens[exponent].Type == Number && adjacent(tokens, exponent) {
+				end = exponent + 1
+			}
+		}
+		if (tokens[end].Type == Multiply || tokens[end].Type == Divide) && adjacent(tokens, end) &&
+			tokens[end+1].Type == Identifier && adjacent(tokens, end+1) && tokens[end+2].Type != LeftParen {
+			end += 2
+			continue
+		}
+		break
+	}
+
+	var unit strings.Builder
+	for _, token := range tokens[start:end] {
+		unit.WriteString(token.Value)
+	}
+	return &Node{Token: Token{Type: Unit, Value: unit.String(), Pos: tokens[start].Pos}, Left: number}, tokens[end:], nil
+}
+
+// adjacent reports whether the token follows the previous one without a space.
+func adjacent(tokens []Token, i int) bool {
+	return tokens[i-1].Pos+len(tokens[i-1].Value) == tokens[i].Pos
+}
+
+// parseCall parses a function call like sqrt(2). The call is stored as a
+// Function node with its single argument in Left. The conditional
+// if(cond, then, else) is stored as an If node and a call of a function of
+// any number of arguments like sum(1, 2, 3) as an Aggregate node. A call of
+// any other name is stored as a Call node, it is a call of a user-defined
+// function.
+func parseCall(tokens []Token, start int) (*Node, []Token, error) {
+	nameToken := tokens[start]
+	name := nameToken.Value
+
+	var args []*Node
+	remaining := tokens[start+2:]
+	for remaining[0].Type != RightParen {
+		arg, remaining2, err := parseExpression(remaining, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, arg)
+		remaining = remaining2
+
+		if remaining[0].Type == Comma {
+			remaining = remaining[1:]
+			// a comma is followed by an argument, sum(1, 2,) is an error
+			if remaining[0].Type == RightParen {
+				return nil, nil, errorAt(remaining[0], "unexpected token: )", expectedOperand...)
+			}
+			continue
+		}
+		if remaining[0].Type == End {
+			return nil, nil, errorAt(remaining[0], "missing closing parenthesis", ",", ")")
+		}
+		if remaining[0].Type != RightParen {
+			return nil, nil, errorAt(remaining[0], "unexpected token: "+remaining[0].Value, ",", ")")
+		}
+	}
+
+	if functions.IsAggregate(name) {
+		if len(args) == 0 {
+			return nil, nil, errorAt(nameToken, fmt.Sprintf("function %s expects at least 1 argument", name))
+		}
+		return &Node{Token: Token{Type: Aggregate, Value: name, Pos: nameToken.Pos}, Left: argumentList(args)}, remaining[1:], nil
+	}
+
+	if name != "if" && !functions.IsUnary(name) {
+		return &Node{Token: Token{Type: Call, Value: name, Pos: nameToken.Pos}, Left: argumentList(args)}, remaining[1:], nil
 	}
+
+	if name == "if" {
+		if len(args) != 3 {
+			return nil, nil, errorAt(nameToken, fmt.Sprintf("function if expects 3 arguments, got %d", len(args)))
+		}
+		branches := &Node{Token: Token{Type: Branches, Pos: nameToken.Pos}, Left: args[1], Right: args[2]}
+		return &Node{Token: Token{Type: If, Value: name, Pos: nameToken.Pos}, Left: args[0], Right: branches}, remaining[1:], nil
+	}
+
+	if len(args) != 1 {
+		return nil, nil, errorAt(nameToken, fmt.Sprintf("function %s expects 1 argument, got %d", name, len(args)))
+	}
+
+	return &Node{Token: Token{Type: Function, Value: name, Pos: nameToken.Pos}, Left: args[0]}, remaining[1:], nil
 }
@@ -1,6 +1,10 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -20,12 +24,73 @@ func TestParser(t *testing.T) {
 		{"2 + 3 * (4 + 5)", 29.0, ""},
 		{"2.5 + 3.7", 6.2, ""},
 		{"2 * (3 + 4) / 2", 7.0, ""},
+		{"2 ^ 3", 8.0, ""},
+		{"2 ^ 3 ^ 2", 512.0, ""},
+		{"(2 ^ 3) ^ 2", 64.0, ""},
+		{"-2 ^ 2", -4.0, ""},
+		{"(-2) ^ 2", 4.0, ""},
+		{"2 ^ -1", 0.5, ""},
+		{"2 * 3 ^ 2", 18.0, ""},
+		{"100 * (1 + 0.5) ^ 2", 225.0, ""},
+		{"2 ^ ", 0.0, "unexpected end of expression"},
+		{"sqrt(16)", 4.0, ""},
+		{"abs(2 - 5) * 2", 6.0, ""},
+		{"-sqrt(4) ^ 2", -4.0, ""},
+		{"floor(2.7) + ceil(2.2) + round(2.5)", 8.0, ""},
+		{"exp(0) + ln(1) + log10(100)", 3.0, ""},
+		{"cos(0) - sin(0) + tan(0)", 1.0, ""},
+		{"sqrt(abs(-9))", 3.0, ""},
+		{"sqrt 4", 0.0, "unexpected token in expression"},
+		{"sqrt()", 0.0, "function sqrt expects 1 argument, got 0"},
+		{"sqrt(1, 2)", 0.0, "function sqrt expects 1 argument, got 2"},
+		{"sqrt(1", 0.0, "missing closing parenthesis"},
 		//{"2 / 0", 0.0, "division by zero"},
 		{"2 + ", 0.0, "unexpected end of expression"},
 		{"2 + 3 * ", 0.0, "unexpected end of expression"},
 		{"(2 + 3", 0.0, "missing closing parenthesis"},
 		{"2 + 3 ) ", 0.0, "unexpected token in expression"},
-		{"2 % 3", 0.0, "unexpected character: %"},
+		{"7 % 3", 1.0, ""},
+		{"-7 % 3", 2.0, ""},
+		{"7 // 2", 3.0, ""},
+		{"-7 // 2", -4.0, ""},
+		{"1 + 10 % 4 * 3", 7.0, ""},
+		{"2 * 7 // 4", 3.0, ""},
+		{"2 ^ 3 % 5", 3.0, ""},
+		{"7 % ", 0.0, "unexpected end of expression"},
+		{"7 / / 2", 0.0, "unexpected token: /"},
+		{"2 & 3", 0.0, "unexpected character: &"},
+		{"1 < 2", 1.0, ""},
+		{"2 <= 1", 0.0, ""},
+		{"1 + 1 == 2", 1.0, ""},
+		{"3 > 2 == 1", 1.0, ""},
+		{"2 >= 3 != 1", 1.0, ""},
+		{"(2 >= 2) * 5", 5.0, ""},
+		{"1 < 2 and 2 < 1", 0.0, ""},
+		{"0 or 2", 1.0, ""},
+		{"1 or 0 and 0", 1.0, ""},
+		{"not 1 < 0", 1.0, ""},
+		{"not 0 and 0", 0.0, ""},
+		{"if(2 > 1, 10, 20)", 10.0, ""},
+		{"if(0, 1, 2) * 3", 6.0, ""},
+		{"if(1, 2, 1 / 0)", 2.0, ""},
+		{"if(1 > 2, 1, if(2 > 1, 2, 3))", 2.0, ""},
+		{"if(1, 2)", 0.0, "function if expects 3 arguments, got 2"},
+		{"sum(1, 2, 3) + max(4, 9, 2)", 15.0, ""},
+		{"product(2, min(3, 1), 4) - avg(1, 2)", 6.5, ""},
+		{"median(5, 1, 4, 2) * median(3)", 9.0, ""},
+		{"min()", 0.0, "function min expects at least 1 argument"},
+		{"1 = 1", 0.0, "only a name can be assigned"},
+		{"!1", 0.0, "unexpected character: !"},
+		{"1 < ", 0.0, "unexpected end of expression"},
+		{"a = 2 + 3; b = a * 4; b - a", 15.0, ""},
+		{"a = 2; a = a * 3; a + 1", 7.0, ""},
+		{"a = 2; b = if(a > 1, a, 0); a * b", 4.0, ""},
+		{"a = 5;", 5.0, ""},
+		{"a = 1; b = a + 1", 2.0, ""},
+		{"1; 2", 0.0, "only the last statement can be an expression"},
+		{"a = ; 1", 0.0, "unexpected token: ;"},
+		{"a = 1 = 2", 0.0, "only a name can be assigned"},
+		{"a = x b", 0.0, "unexpected token in expression"},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.expr, func(t *testing.T) {
@@ -56,3 +121,615 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+func TestBind(t *testing.T) {
+	testCases := []struct {
+		expr      string
+		variables map[string]float64
+		expected  float64
+		errMsg    string
+	}{
+		{"price * qty * (1 - discount)", map[string]float64{"price": 9.5, "qty": 3, "discount": 0.1}, 25.65, ""},
+		{"sqrt(x) + x_2", map[string]float64{"x": 16, "x_2": 1}, 5.0, ""},
+		{"a + b * c", map[string]float64{"b": 1}, 0.0, "unbound variable: a at position 0; unbound variable: c at position 8"},
+		{"a + a", nil, 0.0, "unbound variable: a at position 0; unbound variable: a at position 4"},
+		{"2 + 2", nil, 4.0, ""},
+		{"a = x * 2; a + y", map[string]float64{"x": 3, "y": 1}, 7.0, ""},
+		{"x = 3; x * 2", map[string]float64{"x": 10}, 6.0, ""},
+		{"a = 1; b = 2; b", nil, 2.0, ""},
+		{"a = 1 + 2; b = a * 4; a", nil, 3.0, ""},
+		{"a = b; a", nil, 0.0, "unbound variable: b at position 4"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			root, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = Bind(root, tc.variables)
+			if err != nil {
+				if tc.errMsg == "" {
+					t.Errorf("unexpected error: %v", err)
+				} else if err.Error() != tc.errMsg {
+					t.Errorf("expected error '%s', got '%v'", tc.errMsg, err)
+				}
+				return
+			}
+
+			if tc.errMsg != "" {
+				t.Errorf("expected error '%s', but got no error", tc.errMsg)
+				return
+			}
+
+			result, err := root.Evaluate()
+			if err != nil {
+				t.Errorf("unexpected error during evaluation: %v", err)
+				return
+			}
+
+			if math.Abs(result-tc.expected) > 1e-9 {
+				t.Errorf("expected %f, got %f", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected float64
+		errMsg   string
+	}{
+		{"42", 42, ""},
+		{"3.25", 3.25, ""},
+		{"5.", 5, ""},
+		{".5", 0.5, ""},
+		{"6.02e23", 6.02e23, ""},
+		{"1E-9", 1e-9, ""},
+		{"2.5e+3", 2500, ""},
+		{".5e1", 5, ""},
+		{"1_000_000", 1000000, ""},
+		{"1_000.000_1", 1000.0001, ""},
+		{"1e1_0", 1e10, ""},
+		{"1.2.3", 0, "malformed number: 1.2.3 at position 0"},
+		{"2 + .", 0, "malformed number: . at position 4"},
+		{"1e", 0, "malformed number: 1e at position 0"},
+		{"1e+", 0, "malformed number: 1e at position 0"},
+		{"3 * 2.5E-", 0, "malformed number: 2.5E at position 4"},
+		{"1__000", 0, "malformed number: 1__000 at position 0"},
+		{"1000_", 0, "malformed number: 1000_ at position 0"},
+		{"1_.5", 0, "malformed number: 1_.5 at position 0"},
+		{"1._5", 0, "malformed number: 1._5 at position 0"},
+		{"1 + 2..5", 0, "malformed number: 2..5 at position 4"},
+		{"1e999", 0, "number out of range: 1e999 at position 0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			root, err := Parse(tc.expr)
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Errorf("expected error '%s', but got no error", tc.errMsg)
+				} else if err.Error() != tc.errMsg {
+					t.Errorf("expected error '%s', got '%v'", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result, err := root.Evaluate()
+			if err != nil {
+				t.Fatalf("unexpected error during evaluation: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("expected %g, got %g", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestUnits(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{"5 m + 30 cm", "(+ (m 5) (cm 30))"},
+		{"100 km / 2 h", "(/ (km 100) (h 2))"},
+		{"3 kg * 9.81 m/s^2", "(* (kg 3) (m/s^2 9.81))"},
+		{"2 m^2", "(m^2 2)"},
+		{"2 m ^ 2", "(^ (m 2) 2)"},
+		{"1 s^-1*kg", "(s^-1*kg 1)"},
+		{"10 m / t", "(/ (m 10) t)"},
+		{"10 m/t", "(m/t 10)"},
+		{"3 m*sqrt(4)", "(* (m 3) (sqrt 4))"},
+		{"x = 2 km; x / 4 min", "(let x (km 2) (/ x (min 4)))"},
+		{"2in", "(in 2)"},
+		{"3i V", "(V 3i)"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			root, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := sexpr(root); s != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, s)
+			}
+		})
+	}
+}
+
+// sexpr writes the tree as an s-expression with the unit in place of the operator.
+func sexpr(n *Node) string {
+	switch n.Token.Type {
+	case Number, Variable:
+		return n.Token.Value
+	case Let:
+		return fmt.Sprintf("(let %s %s %s)", n.Token.Value, sexpr(n.Left), sexpr(n.Right))
+	case Unit, Function:
+		return fmt.Sprintf("(%s %s)", n.Token.Value, sexpr(n.Left))
+	}
+	return fmt.Sprintf("(%s %s %s)", n.Token.Value, sexpr(n.Left), sexpr(n.Right))
+}
+
+func TestParseDialect(t *testing.T) {
+	isUnit := func(name string) bool { return name == "m" || name == "s" || name == "t" || name == "min" }
+	testCases := []struct {
+		expr     string
+		dialect  Dialect
+		expected string
+	}{
+		{"2(3+4)", Dialect{Math: true}, "2 * (3 + 4)"},
+		{"(a+b)(a-b)", Dialect{Math: true}, "(a + b) * (a - b)"},
+		{"3x", Dialect{Math: true}, "3 * x"},
+		{"2x^2 y", Dialect{Math: true}, "2 * x^2 * y"},
+		{"1/2x", Dialect{Math: true}, "1 / 2 * x"},
+		{"2 sqrt(4)(1)", Dialect{Math: true}, "2 * sqrt(4) * 1"},
+		{"(1)2", Dialect{Math: true}, "1 * 2"},
+		{"f(x) = 2x; f(3)", Dialect{Math: true}, "f(x) = 2 * x; f(3)"},
+		{"2 × 3 ÷ 4", Dialect{Math: true}, "2 * 3 / 4"},
+		{"5 − 2", Dialect{Math: true}, "5 - 2"},
+		{"x² ≤ 2·x", Dialect{Math: true}, "x^2 <= 2 * x"},
+		{"5 m/s t", Dialect{Math: true, IsUnit: isUnit}, "5 m/s * t"},
+		{"5 mt", Dialect{Math: true, IsUnit: isUnit}, "5 * mt"},
+		{"t = 3; 2 t + 4 t", Dialect{Math: true, IsUnit: isUnit}, "t = 3; 2 * t + 4 * t"},
+		{"f(t) = 2 t; f(1) * 5 m", Dialect{Math: true, IsUnit: isUnit}, "f(t) = 2 * t; f(1) * 5 m"},
+		{"3 min(1, 2) + 4 min", Dialect{Math: true, IsUnit: isUnit}, "3 * min(1, 2) + 4 min"},
+		{"1,5 + 2,25", Dialect{DecimalComma: true}, "1.5 + 2.25"},
+		{"g(x1,2) * 1,5 + g(1, 2)", Dialect{DecimalComma: true}, "g(x1, 2) * 1.5 + g(1, 2)"},
+		{"sum(1, 2.5) + (1,5)", Dialect{DecimalComma: true}, "sum(1, 2.5) + 1.5"},
+		{"2(1,5)", Dialect{Math: true, DecimalComma: true}, "2 * 1.5"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			root, err := ParseDialect(tc.expr, tc.dialect)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := root.String(); s != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, s)
+			}
+		})
+	}
+
+	// the positions of errors point to the expression as it was written
+	_, err := ParseDialect("2 × × 3", Dialect{Math: true})
+	var errs SyntaxErrors
+	if !errors.As(err, &errs) || errs[0].Offset != 5 {
+		t.Errorf("expected an error at position 5, got %v", err)
+	}
+	// a comma between digits in the arguments of a function is ambiguous
+	for _, expr := range []string{"sum(1,2,3)", "sum(1, 2,5)", "sqrt (2,25)", "f(1, g(1,5))"} {
+		_, err = ParseDialect(expr, Dialect{DecimalComma: true})
+		if !errors.As(err, &errs) || !strings.Contains(errs[0].Message, "ambiguous comma") {
+			t.Errorf("expected an ambiguous comma error for %s, got %v", expr, err)
+		}
+	}
+	if _, err := ParseDialect("2(3+4)", Dialect{}); err == nil {
+		t.Errorf("expected an error for implicit multiplication in the strict dialect")
+	}
+}
+
+func TestNotations(t *testing.T) {
+	testCases := []struct {
+		infix  string
+		rpn    string
+		prefix string
+	}{
+		{"(3 + 4) * 2", "3 4 + 2 *", "(* (+ 3 4) 2)"},
+		{"1 - 2 - 3", "1 2 - 3 -", "(- (- 1 2) 3)"},
+		{"2^3^2", "2 3 2 ^ ^", "(^ 2 (^ 3 2))"},
+		{"-x * 2", "0 x - 2 *", "(* (- 0 x) 2)"},
+		{"sqrt(x^2 + 1) // 2", "x 2 ^ 1 + sqrt 2 //", "(// (sqrt (+ (^ x 2) 1)) 2)"},
+		{"if(x > 1 and not y, 1, 0)", "x 1 > y not and 1 0 if", "(if (and (> x 1) (not y)) 1 0)"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.infix, func(t *testing.T) {
+			root, err := Parse(tc.infix)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rpn, err := root.FormatRPN()
+			if err != nil || rpn != tc.rpn {
+				t.Errorf("expected RPN %q, got %q, %v", tc.rpn, rpn, err)
+			}
+			prefix, err := root.FormatPrefix()
+			if err != nil || prefix != tc.prefix {
+				t.Errorf("expected prefix %q, got %q, %v", tc.prefix, prefix, err)
+			}
+
+			fromRPN, err := ParseRPN(tc.rpn, Dialect{})
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", tc.rpn, err)
+			}
+			fromPrefix, err := ParsePrefix(tc.prefix, Dialect{})
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", tc.prefix, err)
+			}
+			if fromRPN.String() != root.String() || fromPrefix.String() != root.String() {
+				t.Errorf("expected %q, got %q from RPN and %q from prefix", root, fromRPN, fromPrefix)
+			}
+		})
+	}
+
+	shorthands := []struct {
+		expr     string
+		parse    func(string, Dialect) (*Node, error)
+		expected string
+	}{
+		{"3 -4 *", ParseRPN, "3 * (0 - 4)"},
+		{"3 4 ×", ParseRPN, "3 * 4"},
+		{"* + 3 4 2", ParsePrefix, "(3 + 4) * 2"},
+		{"(+ 1 2 3)", ParsePrefix, "1 + 2 + 3"},
+		{"(- x)", ParsePrefix, "0 - x"},
+		{"(f x (g) 2)", ParsePrefix, "f(x, g(), 2)"},
+		{"((+ 1 2))", ParsePrefix, "1 + 2"},
+		{"(sum 1 (* 2 x) 3)", ParsePrefix, "sum(1, 2 * x, 3)"},
+	}
+	for _, tc := range shorthands {
+		t.Run(tc.expr, func(t *testing.T) {
+			root, err := tc.parse(tc.expr, Dialect{Math: true})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if root.String() != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, root)
+			}
+		})
+	}
+
+	errorCases := []struct {
+		expr   string
+		parse  func(string, Dialect) (*Node, error)
+		errMsg string
+	}{
+		{"3 +", ParseRPN, "+ expects 2 operands, got 1 at position 2"},
+		{"3 4", ParseRPN, "missing operator, 2 operands are left at position 3"},
+		{"", ParseRPN, "unexpected end of expression at position 0"},
+		{"3 ( 4 +", ParseRPN, "unexpected token: ( at position 2"},
+		{"(+ 1 2", ParsePrefix, "missing closing parenthesis at position 6"},
+		{"(sqrt 1 2)", ParsePrefix, "sqrt expects 1 operand, got 2 at position 1"},
+		{"+ 1", ParsePrefix, "unexpected end of expression at position 3"},
+		{"+ 1 2 3", ParsePrefix, "unexpected token in expression at position 6"},
+		{"(median)", ParsePrefix, "function median expects at least 1 argument at position 1"},
+		{"1 2 max", ParseRPN, "unexpected token: max at position 4"},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := tc.parse(tc.expr, Dialect{})
+			if err == nil || err.Error() != tc.errMsg {
+				t.Errorf("expected error %q, got %v", tc.errMsg, err)
+			}
+		})
+	}
+
+	aggregate, err := Parse("max(1, 2, 3)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := aggregate.FormatRPN(); err == nil {
+		t.Errorf("expected error writing %q in RPN", aggregate)
+	}
+	if prefix, err := aggregate.FormatPrefix(); err != nil || prefix != "(max 1 2 3)" {
+		t.Errorf("expected prefix %q, got %q, %v", "(max 1 2 3)", prefix, err)
+	}
+
+	for _, expr := range []string{"a = 1; a", "2 m"} {
+		root, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := root.FormatRPN(); err == nil {
+			t.Errorf("expected error writing %q in RPN", expr)
+		}
+		if _, err := root.FormatPrefix(); err == nil {
+			t.Errorf("expected error writing %q in prefix notation", expr)
+		}
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected SyntaxErrors
+	}{
+		{"2 + ", SyntaxErrors{{Offset: 4, Length: 0, Expected: expectedOperand, Message: "unexpected end of expression"}}},
+		{"(2 + 3", SyntaxErrors{{Offset: 6, Length: 0, Expected: []string{")"}, Message: "missing closing parenthesis"}}},
+		{"2 + 3 ) ", SyntaxErrors{{Offset: 6, Length: 1, Expected: expectedOperator, Message: "unexpected token in expression"}}},
+		{"2 * * 3", SyntaxErrors{{Offset: 4, Length: 1, Expected: expectedOperand, Message: "unexpected token: *"}}},
+		{"sqrt(1 2)", SyntaxErrors{{Offset: 7, Length: 1, Expected: []string{",", ")"}, Message: "unexpected token: 2"}}},
+		{"sum(1,2,)", SyntaxErrors{{Offset: 8, Length: 1, Expected: expectedOperand, Message: "unexpected token: )"}}},
+		{"sin(1,)", SyntaxErrors{{Offset: 6, Length: 1, Expected: expectedOperand, Message: "unexpected token: )"}}},
+		{"1.2.3 + 4 $ 5 # 6", SyntaxErrors{
+			{Offset: 0, Length: 5, Message: "malformed number: 1.2.3"},
+			{Offset: 10, Length: 1, Message: "unexpected character: $"},
+			{Offset: 14, Length: 1, Message: "unexpected character: #"},
+		}},
+		{"2 × 3", SyntaxErrors{{Offset: 2, Length: 2, Message: "unexpected character: ×"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := Parse(tc.expr)
+			var errs SyntaxErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected SyntaxErrors, got %v", err)
+			}
+			if !reflect.DeepEqual(errs, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, errs)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	functions := map[string]*Definition{}
+	for _, definition := range []string{
+		"sq(x) = x^2",
+		"hyp(a, b) = sqrt(sq(a) + sq(b))",
+		"f(x, y) = x^2 + y",
+		"ping(x) = pong(x) + 1",
+		"pong(x) = ping(x) - 1",
+		"twice(x) = x + x",
+	} {
+		fn, err := ParseDefinition(definition, Limits{})
+		if err != nil {
+			t.Fatalf("ParseDefinition(%q) returned error: %v", definition, err)
+		}
+		functions[fn.Name] = fn
+	}
+
+	testCases := []struct {
+		expr     string
+		expected float64
+		errMsg   string
+	}{
+		{"f(3, 4)", 13.0, ""},
+		{"hyp(3, 4)", 5.0, ""},
+		{"sq(sq(2)) + sq(1 + 1)", 20.0, ""},
+		{"twice(twice(3))", 12.0, ""},
+		{"a = 3; sq(a) - a", 6.0, ""},
+		{"g(x) = x * 2; g(g(3))", 12.0, ""},
+		{"f(x) = x + 1; f(1)", 2.0, ""},
+		{"g(x) = sq(x) + 1; h() = g(2) * 2; h()", 10.0, ""},
+		{"foo(1)", 0.0, "unknown function: foo at position 0"},
+		{"f(1)", 0.0, "function f expects 2 arguments, got 1"},
+		{"ping(1)", 0.0, "recursive call of function ping: ping -> pong -> ping"},
+		{"r(x) = r(x) + 1; 2", 0.0, "recursive call of function r: r -> r"},
+		{"p(x) = q(x); q(x) = p(x); q(1)", 0.0, "unknown function: q at position 7"},
+		{"twice(twice(twice(twice(twice(twice(1))))))", 0.0, "calls of user-defined functions expand to more than 100 nodes"},
+		{"g(x) = x + y; g(1)", 0.0, "function g has no parameter y at position 11"},
+		{"g(x, x) = x; g(1, 2)", 0.0, "duplicate parameter: x at position 5"},
+		{"sqrt(x) = x; 1", 0.0, "cannot redefine built-in function: sqrt"},
+		{"g(x) = x", 0.0, "a script cannot end with a function definition"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			root, err := Parse(tc.expr)
+			if err == nil {
+				root, err = Expand(root, functions, 100)
+			}
+			if err == nil {
+				err = Bind(root, nil)
+			}
+			if tc.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+					t.Errorf("expected error '%s', got '%v'", tc.errMsg, err)
+				}
+				var errs SyntaxErrors
+				if err != nil && !errors.As(err, &errs) {
+					t.Errorf("expected SyntaxErrors, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result, err := root.Evaluate()
+			if err != nil {
+				t.Fatalf("unexpected error during evaluation: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("expected %g, got %g", tc.expected, result)
+			}
+		})
+	}
+
+	// a zero limit is no limit, recursion is still rejected
+	root, _ := Parse("twice(twice(twice(twice(twice(twice(1))))))")
+	if root, err := Expand(root, functions, 0); err != nil {
+		t.Errorf("unexpected error with no limit: %v", err)
+	} else if result, _ := root.Evaluate(); result != 64 {
+		t.Errorf("expected 64, got %g", result)
+	}
+	root, _ = Parse("ping(1)")
+	if _, err := Expand(root, functions, 0); err == nil {
+		t.Error("expected an error for a recursive call with no limit, got nil")
+	}
+}
+
+func TestParseDefinition(t *testing.T) {
+	fn, err := ParseDefinition("f(x, y) = x^2 + y", Limits{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fn.Name != "f" || !reflect.DeepEqual(fn.Params, []string{"x", "y"}) || fn.String() != "f(x, y) = x^2 + y" {
+		t.Errorf("expected f(x, y) = x^2 + y, got %v", fn)
+	}
+
+	root, err := Parse("g(x) = x + 1; a = g(2); h(y) = g(y) * 2; h(a)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defs := ScriptDefinitions(root)
+	if len(defs) != 2 || defs[0].String() != "g(x) = x + 1" || defs[1].String() != "h(y) = g(y) * 2" {
+		t.Errorf("expected the definitions of g and h, got %v", defs)
+	}
+
+	for _, definition := range []string{"f(3) = 3", "f(x) = x; f(1)", "2 + 2", "sum(x) = x"} {
+		if _, err := ParseDefinition(definition, Limits{}); err == nil {
+			t.Errorf("expected error for %q", definition)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{"1 + 2 * 3", "1 + 2 * 3"},
+		{"(1 + 2) * 3", "(1 + 2) * 3"},
+		{"1 - (2 - 3)", "1 - (2 - 3)"},
+		{"2^3^2", "2^3^2"},
+		{"(2^3)^2", "(2^3)^2"},
+		{"-2^2", "0 - 2^2"},
+		{"2^-1", "2^(0 - 1)"},
+		{"not (1 and 0) or x < 2", "not (1 and 0) or x < 2"},
+		{"if(x > 1, sqrt(x), f(x, 2))", "if(x > 1, sqrt(x), f(x, 2))"},
+		{"g(x) = x * 2; a = g(3); a + 1", "g(x) = x * 2; a = g(3); a + 1"},
+		{"-5 m/s^2 * 2 s", "(0 - 5 m/s^2) * 2 s"},
+		{"sum(1, x + 2, max(y)) * 3 min", "sum(1, x + 2, max(y)) * 3 min"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			root, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := root.String(); s != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, s)
+			}
+
+			reparsed, err := Parse(root.String())
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", root.String(), err)
+			}
+			if reparsed.String() != root.String() {
+				t.Errorf("expected %q to parse into the same tree, got %q", root.String(), reparsed.String())
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{MaxLength: 1000, MaxTokens: 100, MaxDepth: 10}
+	testCases := []struct {
+		expr   string
+		limit  Limit
+		offset int
+	}{
+		{expr: strings.Repeat("1+", 600) + "1", limit: LimitLength, offset: 1000},
+		{expr: strings.Repeat("1+", 60) + "1", limit: LimitTokens, offset: 100},
+		{expr: strings.Repeat("(", 11) + "1" + strings.Repeat(")", 11), limit: LimitDepth, offset: 10},
+		{expr: strings.Repeat("-", 11) + "1", limit: LimitDepth, offset: 10},
+		{expr: "2" + strings.Repeat("^2", 11), limit: LimitDepth, offset: 21},
+		{expr: "not " + strings.Repeat("-(", 5) + "1" + strings.Repeat(")", 5), limit: LimitDepth, offset: 13},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%.20s", tc.expr), func(t *testing.T) {
+			var limitErr *LimitError
+			_, err := ParseDialect(tc.expr, Dialect{Limits: limits})
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Expected a limit error, got %v", err)
+			}
+			if limitErr.Limit != tc.limit || limitErr.Offset != tc.offset {
+				t.Errorf("Expected %s limit at position %d, got %s at position %d", tc.limit, tc.offset, limitErr.Limit, limitErr.Offset)
+			}
+		})
+	}
+
+	// operands at the same level do not nest
+	for _, expr := range []string{
+		strings.Repeat("(1) + ", 16) + "1",
+		strings.Repeat("-1 * ", 16) + "1",
+		"f(" + strings.Repeat("-(2)^2, ", 8) + "1)",
+		strings.Repeat("(", 10) + "1" + strings.Repeat(")", 10),
+	} {
+		if _, err := ParseDialect(expr, Dialect{Limits: limits}); err != nil {
+			t.Errorf("ParseDialect(%q) returned error: %v", expr, err)
+		}
+	}
+
+	// the nesting is checked before the parser recurses into it
+	deep := strings.Repeat("(", 1<<18) + "1" + strings.Repeat(")", 1<<18)
+	for name, parse := range map[string]func(string, Dialect) (*Node, error){"infix": ParseDialect, "rpn": ParseRPN, "prefix": ParsePrefix} {
+		var limitErr *LimitError
+		if _, err := parse(deep, Dialect{Limits: Limits{MaxDepth: 100}}); !errors.As(err, &limitErr) {
+			t.Errorf("Expected a limit error in %s notation, got %v", name, err)
+		}
+	}
+	var limitErr *LimitError
+	if _, err := ParseDefinition("f(x) = "+strings.Repeat("-", 1<<18)+"x", limits); !errors.As(err, &limitErr) {
+		t.Errorf("Expected a limit error for the definition, got %v", err)
+	}
+}
+
+// FuzzParse checks that the parsers never panic: any input is either parsed
+// into a tree that can be printed and parsed again or rejected with an error
+// describing the problem.
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"2 + 2 * 2", "-(3 - 4) ^ -2", "a = 2; b = a * 4; b - a", "f(x, y) = x^2 + y; f(3, 4)",
+		"if(x > 0 and not y, sqrt(x), -x)", "5 km/h + 2 m/s^2", "2(3+4)x²", "1,5 × 2", "3+4i",
+		"3 4 + 2 *", "(* (+ 1 2 3) (- x))", "if 1 2 3", "1_000.5e-3 // 7 % 2", "median(1, sum(2, 3), max(x)) min", "((((", "))))", "1 +", "",
+	} {
+		f.Add(seed)
+	}
+	limits := Limits{MaxLength: 1000, MaxTokens: 200, MaxDepth: 20}
+	f.Fuzz(func(t *testing.T, expr string) {
+		parsers := map[string]func(string, Dialect) (*Node, error){"infix": ParseDialect, "rpn": ParseRPN, "prefix": ParsePrefix}
+		dialects := []Dialect{{Limits: limits}, {Math: true, DecimalComma: true, Limits: limits}}
+		for name, parse := range parsers {
+			for _, dialect := range dialects {
+				root, err := parse(expr, dialect)
+				if err != nil {
+					checkParseError(t, expr, err)
+					continue
+				}
+				printed := root.String()
+				if _, err := Parse(printed); err != nil {
+					t.Errorf("%s: %q is printed as %q, which does not parse: %v", name, expr, printed, err)
+				}
+				root.FormatRPN()
+				root.FormatPrefix()
+			}
+		}
+		if fn, err := ParseDefinition(expr, limits); err != nil {
+			checkParseError(t, expr, err)
+		} else {
+			_ = fn.String()
+		}
+	})
+}
+
+func checkParseError(t *testing.T, expr string, err error) {
+	t.Helper()
+	var syntaxErrors SyntaxErrors
+	var limitErr *LimitError
+	if !errors.As(err, &syntaxErrors) && !errors.As(err, &limitErr) {
+		t.Errorf("%q is rejected with an unexpected error: %v", expr, err)
+	}
+}
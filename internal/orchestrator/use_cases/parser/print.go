@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// precedence returns how tightly the operator of the node binds,
+// it follows the order of the parse functions.
+func precedence(node *Node) int {
+	switch node.Token.Type {
+	case Or:
+		return 1
+	case And:
+		return 2
+	case Not:
+		return 3
+	case Less, LessEqual, Greater, GreaterEqual, Equal, NotEqual:
+		return 4
+	case Plus, Minus:
+		return 5
+	case Multiply, Divide, Modulo, FloorDivide:
+		return 6
+	case Power:
+		return 7
+	}
+	return 8
+}
+
+// String writes the expression tree back as an expression that parses
+// into the same tree. Parentheses are added only where they are needed.
+// A unary minus is written as a subtraction from zero, like it is parsed.
+func (n *Node) String() string {
+	switch n.Token.Type {
+	case Number, Variable:
+		return n.Token.Value
+	case Function:
+		return fmt.Sprintf("%s(%s)", n.Token.Value, n.Left)
+	case If:
+		return fmt.Sprintf("if(%s, %s, %s)", n.Left, n.Right.Left, n.Right.Right)
+	case Call, Aggregate:
+		var args []string
+		for _, arg := range argumentNodes(n.Left) {
+			args = append(args, arg.String())
+		}
+		return fmt.Sprintf("%s(%s)", n.Token.Value, strings.Join(args, ", "))
+	case Min, Max:
+		return fmt.Sprintf("%s(%s, %s)", n.Token.Value, n.Left, n.Right)
+	case Not:
+		return "not " + operand(n.Left, precedence(n), false)
+	case Let:
+		return fmt.Sprintf("%s = %s; %s", n.Token.Value, n.Left, n.Right)
+	case Define:
+		return fmt.Sprintf("%s; %s", lambdaDefinition(n.Left), n.Right)
+	case Unit:
+		return fmt.Sprintf("%s %s", n.Left, n.Token.Value)
+	}
+
+	if n.Token.Type == Power {
+		// exponentiation is right associative
+		return operand(n.Left, precedence(n)+1, false) + "^" + operand(n.Right, precedence(n), false)
+	}
+	return operand(n.Left, precedence(n), false) + " " + n.Token.Value + " " + operand(n.Right, precedence(n), true)
+}
+
+// operand writes the operand of an operator that binds as tightly as p.
+// The right operand of a left associative operator is parenthesized
+// when it binds as tightly as the operator.
+func operand(node *Node, p int, right bool) string {
+	if precedence(node) < p || (right && precedence(node) == p) {
+		return "(" + node.String() + ")"
+	}
+	return node.String()
+}
+
+// String writes the definition like f(x, y) = x^2 + y.
+func (d *Definition) String() string {
+	return fmt.Sprintf("%s(%s) = %s", d.Name, strings.Join(d.Params, ", "), d.Body)
+}
@@ -0,0 +1,175 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/use_cases/parser"
+	"fmt"
+)
+
+// aggregateReducer rewrites the calls of the functions of any number of
+// arguments into trees of binary operations.
+type aggregateReducer struct {
+	strictOrder bool
+	// names is the number of internal names assigned so far.
+	names int
+}
+
+// reduceAggregates reduces sum, product, min, max, avg and median to binary
+// operations, so their arguments are computed by many agents at once. min
+// and max are balanced trees of Min and Max. sum and product are chains of
+// additions and multiplications, which rebalance balances unless the order
+// is strict, and avg is the sum divided by the number of arguments. median
+// is the middle of the arguments sorted by a network of Min and Max, only
+// the comparisons the middle depends on are kept.
+func reduceAggregates(root *parser.Node, strictOrder bool) *parser.Node {
+	r := &aggregateReducer{strictOrder: strictOrder}
+	return r.reduce(root)
+}
+
+func (r *aggregateReducer) reduce(node *parser.Node) *parser.Node {
+	if node == nil {
+		return nil
+	}
+	node.Left = r.reduce(node.Left)
+	node.Right = r.reduce(node.Right)
+	if node.Token.Type != parser.Aggregate {
+		return node
+	}
+
+	var args []*parser.Node
+	for list := node.Left; list != nil; list = list.Right {
+		args = append(args, list.Left)
+	}
+	if len(args) == 1 {
+		return args[0]
+	}
+
+	pos := node.Token.Pos
+	switch node.Token.Value {
+	case "sum":
+		return chain(operator(parser.Plus, "+", pos), args)
+	case "product":
+		return chain(operator(parser.Multiply, "*", pos), args)
+	case "min":
+		return balancedTree(operator(parser.Min, "min", pos), args)
+	case "max":
+		return balancedTree(operator(parser.Max, "max", pos), args)
+	case "avg":
+		return average(args, pos)
+	}
+	return r.median(args, pos)
+}
+
+func operator(t parser.TokenType, value string, pos int) parser.Token {
+	return parser.Token{Type: t, Value: value, Pos: pos}
+}
+
+// chain applies the operator to the operands from left to right.
+func chain(op parser.Token, operands []*parser.Node) *parser.Node {
+	node := operands[0]
+	for _, operand := range operands[1:] {
+		node = &parser.Node{Token: op, Left: node, Right: operand}
+	}
+	return node
+}
+
+// average divides the sum of the operands by their number.
+func average(operands []*parser.Node, pos int) *parser.Node {
+	return &parser.Node{
+		Token: operator(parser.Divide, "/", pos),
+		Left:  chain(operator(parser.Plus, "+", pos), operands),
+		Right: numberNode(float64(len(operands))),
+	}
+}
+
+// median sorts the operands with Batcher's odd-even merge sort network and
+// returns its middle output, or the average of the two middle outputs. The
+// network sorts a power of two of values, the missing ones are taken as
+// infinite, so the comparisons with them are left out.
+//
+// The outputs of the comparisons are used twice, by the next comparisons of
+// both of their values, so every output is assigned an internal name which
+// the tasks share instead of copying its tree.
+func (r *aggregateReducer) median(operands []*parser.Node, pos int) *parser.Node {
+	n := len(operands)
+	if n == 2 {
+		return average(operands, pos)
+	}
+
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+	type comparator struct {
+		a, b             int
+		needMin, needMax bool
+	}
+	var comparators []comparator
+	for p := 1; p < size; p <<= 1 {
+		for k := p; k >= 1; k >>= 1 {
+			for j := k % p; j+k < size; j += 2 * k {
+				for i := 0; i < k; i++ {
+					a, b := i+j, i+j+k
+					if a/(2*p) == b/(2*p) && b < n {
+						comparators = append(comparators, comparator{a: a, b: b})
+					}
+				}
+			}
+		}
+	}
+
+	// the values the middle outputs depend on, from the last comparison back
+	middle := []int{n / 2}
+	if n%2 == 0 {
+		middle = []int{n/2 - 1, n / 2}
+	}
+	needed := make([]bool, n)
+	for _, i := range middle {
+		needed[i] = true
+	}
+	for i := len(comparators) - 1; i >= 0; i-- {
+		c := &comparators[i]
+		c.needMin, c.needMax = needed[c.a], needed[c.b]
+		if c.needMin || c.needMax {
+			needed[c.a], needed[c.b] = true, true
+		}
+	}
+
+	var lets []*parser.Node
+	assign := func(value *parser.Node) *parser.Node {
+		switch value.Token.Type {
+		case parser.Number, parser.Variable:
+			return value
+		}
+		r.names++
+		// the lexer makes no names with #, so they never hide the names of the script
+		name := fmt.Sprintf("#%d", r.names)
+		lets = append(lets, &parser.Node{Token: parser.Token{Type: parser.Let, Value: name, Pos: pos}, Left: value})
+		return &parser.Node{Token: parser.Token{Type: parser.Variable, Value: name, Pos: pos}}
+	}
+
+	wires := make([]*parser.Node, n)
+	for i, operand := range operands {
+		if needed[i] {
+			wires[i] = assign(operand)
+		}
+	}
+	for _, c := range comparators {
+		a, b := wires[c.a], wires[c.b]
+		if c.needMin {
+			wires[c.a] = assign(&parser.Node{Token: operator(parser.Min, "min", pos), Left: a, Right: b})
+		}
+		if c.needMax {
+			wires[c.b] = assign(&parser.Node{Token: operator(parser.Max, "max", pos), Left: a, Right: b})
+		}
+	}
+
+	body := wires[middle[0]]
+	if len(middle) == 2 {
+		body = average([]*parser.Node{wires[middle[0]], wires[middle[1]]}, pos)
+	}
+	for i := len(lets) - 1; i >= 0; i-- {
+		lets[i].Right = body
+		body = lets[i]
+	}
+	return body
+}
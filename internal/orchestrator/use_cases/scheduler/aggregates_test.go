@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"calculator/internal/shared/functions"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReduceAggregates(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for n := 1; n <= 33; n++ {
+		// the middle of a sorting network is checked on many orders of the arguments
+		for round := 0; round < 20; round++ {
+			args := make([]float64, n)
+			terms := make([]string, n)
+			for i := range args {
+				args[i] = float64(random.Intn(2 * n))
+				terms[i] = strconv.FormatFloat(args[i], 'g', -1, 64)
+			}
+			for _, name := range []string{"sum", "product", "min", "max", "avg", "median"} {
+				expression := name + "(" + strings.Join(terms, ", ") + ")"
+				root, err := parser.Parse(expression)
+				if err != nil {
+					t.Fatalf("Parse(%q) returned error: %v", expression, err)
+				}
+				root = rebalance(reduceAggregates(root, false))
+
+				expected, err := functions.ApplyAggregate(name, args)
+				if err != nil {
+					t.Fatalf("ApplyAggregate returned error: %v", err)
+				}
+				result, err := root.Evaluate()
+				if err != nil {
+					t.Fatalf("Evaluate(%q) returned error: %v", expression, err)
+				}
+				// the grouping of a balanced product changes its rounding
+				if math.Abs(result-expected) > 1e-12*math.Abs(expected) {
+					t.Errorf("Expected %s to be %f, got %f", expression, expected, result)
+				}
+				if depth, bound := criticalPath(root), aggregateDepth(name, n); depth > bound {
+					t.Errorf("Expected %s to take at most %d steps, got %d", expression, bound, depth)
+				}
+			}
+		}
+	}
+}
+
+// aggregateDepth returns the critical path of the reduced aggregate of n arguments:
+// the depth of a balanced tree, the division of avg after it, and the depth
+// of Batcher's network and the average of the two middle values for median.
+func aggregateDepth(name string, n int) int {
+	log := 0
+	for 1<<log < n {
+		log++
+	}
+	switch name {
+	case "avg":
+		return log + 1
+	case "median":
+		return log*(log+1)/2 + 2
+	}
+	return log
+}
+
+func TestScheduleExpressionAggregates(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{})
+
+	testCases := []struct {
+		expression  string
+		strictOrder bool
+		result      float64
+		depth       int
+	}{
+		{expression: "sum(1, 2, 3, 4, 5, 6, 7, 8)", result: 36, depth: 3},
+		{expression: "sum(1, 2, 3, 4, 5, 6, 7, 8)", strictOrder: true, result: 36, depth: 7},
+		{expression: "product(x, 2, 3)", result: 30, depth: 2},
+		{expression: "min(x, 2, 9, 1) + max(3, x)", result: 6, depth: 3},
+		{expression: "avg(1, 2, 3, x)", result: 2.75, depth: 3},
+		{expression: "median(9, x, 1, 7, 3)", result: 5, depth: 5},
+		{expression: "median(4, x, 1, 7)", result: 4.5, depth: 5},
+		{expression: "a = median(3, 1, 2); sum(a, median(a, 5, x))", result: 7, depth: 7},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			id := strconv.Itoa(i)
+			expr := &entities.Expression{ID: id, Expression: tc.expression, Variables: map[string]float64{"x": 5}, StrictOrder: tc.strictOrder}
+			if err := s.ScheduleExpression(expr); err != nil {
+				t.Fatalf("ScheduleExpression returned error: %v", err)
+			}
+			for {
+				task, err := s.GetTask()
+				if err != nil {
+					break
+				}
+				if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: compute(task), Lease: task.Lease}); err != nil {
+					t.Fatalf("ProcessResult returned error: %v", err)
+				}
+			}
+
+			stored, err := storage.GetExpression(id)
+			if err != nil {
+				t.Fatalf("GetExpression returned error: %v", err)
+			}
+			if stored.Status != entities.ExpressionStatusCompleted || stored.Result != tc.result {
+				t.Errorf("Expected completed expression with result %f, got %+v", tc.result, stored)
+			}
+			if stored.Depth != tc.depth {
+				t.Errorf("Expected depth %d, got %d", tc.depth, stored.Depth)
+			}
+			for name := range stored.Bindings {
+				if strings.HasPrefix(name, "#") {
+					t.Errorf("Expected no internal names in bindings, got %v", stored.Bindings)
+				}
+			}
+		})
+	}
+
+	// a median of many arguments is a DAG, its tasks share the comparisons
+	terms := make([]string, 200)
+	for i := range terms {
+		terms[i] = fmt.Sprintf("%d", (i*37)%200)
+	}
+	expr := &entities.Expression{ID: "large", Expression: "median(" + strings.Join(terms, ", ") + ")"}
+	if err := s.ScheduleExpression(expr); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	if expr.Depth > aggregateDepth("median", len(terms)) {
+		t.Errorf("Expected the median to take at most %d steps, got %d", aggregateDepth("median", len(terms)), expr.Depth)
+	}
+
+	if _, err := s.DefineFunction("sum(x) = x + 1"); err == nil {
+		t.Error("Expected an error for redefining sum, got nil")
+	}
+}
+
+func TestScheduleExpressionMedianInBranch(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{})
+
+	testCases := []struct {
+		x          float64
+		result     float64
+		operations []string
+	}{
+		// the comparisons of the median of the branch not chosen are never sent
+		{x: 0, result: 0, operations: []string{">"}},
+		{x: 5, result: 8},
+	}
+	for i, tc := range testCases {
+		id := fmt.Sprintf("branch%d", i)
+		expr := &entities.Expression{ID: id, Expression: "if(x > 1, median(x+1, x+2, x+3, x+4, x+5), 0)", Variables: map[string]float64{"x": tc.x}}
+		if err := s.ScheduleExpression(expr); err != nil {
+			t.Fatalf("ScheduleExpression returned error: %v", err)
+		}
+		var operations []string
+		for {
+			task, err := s.GetTask()
+			if err != nil {
+				break
+			}
+			operations = append(operations, task.Operation)
+			if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: compute(task), Lease: task.Lease}); err != nil {
+				t.Fatalf("ProcessResult returned error: %v", err)
+			}
+		}
+
+		stored, err := storage.GetExpression(id)
+		if err != nil {
+			t.Fatalf("GetExpression returned error: %v", err)
+		}
+		if stored.Status != entities.ExpressionStatusCompleted || stored.Result != tc.result {
+			t.Errorf("Expected completed expression with result %f for x = %f, got %+v", tc.result, tc.x, stored)
+		}
+		if tc.operations != nil && !slices.Equal(operations, tc.operations) {
+			t.Errorf("Expected the tasks %v to be sent for x = %f, got %v", tc.operations, tc.x, operations)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/functions"
+	"math"
+	"strconv"
+)
+
+// degreesToRadians rewrites the arguments of trigonometric functions
+// from degrees to radians, so agents always compute in radians.
+// Real literal arguments are converted in place, other arguments,
+// imaginary literals included, are multiplied by pi/180.
+func degreesToRadians(root *parser.Node) *parser.Node {
+	if root == nil {
+		return nil
+	}
+
+	root.Left = degreesToRadians(root.Left)
+	root.Right = degreesToRadians(root.Right)
+
+	if root.Token.Type != parser.Function || !functions.IsTrigonometric(root.Token.Value) {
+		return root
+	}
+
+	if root.Left.Token.Type == parser.Number && !root.Left.IsImaginary() {
+		root.Left = numberNode(root.Left.Value * math.Pi / 180)
+		return root
+	}
+
+	root.Left = &parser.Node{
+		Token: parser.Token{Type: parser.Multiply, Value: "*"},
+		Left:  root.Left,
+		Right: numberNode(math.Pi / 180),
+	}
+	return root
+}
+
+func numberNode(value float64) *parser.Node {
+	return &parser.Node{
+		Token:  parser.Token{Type: parser.Number, Value: strconv.FormatFloat(value, 'g', -1, 64)},
+		Value:  value,
+		Parsed: true,
+	}
+}
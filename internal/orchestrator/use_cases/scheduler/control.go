@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/shared/entities"
+	"calculator/pkg/logger"
+	"fmt"
+)
+
+// CancelExpression stops computing the expression: its unfinished tasks are
+// removed from the task pool and the results agents still return for them
+// are ignored. A completed, failed or cancelled expression is rejected with
+// ErrInvalidStatus.
+func (s *Scheduler) CancelExpression(id string) (*entities.Expression, error) {
+	err := s.changeStatus(id, "cancel", entities.ExpressionStatusCancelled,
+		entities.ExpressionStatusPending, entities.ExpressionStatusProcessing, entities.ExpressionStatusPaused)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.taskPoll.CancelExpression(id); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	logger.Infof("Expression %s cancelled", id)
+	return s.GetExpression(id)
+}
+
+// PauseExpression keeps the tasks of the pending or processing expression
+// from being sent to agents until it is resumed. The tasks agents already
+// compute are completed.
+func (s *Scheduler) PauseExpression(id string) (*entities.Expression, error) {
+	err := s.changeStatus(id, "pause", entities.ExpressionStatusPaused,
+		entities.ExpressionStatusPending, entities.ExpressionStatusProcessing)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.taskPoll.PauseExpression(id); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	logger.Infof("Expression %s paused", id)
+	return s.GetExpression(id)
+}
+
+// ResumeExpression sends the tasks of the paused expression to agents again.
+// It is processing again if a task of it was sent before, pending otherwise.
+func (s *Scheduler) ResumeExpression(id string) (*entities.Expression, error) {
+	_, started, err := s.taskPoll.GetProgress(id)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	status := entities.ExpressionStatusPending
+	if !started.IsZero() {
+		status = entities.ExpressionStatusProcessing
+	}
+
+	if err = s.changeStatus(id, "resume", status, entities.ExpressionStatusPaused); err != nil {
+		return nil, err
+	}
+	if err = s.taskPoll.ResumeExpression(id); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	logger.Infof("Expression %s resumed", id)
+	return s.GetExpression(id)
+}
+
+// changeStatus sets the status of the expression if it has one of the
+// statuses from, otherwise the action is rejected with ErrInvalidStatus.
+func (s *Scheduler) changeStatus(id, action string, status entities.ExpressionStatus, from ...entities.ExpressionStatus) error {
+	changed, err := s.storage.ChangeStatus(id, status, from...)
+	if err != nil {
+		return err
+	}
+	if changed {
+		return nil
+	}
+
+	expr, err := s.storage.GetExpression(id)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: cannot %s a %s expression", use_cases_errors.ErrInvalidStatus, action, expr.Status)
+}
+
+// isCancelled reports whether the task was cancelled with its expression,
+// the results and the errors agents return for it are ignored.
+func (s *Scheduler) isCancelled(taskID string) bool {
+	cancelled, err := s.taskPoll.IsCancelled(taskID)
+	if err != nil {
+		logger.Error(err)
+		return false
+	}
+	if cancelled {
+		logger.Infof("Task %s was cancelled, what the agent returned for it is ignored", taskID)
+	}
+	return cancelled
+}
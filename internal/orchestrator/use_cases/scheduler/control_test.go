@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPauseResumeExpression(t *testing.T) {
+	s := NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{LeaseGraceMS: 1000})
+	if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "x * 2 + y * 3", Variables: map[string]float64{"x": 1, "y": 2}}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	leased, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+
+	expr, err := s.PauseExpression("1")
+	if err != nil {
+		t.Fatalf("PauseExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusPaused {
+		t.Errorf("Expected status %s, got %s", entities.ExpressionStatusPaused, expr.Status)
+	}
+	if task, err := s.GetTask(); err == nil {
+		t.Errorf("Expected no tasks of the paused expression, got %+v", task)
+	}
+	if _, err = s.PauseExpression("1"); !errors.Is(err, use_cases_errors.ErrInvalidStatus) {
+		t.Errorf("Expected ErrInvalidStatus for pausing a paused expression, got %v", err)
+	}
+
+	// the task leased before the pause is still computed
+	if err = s.ProcessResult(entities.TaskResult{ID: leased.ID, Result: leased.Arg1 * leased.Arg2, Lease: leased.Lease}); err != nil {
+		t.Fatalf("ProcessResult returned error: %v", err)
+	}
+	if expr, _ = s.GetExpression("1"); expr.Status != entities.ExpressionStatusPaused || expr.Progress.Completed != 1 {
+		t.Errorf("Expected paused expression with 1 completed task, got %+v", expr)
+	}
+
+	if expr, err = s.ResumeExpression("1"); err != nil {
+		t.Fatalf("ResumeExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusProcessing {
+		t.Errorf("Expected status %s, got %s", entities.ExpressionStatusProcessing, expr.Status)
+	}
+	if _, err = s.GetTask(); err != nil {
+		t.Errorf("Expected the tasks of the resumed expression, got %v", err)
+	}
+	if _, err = s.ResumeExpression("1"); !errors.Is(err, use_cases_errors.ErrInvalidStatus) {
+		t.Errorf("Expected ErrInvalidStatus for resuming a processing expression, got %v", err)
+	}
+}
+
+func TestResumeExpressionPending(t *testing.T) {
+	s := NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{})
+	if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "x + 2", Variables: map[string]float64{"x": 1}}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	if _, err := s.PauseExpression("1"); err != nil {
+		t.Fatalf("PauseExpression returned error: %v", err)
+	}
+	// no task was sent before the pause
+	expr, err := s.ResumeExpression("1")
+	if err != nil {
+		t.Fatalf("ResumeExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusPending {
+		t.Errorf("Expected status %s, got %s", entities.ExpressionStatusPending, expr.Status)
+	}
+}
+
+func TestCancelExpression(t *testing.T) {
+	s := NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{LeaseGraceMS: 1000})
+	if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "x * 2 + y * 3", Variables: map[string]float64{"x": 1, "y": 2}}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	leased, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+
+	expr, err := s.CancelExpression("1")
+	if err != nil {
+		t.Fatalf("CancelExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusCancelled {
+		t.Errorf("Expected status %s, got %s", entities.ExpressionStatusCancelled, expr.Status)
+	}
+	if task, err := s.GetTask(); err == nil {
+		t.Errorf("Expected no tasks of the cancelled expression, got %+v", task)
+	}
+
+	// the result of the task leased before the cancellation is accepted and
+	// ignored, also when it arrives after the lease ended
+	s.expireLeases(time.Now().Add(time.Hour))
+	if err = s.ProcessResult(entities.TaskResult{ID: leased.ID, Result: 2, Lease: leased.Lease}); err != nil {
+		t.Errorf("Expected the result of a cancelled task to be ignored, got %v", err)
+	}
+	if err = s.ProcessError(entities.TaskError{ID: leased.ID, Lease: leased.Lease, Code: entities.ErrorCodeComputation}); err != nil {
+		t.Errorf("Expected the error of a cancelled task to be ignored, got %v", err)
+	}
+	if expr, _ = s.GetExpression("1"); expr.Status != entities.ExpressionStatusCancelled || expr.Error != nil {
+		t.Errorf("Expected the expression to stay cancelled, got %+v", expr)
+	}
+
+	for action, control := range map[string]func(string) (*entities.Expression, error){
+		"cancel": s.CancelExpression,
+		"pause":  s.PauseExpression,
+		"resume": s.ResumeExpression,
+	} {
+		if _, err = control("1"); !errors.Is(err, use_cases_errors.ErrInvalidStatus) {
+			t.Errorf("Expected ErrInvalidStatus to %s a cancelled expression, got %v", action, err)
+		}
+		if _, err = control("2"); !errors.Is(err, use_cases_errors.ErrExpressionNotFound) {
+			t.Errorf("Expected ErrExpressionNotFound to %s an unknown expression, got %v", action, err)
+		}
+	}
+}
+
+// racingPool runs arrive once before the expression is cancelled in the
+// pool, as a result or an error that comes between the status change and the
+// removal of the tasks does.
+type racingPool struct {
+	TaskService
+	arrive func()
+}
+
+func (p *racingPool) CancelExpression(exprID string) error {
+	if arrive := p.arrive; arrive != nil {
+		p.arrive = nil
+		arrive()
+	}
+	return p.TaskService.CancelExpression(exprID)
+}
+
+func TestCancelExpressionRace(t *testing.T) {
+	for name, arrive := range map[string]func(*Scheduler, *entities.AgentTask) error{
+		"result": func(s *Scheduler, leased *entities.AgentTask) error {
+			return s.ProcessResult(entities.TaskResult{ID: leased.ID, Result: leased.Arg1 + leased.Arg2, Lease: leased.Lease})
+		},
+		"error": func(s *Scheduler, leased *entities.AgentTask) error {
+			return s.ProcessError(entities.TaskError{ID: leased.ID, Lease: leased.Lease, Code: entities.ErrorCodeComputation})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pool := &racingPool{TaskService: memory_task_storage.NewTaskPool()}
+			s := NewScheduler(memory_expression_storage.NewStorage(), pool, &configs.Config{LeaseGraceMS: 1000})
+			if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "x + 2", Variables: map[string]float64{"x": 1}}); err != nil {
+				t.Fatalf("ScheduleExpression returned error: %v", err)
+			}
+			leased, err := s.GetTask()
+			if err != nil {
+				t.Fatalf("GetTask returned error: %v", err)
+			}
+			pool.arrive = func() {
+				if err := arrive(s, leased); err != nil {
+					t.Errorf("Expected the %s to be accepted, got %v", name, err)
+				}
+			}
+
+			expr, err := s.CancelExpression("1")
+			if err != nil {
+				t.Fatalf("CancelExpression returned error: %v", err)
+			}
+			if expr.Status != entities.ExpressionStatusCancelled || expr.Result != 0 || expr.Error != nil {
+				t.Errorf("Expected the expression to stay cancelled, got %+v", expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/entities"
+	"fmt"
+	"strings"
+)
+
+// decimalCommaLanguages lists the languages that write a comma between the
+// integer and the fractional part of a number.
+var decimalCommaLanguages = map[string]bool{
+	"bg": true, "cs": true, "da": true, "de": true, "el": true, "es": true,
+	"fi": true, "fr": true, "hr": true, "hu": true, "id": true, "it": true,
+	"lt": true, "lv": true, "nb": true, "nl": true, "nn": true, "no": true,
+	"pl": true, "pt": true, "ro": true, "ru": true, "sk": true, "sl": true,
+	"sr": true, "sv": true, "tr": true, "uk": true, "vi": true,
+}
+
+// parse parses the expression written in the notation, the dialect and the
+// locale of the request within the configured limits. The infix notation and
+// the strict dialect are the default ones.
+func (s *Scheduler) parse(expr *entities.Expression) (*parser.Node, error) {
+	dialect := parser.Dialect{DecimalComma: decimalComma(expr.Locale), Limits: s.limits()}
+	switch expr.Dialect {
+	case "":
+		expr.Dialect = entities.DialectStrict
+	case entities.DialectStrict:
+	case entities.DialectMath:
+		dialect.Math = true
+		// a variable of the expression is multiplied, not read as a unit
+		dialect.IsUnit = func(name string) bool {
+			_, bound := expr.Variables[name]
+			return !bound && s.catalog.IsUnit(name)
+		}
+	default:
+		return nil, use_cases_errors.ErrInvalidDialect
+	}
+
+	var root *parser.Node
+	var err error
+	switch expr.Notation {
+	case "", entities.NotationInfix:
+		expr.Notation = entities.NotationInfix
+		root, err = parser.ParseDialect(expr.Expression, dialect)
+	case entities.NotationRPN:
+		root, err = parser.ParseRPN(expr.Expression, dialect)
+	case entities.NotationPrefix:
+		root, err = parser.ParsePrefix(expr.Expression, dialect)
+	default:
+		return nil, use_cases_errors.ErrInvalidNotation
+	}
+	if err != nil {
+		return nil, limitError(err)
+	}
+	return root, nil
+}
+
+// RenderExpression returns the stored expression written in the notation.
+// The expression is written as it was submitted, before the calls of
+// user-defined functions are inlined and the variables are bound.
+func (s *Scheduler) RenderExpression(id string, notation entities.Notation) (*entities.Expression, error) {
+	expr, err := s.storage.GetExpression(id)
+	if err != nil {
+		return nil, err
+	}
+	root, err := s.parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch notation {
+	case entities.NotationInfix:
+		expr.Expression = root.String()
+	case entities.NotationRPN:
+		expr.Expression, err = root.FormatRPN()
+	case entities.NotationPrefix:
+		expr.Expression, err = root.FormatPrefix()
+	default:
+		return nil, use_cases_errors.ErrInvalidNotation
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", use_cases_errors.ErrInvalidNotation, err)
+	}
+	expr.Notation = notation
+	return expr, nil
+}
+
+// decimalComma reports whether numbers are written with a decimal comma
+// in the locale, like de-DE or ru_RU.
+func decimalComma(locale string) bool {
+	language, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	return decimalCommaLanguages[strings.ToLower(language)]
+}
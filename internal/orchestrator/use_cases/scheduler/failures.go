@@ -0,0 +1,46 @@
+package scheduler
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/shared/entities"
+	"calculator/pkg/logger"
+)
+
+// ProcessError fails the expression of the task the agent could not compute,
+// like a division by zero. The remaining tasks of the expression are
+// cancelled, so no agent computes them, and the error is recorded on the
+// expression. The error of a lease that ended is rejected with ErrLeaseExpired.
+// The error of a task of a cancelled or failed expression is accepted and
+// ignored, even after its lease ended.
+func (s *Scheduler) ProcessError(taskError entities.TaskError) error {
+	if s.isCancelled(taskError.ID) {
+		return nil
+	}
+	if err := s.checkLease(taskError.ID, taskError.Lease); err != nil {
+		return err
+	}
+
+	exprID, err := s.taskPoll.GetExpressionIDByTaskID(taskError.ID)
+	if err != nil {
+		logger.Error(err)
+		return use_cases_errors.ErrNoTasksAvailable
+	}
+
+	if err = s.taskPoll.CancelExpression(exprID); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	code := taskError.Code
+	if code == "" {
+		code = entities.ErrorCodeComputation
+	}
+	exprError := entities.ExpressionError{Code: code, Message: taskError.Message, TaskID: taskError.ID}
+	if err = s.storage.FailExpression(exprID, exprError); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	logger.Infof("Expression %s failed: task %s: %s", exprID, taskError.ID, taskError.Message)
+	return nil
+}
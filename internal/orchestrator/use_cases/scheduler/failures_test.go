@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"errors"
+	"testing"
+)
+
+func TestProcessError(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{LeaseGraceMS: 1000})
+
+	if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "1 / x + 2 * 3", Variables: map[string]float64{"x": 0}}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	first, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+	second, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+	failed, other := first, second
+	if first.Operation != "/" {
+		failed, other = second, first
+	}
+
+	// the error of an expired lease is rejected like its result
+	err = s.ProcessError(entities.TaskError{ID: failed.ID, Lease: failed.Lease + 1, Code: entities.ErrorCodeDivisionByZero})
+	if !errors.Is(err, use_cases_errors.ErrLeaseExpired) {
+		t.Errorf("Expected ErrLeaseExpired for the error of another lease, got %v", err)
+	}
+
+	err = s.ProcessError(entities.TaskError{ID: failed.ID, Lease: failed.Lease, Code: entities.ErrorCodeDivisionByZero, Message: "division by zero"})
+	if err != nil {
+		t.Fatalf("ProcessError returned error: %v", err)
+	}
+
+	expr, err := storage.GetExpression("1")
+	if err != nil {
+		t.Fatalf("GetExpression returned error: %v", err)
+	}
+	expected := entities.ExpressionError{Code: entities.ErrorCodeDivisionByZero, Message: "division by zero", TaskID: failed.ID}
+	if expr.Status != entities.ExpressionStatusFailed || expr.Error == nil || *expr.Error != expected {
+		t.Errorf("Expected failed expression with error %+v, got %+v", expected, expr)
+	}
+
+	// the remaining tasks are cancelled
+	if task, err := s.GetTask(); err == nil {
+		t.Errorf("Expected no tasks of the failed expression, got %+v", task)
+	}
+	if err = s.ProcessResult(entities.TaskResult{ID: other.ID, Result: 6, Lease: other.Lease}); err != nil {
+		t.Errorf("Expected the result of a cancelled task to be ignored, got %v", err)
+	}
+	if expr, _ = storage.GetExpression("1"); expr.Status != entities.ExpressionStatusFailed {
+		t.Errorf("Expected the expression to stay failed, got %s", expr.Status)
+	}
+}
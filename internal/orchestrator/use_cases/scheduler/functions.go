@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/entities"
+	"calculator/pkg/logger"
+)
+
+// DefineFunction parses the definition of a function like f(x, y) = x^2 + y
+// and stores it, replacing the function with the same name. The function is
+// rejected if its calls could not be expanded.
+func (s *Scheduler) DefineFunction(definition string) (*entities.Function, error) {
+	fn, err := parser.ParseDefinition(definition, s.limits())
+	if err != nil {
+		return nil, limitError(err)
+	}
+
+	functions, err := s.storedFunctions()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkFunctions([]*parser.Definition{fn}, functions); err != nil {
+		return nil, err
+	}
+
+	stored := toEntity(fn)
+	if err = s.storage.SaveFunction(stored); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	return stored, nil
+}
+
+// expandCalls inlines the calls of user-defined functions into the tree.
+// It returns the functions defined in the script, they are stored once
+// the expression is scheduled.
+func (s *Scheduler) expandCalls(root *parser.Node) (*parser.Node, []*parser.Definition, error) {
+	functions, err := s.storedFunctions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defs := parser.ScriptDefinitions(root)
+	if root, err = parser.Expand(root, functions, s.cfg.MaxExpansionNodes); err != nil {
+		return nil, nil, err
+	}
+	if err = s.checkFunctions(defs, functions); err != nil {
+		return nil, nil, err
+	}
+	return root, defs, nil
+}
+
+// checkFunctions checks the functions as they will be once they are stored:
+// they may call each other and the stored functions they do not replace.
+func (s *Scheduler) checkFunctions(defs []*parser.Definition, functions map[string]*parser.Definition) error {
+	for _, fn := range defs {
+		functions[fn.Name] = fn
+	}
+	for _, fn := range defs {
+		if err := parser.CheckDefinition(fn, functions, s.cfg.MaxExpansionNodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveFunctions stores the functions defined in a script.
+func (s *Scheduler) saveFunctions(defs []*parser.Definition) error {
+	for _, fn := range defs {
+		if err := s.storage.SaveFunction(toEntity(fn)); err != nil {
+			logger.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// storedFunctions parses the stored functions by their names.
+func (s *Scheduler) storedFunctions() (map[string]*parser.Definition, error) {
+	stored, err := s.storage.GetFunctions()
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	functions := make(map[string]*parser.Definition, len(stored))
+	for _, fn := range stored {
+		// the stored definitions were within the limits when they were defined
+		def, err := parser.ParseDefinition(fn.Definition, parser.Limits{})
+		if err != nil {
+			logger.Errorf("Stored function %s is invalid: %v", fn.Name, err)
+			return nil, err
+		}
+		functions[fn.Name] = def
+	}
+	return functions, nil
+}
+
+// toEntity returns the function to store, its definition is written in the normalized form.
+func toEntity(fn *parser.Definition) *entities.Function {
+	return &entities.Function{Name: fn.Name, Params: fn.Params, Definition: fn.String()}
+}
@@ -2,21 +2,81 @@ package scheduler
 
 import (
 	"calculator/internal/shared/entities"
+	"time"
 )
 
 type ExpressionService interface {
-	CreateExpression(id string, expr string) error
+	CreateExpression(expr *entities.Expression) error
+	// GetExpression returns a copy of the stored expression, which is not
+	// changed by the updates of the stored one.
 	GetExpression(id string) (*entities.Expression, error)
 	GetExpressions() ([]entities.Expression, error)
-	UpdateExpression(id string, status entities.ExpressionStatus, result float64) error
+	// UpdateExpression sets the status and the result of the expression,
+	// unless it is already completed, failed or cancelled: a result arriving
+	// while the expression is cancelled does not complete it.
+	UpdateExpression(id string, status entities.ExpressionStatus, result entities.TaskResult) error
+	// ChangeStatus sets the status of the expression if it has one of the
+	// statuses from. It reports whether the status was changed.
+	ChangeStatus(id string, status entities.ExpressionStatus, from ...entities.ExpressionStatus) (bool, error)
+	// FailExpression marks the expression failed with the error of its task,
+	// unless it is already completed, failed or cancelled.
+	FailExpression(id string, exprError entities.ExpressionError) error
+	// UpdateBindings records the result as the value of the names assigned in the expression.
+	UpdateBindings(id string, names []string, result entities.TaskResult) error
+	// SaveFunction stores the user-defined function, replacing the function with the same name.
+	SaveFunction(fn *entities.Function) error
+	// GetFunctions returns the stored user-defined functions ordered by name.
+	GetFunctions() ([]entities.Function, error)
 }
 
 type TaskService interface {
 	AddTasks(tasks []entities.Task) error
-	GetTaskToCompute() (entities.Task, error)
-	SetTaskResultAfterCompute(id string, result float64) error
+	// GetTaskToCompute leases the next task to compute until the deadline
+	// returned for it. The Lease of the task tells this lease from the earlier ones.
+	// The task is taken from the expression of the highest priority, an
+	// expression gains a level for every aging it waits since it was added or
+	// its last task was leased, so the ones of a low priority are not starved.
+	// The expressions of equal priority take turns, the one whose last task
+	// was leased first goes first.
+	GetTaskToCompute(aging time.Duration, deadline func(task entities.Task) time.Time) (entities.Task, error)
+	// HoldsLease reports whether the task is leased with the given lease.
+	HoldsLease(id string, lease int64) (bool, error)
+	// ExpireLeases returns the tasks whose leases ended before now to the
+	// tasks to compute. It returns the number of expired leases.
+	ExpireLeases(now time.Time) (int, error)
+	SetTaskResultAfterCompute(result entities.TaskResult) error
 	DeleteTask(id string) error
+	// DeleteExpression deletes a completed expression, its progress is forgotten.
 	DeleteExpression(id string) error
-	IsLastTask(id string) (bool, error)
+	// GetProgress counts the tasks of the expression and returns the time its
+	// first task was leased, the zero time if none was.
+	GetProgress(exprID string) (entities.Progress, time.Time, error)
+	// CancelExpression deletes the remaining tasks of the expression, so they
+	// are not sent to agents, and forgets its progress. The tasks that were
+	// leased are remembered as cancelled, as the expression is, so what an
+	// agent returns for them is ignored rather than rejected, even after the
+	// lease ended.
+	CancelExpression(exprID string) error
+	// IsCancelled reports whether the task was leased and then cancelled with
+	// its expression.
+	IsCancelled(taskID string) (bool, error)
+	// PauseExpression keeps the tasks of the expression from being sent to
+	// agents until ResumeExpression, the leased ones are still computed.
+	PauseExpression(exprID string) error
+	ResumeExpression(exprID string) error
+	// ExpressionResult reports whether the expression is computed: its root
+	// task is computed and no other task of it, like the value of a name the
+	// result does not use, is left. It returns the result of the root task.
+	ExpressionResult(exprID string) (entities.TaskResult, bool, error)
 	GetExpressionIDByTaskID(taskID string) (string, error)
+	// GetBindings returns the names the script assigns the result of the task to.
+	GetBindings(taskID string) ([]string, error)
+	// GetReadyConditionals returns the conditional tasks of the expression
+	// whose condition is computed and which are not inside an unchosen branch.
+	GetReadyConditionals(exprID string) ([]entities.Task, error)
+	// ChooseBranch resolves the conditional task: the tasks of the chosen branch
+	// are released to agents and the tasks of the other branch are deleted.
+	// If the chosen branch is a task, it takes the place of the conditional task.
+	// The chosen argument is returned.
+	ChooseBranch(id string, condition bool) (entities.Arg, error)
 }
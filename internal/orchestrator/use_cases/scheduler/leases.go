@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/shared/entities"
+	"calculator/pkg/logger"
+	"context"
+	"time"
+)
+
+// leaseDeadline returns the time the agent has to return the result of the
+// task: the simulated time of its operation and the grace period.
+func (s *Scheduler) leaseDeadline(task entities.Task) time.Time {
+	grace := time.Duration(s.cfg.LeaseGraceMS) * time.Millisecond
+	return time.Now().Add(s.getOperationTime(task.Operation) + grace)
+}
+
+// checkLease rejects the result of a lease that is no longer held, so a late
+// agent cannot overwrite the result of the agent the task was sent to again.
+func (s *Scheduler) checkLease(id string, lease int64) error {
+	held, err := s.taskPoll.HoldsLease(id, lease)
+	if err != nil {
+		logger.Error(err)
+		return use_cases_errors.ErrNoTasksAvailable
+	}
+	if !held {
+		logger.Errorf("Result of task %s with lease %d is rejected, the lease expired", id, lease)
+		return use_cases_errors.ErrLeaseExpired
+	}
+	return nil
+}
+
+// ReapLeases returns the tasks whose agents did not return the results in
+// time to the tasks to compute, so another agent computes them. It checks
+// the leases every LeaseCheckMS until the context is done.
+func (s *Scheduler) ReapLeases(ctx context.Context) {
+	interval := time.Duration(s.cfg.LeaseCheckMS) * time.Millisecond
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.expireLeases(now)
+		}
+	}
+}
+
+func (s *Scheduler) expireLeases(now time.Time) {
+	expired, err := s.taskPoll.ExpireLeases(now)
+	if err != nil {
+		logger.Errorf("Failed to expire task leases: %v", err)
+		return
+	}
+	if expired > 0 {
+		logger.Infof("%d task leases expired, the tasks are sent again", expired)
+	}
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleExpressionLeases(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{TimeAdditionMS: 100, LeaseGraceMS: 1000})
+
+	if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "x + 2", Variables: map[string]float64{"x": 1}}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	first, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+	if _, err = s.GetTask(); err == nil {
+		t.Errorf("Expected the leased task not to be sent again")
+	}
+
+	// the lease lasts the time of the operation and the grace period
+	s.expireLeases(time.Now().Add(time.Second))
+	if _, err = s.GetTask(); err == nil {
+		t.Errorf("Expected the lease not to expire before the grace period ends")
+	}
+	s.expireLeases(time.Now().Add(2 * time.Second))
+	second, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error after the lease expired: %v", err)
+	}
+	if second.ID != first.ID || second.Lease <= first.Lease {
+		t.Errorf("Expected task %s sent again with a newer lease, got %+v after %+v", first.ID, second, first)
+	}
+
+	// the late agent is fenced off
+	err = s.ProcessResult(entities.TaskResult{ID: first.ID, Result: 3, Lease: first.Lease})
+	if !errors.Is(err, use_cases_errors.ErrLeaseExpired) {
+		t.Errorf("Expected ErrLeaseExpired for the result of the expired lease, got %v", err)
+	}
+	if err = s.ProcessResult(entities.TaskResult{ID: second.ID, Result: 3, Lease: second.Lease}); err != nil {
+		t.Fatalf("ProcessResult returned error: %v", err)
+	}
+	expr, err := storage.GetExpression("1")
+	if err != nil {
+		t.Fatalf("GetExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusCompleted || expr.Result != 3 {
+		t.Errorf("Expected completed expression with result 3, got %+v", expr)
+	}
+}
+
+func TestReapLeases(t *testing.T) {
+	s := NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{LeaseCheckMS: 1})
+	if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "x * 2", Variables: map[string]float64{"x": 1}}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	if _, err := s.GetTask(); err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.ReapLeases(ctx)
+		close(done)
+	}()
+
+	// without a grace period the lease ends at once and the reaper sends the task again
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		task, err := s.GetTask()
+		if err == nil {
+			if task.Lease != 2 {
+				t.Errorf("Expected the second lease, got %d", task.Lease)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the reaper to return the task")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/orchestrator/use_cases/parser"
+	"errors"
+	"fmt"
+)
+
+// limits returns the configured limits of the size of expressions.
+func (s *Scheduler) limits() parser.Limits {
+	return parser.Limits{
+		MaxLength: s.cfg.MaxExpressionLength,
+		MaxTokens: s.cfg.MaxTokens,
+		MaxDepth:  s.cfg.MaxNestingDepth,
+	}
+}
+
+// limitError wraps the error of an expression exceeding the limits of the
+// parser: a too long expression is too large, one with too many tokens or
+// nested too deep is too complex.
+func limitError(err error) error {
+	var limit *parser.LimitError
+	if !errors.As(err, &limit) {
+		return err
+	}
+	if limit.Limit == parser.LimitLength {
+		return fmt.Errorf("%w: %w", use_cases_errors.ErrExpressionTooLarge, err)
+	}
+	return fmt.Errorf("%w: %w", use_cases_errors.ErrExpressionTooComplex, err)
+}
+
+// checkTasks checks that the expression does not create more tasks than allowed.
+func (s *Scheduler) checkTasks(tasks int) error {
+	if s.cfg.MaxTasks > 0 && tasks > s.cfg.MaxTasks {
+		return fmt.Errorf("%w: expression creates %d tasks, at most %d are allowed",
+			use_cases_errors.ErrExpressionTooComplex, tasks, s.cfg.MaxTasks)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/complexnum"
+	"calculator/internal/shared/entities"
+	"calculator/internal/shared/exact"
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"time"
+)
+
+// optimizer simplifies an expression tree before it is split into tasks.
+// Sub-trees of numbers whose simulated time is below threshold are computed
+// at once, in the precision of the expression, instead of being sent to agents.
+type optimizer struct {
+	mode      entities.Mode
+	scale     int
+	threshold time.Duration
+	cost      func(operation string) time.Duration
+	// constants are the assigned names whose values are numbers.
+	constants map[string]*parser.Node
+}
+
+// optimize simplifies the tree of the expression with the rules of the scheduler configuration.
+func (s *Scheduler) optimize(root *parser.Node, expr *entities.Expression) *parser.Node {
+	o := &optimizer{
+		mode:      expr.Mode,
+		scale:     expr.Scale,
+		threshold: time.Duration(s.cfg.FoldThresholdMS) * time.Millisecond,
+		cost:      s.getOperationTime,
+		constants: make(map[string]*parser.Node),
+	}
+	root, _ = o.simplify(root)
+	return root
+}
+
+// simplify returns the simplified sub-tree and the simulated time of the
+// operations of the original sub-tree. The identity rules x+0, 0+x, x-0, x*1,
+// 1*x, x/1 and x^1 give x, and the absorption rules x*0, x^0, x and 0, x or 1
+// give a number without computing x. x*0 and x^0 only drop an x known to be a
+// finite number, so the errors of x are still reported. A conditional with a known condition is
+// replaced by its chosen branch. A name assigned a number is replaced by the number.
+func (o *optimizer) simplify(node *parser.Node) (*parser.Node, time.Duration) {
+	switch node.Token.Type {
+	case parser.Number:
+		return node, 0
+	case parser.Variable:
+		if constant, ok := o.constants[node.Token.Value]; ok {
+			number := *constant
+			return &number, 0
+		}
+		return node, 0
+	case parser.Let:
+		// the assignment is kept, its value is reported with the expression
+		value, valueCost := o.simplify(node.Left)
+		if value.Token.Type == parser.Number {
+			o.constants[node.Token.Value] = value
+		} else {
+			delete(o.constants, node.Token.Value)
+		}
+		body, bodyCost := o.simplify(node.Right)
+		node.Left, node.Right = value, body
+		return node, valueCost + bodyCost
+	case parser.If:
+		condition, cost := o.simplify(node.Left)
+		then, thenCost := o.simplify(node.Right.Left)
+		otherwise, elseCost := o.simplify(node.Right.Right)
+		if c, ok := o.literal(condition); ok {
+			if c.Sign() != 0 {
+				return then, cost + thenCost
+			}
+			return otherwise, cost + elseCost
+		}
+		node.Left, node.Right.Left, node.Right.Right = condition, then, otherwise
+		return node, cost + max(thenCost, elseCost)
+	}
+
+	var leftCost, rightCost time.Duration
+	node.Left, leftCost = o.simplify(node.Left)
+	if node.Right != nil {
+		node.Right, rightCost = o.simplify(node.Right)
+	}
+
+	switch simplified := o.rewrite(node); simplified {
+	case nil:
+	case node.Left:
+		return simplified, leftCost
+	case node.Right:
+		return simplified, rightCost
+	default:
+		return simplified, 0
+	}
+
+	cost := o.cost(node.Token.Value) + leftCost + rightCost
+	if cost < o.threshold {
+		if folded, ok := o.fold(node); ok {
+			return folded, cost
+		}
+	}
+	return node, cost
+}
+
+// rewrite applies the identity and absorption rules to the node.
+// It returns nil when no rule matches.
+func (o *optimizer) rewrite(node *parser.Node) *parser.Node {
+	left, leftOK := o.literal(node.Left)
+	right, rightOK := o.literal(node.Right)
+	is := func(x *big.Rat, ok bool, value int64) bool {
+		return ok && x.Cmp(big.NewRat(value, 1)) == 0
+	}
+
+	switch node.Token.Type {
+	case parser.Plus:
+		if is(right, rightOK, 0) {
+			return node.Left
+		}
+		if is(left, leftOK, 0) {
+			return node.Right
+		}
+	case parser.Minus:
+		if is(right, rightOK, 0) {
+			return node.Left
+		}
+	case parser.Multiply:
+		if is(right, rightOK, 1) {
+			return node.Left
+		}
+		if is(left, leftOK, 1) {
+			return node.Right
+		}
+		if is(left, leftOK, 0) && finite(node.Right) || is(right, rightOK, 0) && finite(node.Left) {
+			return numberNode(0)
+		}
+	case parser.Divide:
+		if is(right, rightOK, 1) {
+			return node.Left
+		}
+	case parser.Power:
+		if is(right, rightOK, 1) {
+			return node.Left
+		}
+		if is(right, rightOK, 0) && finite(node.Left) {
+			return numberNode(1)
+		}
+	case parser.And:
+		if is(left, leftOK, 0) || is(right, rightOK, 0) {
+			return numberNode(0)
+		}
+	case parser.Or:
+		if (leftOK && left.Sign() != 0) || (rightOK && right.Sign() != 0) {
+			return numberNode(1)
+		}
+	}
+	return nil
+}
+
+// finite reports whether the sub-tree is computed from numbers to a finite
+// number without errors. The absorption rules drop only such a sub-tree, so
+// (1/0)*0 still fails and an infinite x*0 is not taken for 0. A sub-tree
+// the orchestrator cannot compute, like one with names, is not finite.
+func finite(node *parser.Node) bool {
+	// a copy is computed, Evaluate keeps the values in the nodes
+	value, err := node.Clone().Evaluate()
+	return err == nil && !math.IsInf(value, 0) && !math.IsNaN(value)
+}
+
+// fold computes the node if its arguments are numbers. It reports false
+// when they are not or when the operation fails, so the agent reports the error.
+func (o *optimizer) fold(node *parser.Node) (*parser.Node, bool) {
+	if o.mode == entities.ModeComplex {
+		return foldComplex(node)
+	}
+
+	left, ok := o.literal(node.Left)
+	if !ok {
+		return nil, false
+	}
+	right, ok := o.literal(node.Right)
+	if !ok && !isUnaryNode(node) {
+		return nil, false
+	}
+
+	if o.mode == entities.ModeFloat {
+		value, err := (&parser.Node{Token: node.Token, Left: node.Left, Right: node.Right}).Evaluate()
+		if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, false
+		}
+		return numberNode(value), true
+	}
+
+	var x *big.Rat
+	var err error
+	switch {
+	case isUnaryNode(node) && o.mode == entities.ModeDecimal:
+		x, err = exact.ApplyUnaryDecimal(node.Token.Value, left, o.scale)
+	case isUnaryNode(node):
+		x, err = exact.ApplyUnary(node.Token.Value, left)
+	default:
+		x, err = exact.Apply(node.Token.Value, left, right)
+	}
+	if err != nil {
+		return nil, false
+	}
+	return o.exactNode(x), true
+}
+
+// foldComplex computes the node in complex mode if its arguments are numbers.
+func foldComplex(node *parser.Node) (*parser.Node, bool) {
+	left, err := complexLiteral(node.Left)
+	if err != nil {
+		return nil, false
+	}
+
+	var z complex128
+	if isUnaryNode(node) {
+		z, err = complexnum.ApplyUnary(node.Token.Value, left)
+	} else {
+		var right complex128
+		if right, err = complexLiteral(node.Right); err != nil {
+			return nil, false
+		}
+		z, err = complexnum.Apply(node.Token.Value, left, right)
+	}
+	if err != nil || cmplx.IsInf(z) || cmplx.IsNaN(z) {
+		return nil, false
+	}
+
+	folded := &parser.Node{
+		Token: parser.Token{Type: parser.Number, Value: complexnum.Format(z)},
+		Value: real(z),
+	}
+	folded.Parsed = !folded.IsImaginary()
+	return folded, true
+}
+
+// complexLiteral returns the complex value of a number node.
+func complexLiteral(node *parser.Node) (complex128, error) {
+	if node == nil || node.Token.Type != parser.Number {
+		return 0, fmt.Errorf("not a number")
+	}
+	return complexnum.Parse(node.Token.Value)
+}
+
+// literal returns the value of a number node. In exact modes the value is
+// read from the literal, so 0.1 stays exactly one tenth. In complex mode
+// only a real number has a value.
+func (o *optimizer) literal(node *parser.Node) (*big.Rat, bool) {
+	if node == nil || node.Token.Type != parser.Number {
+		return nil, false
+	}
+	if o.mode == entities.ModeFloat {
+		x := new(big.Rat).SetFloat64(node.Value)
+		return x, x != nil
+	}
+	x, err := exact.Parse(node.Token.Value)
+	return x, err == nil
+}
+
+// exactNode makes a number node of x written like the agents write exact results.
+func (o *optimizer) exactNode(x *big.Rat) *parser.Node {
+	s := x.RatString()
+	if o.mode == entities.ModeDecimal {
+		s = exact.FormatDecimal(x, o.scale)
+		x, _ = exact.Parse(s)
+	}
+	value, _ := x.Float64()
+	return &parser.Node{
+		Token:  parser.Token{Type: parser.Number, Value: s},
+		Value:  value,
+		Parsed: true,
+	}
+}
+
+// countTasks returns the number of tasks the tree is split into.
+func countTasks(node *parser.Node) int {
+	if node == nil {
+		return 0
+	}
+	switch node.Token.Type {
+	case parser.Number, parser.Variable:
+		return 0
+	case parser.Branches, parser.Let:
+		return countTasks(node.Left) + countTasks(node.Right)
+	}
+	return 1 + countTasks(node.Left) + countTasks(node.Right)
+}
@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	cfg := &configs.Config{
+		TimeAdditionMS:       100,
+		TimeSubtractionMS:    100,
+		TimeMultiplicationMS: 100,
+		TimeDivisionMS:       100,
+		TimeExponentiationMS: 100,
+		TimeFunctionMS:       100,
+		TimeComparisonMS:     100,
+		Optimize:             true,
+		FoldThresholdMS:      250,
+	}
+	s := NewScheduler(nil, nil, cfg)
+
+	testCases := []struct {
+		expression string
+		mode       entities.Mode
+		scale      int
+		variables  map[string]float64
+		tasks      int
+		number     string
+	}{
+		{expression: "2+3", tasks: 0, number: "5"},
+		{expression: "(2+3)*(4+5)", tasks: 1},
+		{expression: "x*1", variables: map[string]float64{"x": 7}, tasks: 0, number: "7"},
+		{expression: "(x+y)*1", variables: map[string]float64{"x": 1, "y": 2}, tasks: 0, number: "3"},
+		{expression: "0+sqrt(x)^1-0", variables: map[string]float64{"x": 4}, tasks: 0, number: "2"},
+		{expression: "sqrt(sqrt(sqrt(x)))*0", variables: map[string]float64{"x": 4}, tasks: 0, number: "0"},
+		{expression: "(1+2+3+4)^0", tasks: 0, number: "1"},
+		// the errors of the operand are not dropped with it
+		{expression: "(1/0)*0", tasks: 2},
+		{expression: "0*sqrt(-1)", tasks: 2},
+		{expression: "(1/0)^0", tasks: 2},
+		{expression: "(10^300*10^300)*0", tasks: 2},
+		{expression: "(1<2 or 1/0) and 3", tasks: 0, number: "1"},
+		{expression: "1/0", tasks: 1},
+		{expression: "if(1 > 2, 1/0, 2*3)", tasks: 0, number: "6"},
+		{expression: "if(1+1+1 > 2, 4, 5)", tasks: 2},
+		{expression: "1+1+1+1", tasks: 1},
+		{expression: "0.1+0.2", mode: entities.ModeDecimal, scale: 28, tasks: 0, number: "0.3"},
+		{expression: "(1/3)^2", mode: entities.ModeRational, tasks: 0, number: "1/9"},
+		{expression: "sqrt(2)", mode: entities.ModeDecimal, scale: 5, tasks: 0, number: "1.41421"},
+		{expression: "2^0.5", mode: entities.ModeRational, tasks: 1},
+		{expression: "(3+4i)*2i", mode: entities.ModeComplex, tasks: 0, number: "-8+6i"},
+		{expression: "abs(3+4i) + x*0", mode: entities.ModeComplex, variables: map[string]float64{"x": 1}, tasks: 0, number: "5"},
+		{expression: "1i < 2", mode: entities.ModeComplex, tasks: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			mode := tc.mode
+			if mode == "" {
+				mode = entities.ModeFloat
+			}
+			root, err := parser.Parse(tc.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tc.expression, err)
+			}
+			if err = parser.Bind(root, tc.variables); err != nil {
+				t.Fatalf("Bind(%q) returned error: %v", tc.expression, err)
+			}
+
+			root = s.optimize(root, &entities.Expression{Mode: mode, Scale: tc.scale})
+			if tasks := countTasks(root); tasks != tc.tasks {
+				t.Errorf("Expected %d tasks, got %d", tc.tasks, tasks)
+			}
+			if tc.number != "" && (root.Token.Type != parser.Number || root.Token.Value != tc.number) {
+				t.Errorf("Expected number %s, got %v", tc.number, root.Token)
+			}
+		})
+	}
+}
+
+func TestScheduleExpressionTasksSaved(t *testing.T) {
+	cfg := &configs.Config{TimeAdditionMS: 100, Optimize: true, FoldThresholdMS: 150}
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), cfg)
+
+	// Test with an expression that is folded completely
+	if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "2+2"}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	expr, err := storage.GetExpression("1")
+	if err != nil {
+		t.Fatalf("GetExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusCompleted || expr.Result != 4 || expr.TasksSaved != 1 {
+		t.Errorf("Expected completed expression with result 4 and 1 task saved, got %+v", expr)
+	}
+
+	// Test with an expression that keeps some of its tasks
+	if err := s.ScheduleExpression(&entities.Expression{ID: "2", Expression: "(1+1)+(1+1)+x*1", Variables: map[string]float64{"x": 3}}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	expr, err = storage.GetExpression("2")
+	if err != nil {
+		t.Fatalf("GetExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusPending || expr.TasksSaved != 3 {
+		t.Errorf("Expected pending expression with 3 tasks saved, got %+v", expr)
+	}
+
+	// Test with a number and the optimizer turned off
+	cfg.Optimize = false
+	if err := s.ScheduleExpression(&entities.Expression{ID: "3", Expression: "5"}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	expr, err = storage.GetExpression("3")
+	if err != nil {
+		t.Fatalf("GetExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusCompleted || expr.Result != 5 || expr.TasksSaved != 0 {
+		t.Errorf("Expected completed expression with result 5, got %+v", expr)
+	}
+}
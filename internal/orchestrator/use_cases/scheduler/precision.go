@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/complexnum"
+	"calculator/internal/shared/entities"
+	"calculator/internal/shared/exact"
+	"fmt"
+)
+
+// validatePrecision checks the precision mode and scale of the expression
+// and fills in their defaults.
+func validatePrecision(expr *entities.Expression) error {
+	switch expr.Mode {
+	case "":
+		expr.Mode = entities.ModeFloat
+	case entities.ModeFloat, entities.ModeDecimal, entities.ModeRational, entities.ModeComplex:
+	default:
+		return use_cases_errors.ErrInvalidPrecision
+	}
+
+	if expr.Scale < 0 || expr.Scale > exact.MaxScale {
+		return use_cases_errors.ErrInvalidPrecision
+	}
+	if expr.Mode != entities.ModeDecimal {
+		if expr.Scale != 0 {
+			return use_cases_errors.ErrInvalidPrecision
+		}
+		return nil
+	}
+	if expr.Scale == 0 {
+		expr.Scale = exact.DefaultScale
+	}
+	return nil
+}
+
+// checkModeSupport reports the imaginary numbers of the tree outside of
+// complex mode and the functions that have no exact implementation for
+// the exact modes.
+func checkModeSupport(root *parser.Node, mode entities.Mode) error {
+	var errs parser.SyntaxErrors
+	var walk func(node *parser.Node)
+	walk = func(node *parser.Node) {
+		if node == nil {
+			return
+		}
+		walk(node.Left)
+		walk(node.Right)
+		if node.IsImaginary() && mode != entities.ModeComplex {
+			errs = append(errs, &parser.SyntaxError{
+				Offset:  node.Token.Pos,
+				Length:  len(node.Token.Value),
+				Message: fmt.Sprintf("imaginary number %s is only supported in %s mode", node.Token.Value, entities.ModeComplex),
+			})
+		}
+		if mode == entities.ModeFloat || mode == entities.ModeComplex {
+			return
+		}
+		if node.Token.Type == parser.Function && !exact.IsExactFunction(node.Token.Value, mode == entities.ModeDecimal) {
+			errs = append(errs, &parser.SyntaxError{
+				Offset:  node.Token.Pos,
+				Length:  len(node.Token.Value),
+				Message: fmt.Sprintf("function %s is not supported in %s mode", node.Token.Value, mode),
+			})
+		}
+	}
+	walk(root)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// isTrue reports whether the computed condition is not zero.
+func isTrue(arg entities.Arg) bool {
+	if arg.ArgExact != "" {
+		if x, err := exact.Parse(arg.ArgExact); err == nil {
+			return x.Sign() != 0
+		}
+	}
+	return arg.ArgFloat != 0 || arg.ArgImag != 0
+}
+
+// argResult makes the result of the task from its computed argument,
+// written in the precision of the task.
+func argResult(task entities.Task, arg entities.Arg) entities.TaskResult {
+	result := entities.TaskResult{ID: task.ID, Result: arg.ArgFloat}
+	if task.Mode == entities.ModeFloat {
+		return result
+	}
+	if task.Mode == entities.ModeComplex {
+		z := complexArg(arg)
+		result.Result, result.Imag, result.Exact = real(z), imag(z), complexnum.Format(z)
+		return result
+	}
+
+	x, err := exact.Parse(arg.ArgExact)
+	if err != nil {
+		return result
+	}
+	if task.Mode == entities.ModeRational {
+		result.Exact = x.RatString()
+	} else {
+		result.Exact = exact.FormatDecimal(x, task.Scale)
+	}
+	return result
+}
+
+// complexArg returns the complex value of the argument. A literal is read
+// from its text, a computed value from its real and imaginary part.
+func complexArg(arg entities.Arg) complex128 {
+	if arg.ArgExact != "" {
+		if z, err := complexnum.Parse(arg.ArgExact); err == nil {
+			return z
+		}
+	}
+	return complex(arg.ArgFloat, arg.ArgImag)
+}
+
+// setPrecision copies the precision of the expression to its tasks.
+// In complex mode the numbers of the tasks are split into their real
+// and imaginary parts.
+func setPrecision(tasks []entities.Task, expr *entities.Expression) {
+	for i := range tasks {
+		tasks[i].Mode = expr.Mode
+		tasks[i].Scale = expr.Scale
+		if expr.Mode != entities.ModeComplex {
+			continue
+		}
+		for _, arg := range []*entities.Arg{&tasks[i].ArgLeft, &tasks[i].ArgRight, &tasks[i].ArgThen, &tasks[i].ArgElse} {
+			if arg.ArgType == entities.IsNumber {
+				z := complexArg(*arg)
+				arg.ArgFloat, arg.ArgImag, arg.ArgExact = real(z), imag(z), complexnum.Format(z)
+			}
+		}
+	}
+}
+
+// setBindings records the values of the names the script assigns numbers to.
+func setBindings(expr *entities.Expression, literals map[string]entities.Arg) {
+	if len(literals) == 0 {
+		return
+	}
+	task := entities.Task{Mode: expr.Mode, Scale: expr.Scale}
+	expr.Bindings = make(map[string]float64, len(literals))
+	for name, arg := range literals {
+		result := argResult(task, arg)
+		expr.Bindings[name] = result.Result
+		if expr.Mode != entities.ModeFloat {
+			if expr.ExactBindings == nil {
+				expr.ExactBindings = make(map[string]string, len(literals))
+			}
+			expr.ExactBindings[name] = result.Exact
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/shared/entities"
+	"time"
+)
+
+// MaxPriority is the highest priority of an expression and -MaxPriority the
+// lowest, the default priority is 0.
+const MaxPriority = 100
+
+func validatePriority(expr *entities.Expression) error {
+	if expr.Priority < -MaxPriority || expr.Priority > MaxPriority {
+		return use_cases_errors.ErrInvalidPriority
+	}
+	return nil
+}
+
+// setPriority gives the tasks the priority of their expression, the task
+// pool sends the tasks of the expressions of a higher priority first.
+func setPriority(tasks []entities.Task, expr *entities.Expression) {
+	for i := range tasks {
+		tasks[i].Priority = expr.Priority
+	}
+}
+
+// priorityAging is how long an expression waits for its next task to be sent
+// to gain one level of priority, 0 keeps the priorities strict.
+func (s *Scheduler) priorityAging() time.Duration {
+	return time.Duration(s.cfg.PriorityAgingMS) * time.Millisecond
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"errors"
+	"testing"
+)
+
+func TestScheduleExpressionPriority(t *testing.T) {
+	s := NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{LeaseGraceMS: 1000, PriorityAgingMS: 60000})
+
+	for _, priority := range []int{-MaxPriority - 1, MaxPriority + 1} {
+		err := s.ScheduleExpression(&entities.Expression{ID: "invalid", Expression: "1 + 2", Priority: priority})
+		if !errors.Is(err, use_cases_errors.ErrInvalidPriority) {
+			t.Errorf("Expected ErrInvalidPriority for priority %d, got %v", priority, err)
+		}
+	}
+
+	// the large expression is scheduled first, the urgent one still goes first
+	if err := s.ScheduleExpression(&entities.Expression{ID: "batch", Expression: "1 + 2 + 3 + 4 + 5 + 6 + 7 + 8"}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	if err := s.ScheduleExpression(&entities.Expression{ID: "urgent", Expression: "2 * 3", Priority: 10}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+	expr, err := s.GetExpression("urgent")
+	if err != nil || expr.Priority != 10 {
+		t.Fatalf("Expected the priority to be stored, got %+v, %v", expr, err)
+	}
+
+	task, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+	if task.ExprID != "urgent" {
+		t.Errorf("Expected the task of the urgent expression first, got the one of %s", task.ExprID)
+	}
+}
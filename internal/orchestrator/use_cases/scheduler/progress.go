@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"calculator/internal/shared/entities"
+	"calculator/pkg/logger"
+	"time"
+)
+
+// progress counts the tasks of the expression and estimates the time left to
+// compute them while it is processed. An expression without tasks, like a
+// single number, has none, and neither has a completed or failed one.
+func (s *Scheduler) progress(expr *entities.Expression) *entities.Progress {
+	progress, started, err := s.taskPoll.GetProgress(expr.ID)
+	if err != nil {
+		logger.Errorf("Failed to get progress of expression %s: %v", expr.ID, err)
+		return nil
+	}
+	if progress.Total == 0 {
+		return nil
+	}
+	if expr.Status == entities.ExpressionStatusProcessing {
+		progress.EstimatedMS = estimateRemaining(progress, started, time.Now())
+	}
+	return &progress
+}
+
+// estimateRemaining returns the time left to compute the tasks at the rate
+// they were computed since the first of them was leased, 0 when the rate is
+// not known yet or nothing is left.
+func estimateRemaining(progress entities.Progress, started, now time.Time) int64 {
+	remaining := progress.Total - progress.Completed
+	if progress.Completed == 0 || remaining == 0 || started.IsZero() {
+		return 0
+	}
+	elapsed := now.Sub(started)
+	return (elapsed * time.Duration(remaining) / time.Duration(progress.Completed)).Milliseconds()
+}
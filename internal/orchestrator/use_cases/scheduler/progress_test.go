@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"testing"
+	"time"
+)
+
+func TestScheduleExpressionProgress(t *testing.T) {
+	s := NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{LeaseGraceMS: 1000})
+	if err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "x * 2 + y * 3", Variables: map[string]float64{"x": 1, "y": 2}}); err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+
+	check := func(status entities.ExpressionStatus, expected entities.Progress) {
+		t.Helper()
+		expr, err := s.GetExpression("1")
+		if err != nil {
+			t.Fatalf("GetExpression returned error: %v", err)
+		}
+		if expr.Status != status {
+			t.Errorf("Expected status %s, got %s", status, expr.Status)
+		}
+		if expr.Progress == nil {
+			t.Fatalf("Expected progress %+v, got none", expected)
+		}
+		progress := *expr.Progress
+		progress.EstimatedMS = 0
+		if progress != expected {
+			t.Errorf("Expected progress %+v, got %+v", expected, progress)
+		}
+	}
+
+	check(entities.ExpressionStatusPending, entities.Progress{Total: 3, Ready: 2})
+
+	task, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+	check(entities.ExpressionStatusProcessing, entities.Progress{Total: 3, Ready: 1, InFlight: 1})
+
+	if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: task.Arg1 * task.Arg2, Lease: task.Lease}); err != nil {
+		t.Fatalf("ProcessResult returned error: %v", err)
+	}
+	check(entities.ExpressionStatusProcessing, entities.Progress{Total: 3, Ready: 1, Completed: 1})
+
+	for {
+		task, err := s.GetTask()
+		if err != nil {
+			break
+		}
+		result := task.Arg1 * task.Arg2
+		if task.Operation == "+" {
+			result = task.Arg1 + task.Arg2
+		}
+		if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: result, Lease: task.Lease}); err != nil {
+			t.Fatalf("ProcessResult returned error: %v", err)
+		}
+	}
+
+	// the progress of a completed expression is forgotten
+	expr, err := s.GetExpression("1")
+	if err != nil || expr.Status != entities.ExpressionStatusCompleted || expr.Progress != nil {
+		t.Errorf("Expected completed expression without progress, got %+v, %v", expr, err)
+	}
+}
+
+func TestEstimateRemaining(t *testing.T) {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := started.Add(3 * time.Second)
+
+	testCases := []struct {
+		name     string
+		progress entities.Progress
+		started  time.Time
+		expected int64
+	}{
+		{name: "not started", progress: entities.Progress{Total: 4}, expected: 0},
+		{name: "nothing computed yet", progress: entities.Progress{Total: 4, InFlight: 1}, started: started, expected: 0},
+		{name: "a quarter computed", progress: entities.Progress{Total: 4, Ready: 2, InFlight: 1, Completed: 1}, started: started, expected: 9000},
+		{name: "three quarters computed", progress: entities.Progress{Total: 4, InFlight: 1, Completed: 3}, started: started, expected: 1000},
+		{name: "all computed", progress: entities.Progress{Total: 4, Completed: 4}, started: started, expected: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := estimateRemaining(tc.progress, tc.started, now); got != tc.expected {
+				t.Errorf("Expected %d ms, got %d ms", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/use_cases/parser"
+)
+
+// rebalance rewrites chains of the same associative operator into balanced
+// trees, so a+b+c+d is computed as (a+b)+(c+d) and the two sums can run on
+// different agents at once. The order of the operands is kept, only the
+// grouping changes, which may change the rounding of float results.
+func rebalance(root *parser.Node) *parser.Node {
+	if root == nil {
+		return nil
+	}
+	if !isAssociative(root.Token.Type) {
+		root.Left = rebalance(root.Left)
+		root.Right = rebalance(root.Right)
+		return root
+	}
+
+	var operands []*parser.Node
+	collectOperands(root, root.Token.Type, &operands)
+	for i := range operands {
+		operands[i] = rebalance(operands[i])
+	}
+	return balancedTree(root.Token, operands)
+}
+
+func isAssociative(t parser.TokenType) bool {
+	return t == parser.Plus || t == parser.Multiply || t == parser.And || t == parser.Or ||
+		t == parser.Min || t == parser.Max
+}
+
+// collectOperands appends the operands of the chain of operators of type t, from left to right.
+func collectOperands(node *parser.Node, t parser.TokenType, operands *[]*parser.Node) {
+	if node.Token.Type != t {
+		*operands = append(*operands, node)
+		return
+	}
+	collectOperands(node.Left, t, operands)
+	collectOperands(node.Right, t, operands)
+}
+
+func balancedTree(op parser.Token, operands []*parser.Node) *parser.Node {
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	middle := len(operands) / 2
+	return &parser.Node{
+		Token: op,
+		Left:  balancedTree(op, operands[:middle]),
+		Right: balancedTree(op, operands[middle:]),
+	}
+}
+
+// criticalPath returns the number of tasks on the longest chain of tasks
+// that wait for each other's results. It is the least number of steps the
+// expression takes however many agents compute it. A conditional waits for
+// its condition and then for the longer of its branches.
+func criticalPath(node *parser.Node) int {
+	return pathLength(node, map[string]int{})
+}
+
+// pathLength returns the critical path of the node, names holds the critical
+// paths of the values of the assigned names.
+func pathLength(node *parser.Node, names map[string]int) int {
+	if node == nil {
+		return 0
+	}
+	switch node.Token.Type {
+	case parser.Number:
+		return 0
+	case parser.Variable:
+		return names[node.Token.Value]
+	case parser.Let:
+		names[node.Token.Value] = pathLength(node.Left, names)
+		return pathLength(node.Right, names)
+	case parser.If:
+		return pathLength(node.Left, names) + pathLength(node.Right, names)
+	case parser.Branches:
+		return max(pathLength(node.Left, names), pathLength(node.Right, names))
+	}
+	return 1 + max(pathLength(node.Left, names), pathLength(node.Right, names))
+}
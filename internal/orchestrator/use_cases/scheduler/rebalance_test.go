@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/configs"
+	"calculator/internal/shared/entities"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRebalance(t *testing.T) {
+	terms := make([]string, 64)
+	for i := range terms {
+		terms[i] = strconv.Itoa(i + 1)
+	}
+
+	testCases := []struct {
+		expression string
+		before     int
+		after      int
+		result     float64
+	}{
+		{expression: strings.Join(terms, "+"), before: 63, after: 6, result: 2080},
+		{expression: "1*2*3*4*5", before: 4, after: 3, result: 120},
+		{expression: "1-2-3-4", before: 3, after: 3, result: -8},
+		{expression: "1+2+3*4*5*6+7", before: 5, after: 4, result: 370},
+		{expression: "sqrt(1+2+3+4+5+6+7+8)", before: 8, after: 4, result: 6},
+		{expression: "if(1 and 1 and 1 and 1, 1+1+1+1, 0)", before: 6, after: 4, result: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			root, err := parser.Parse(tc.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tc.expression, err)
+			}
+			if depth := criticalPath(root); depth != tc.before {
+				t.Errorf("Expected depth %d before rebalancing, got %d", tc.before, depth)
+			}
+
+			root = rebalance(root)
+			if depth := criticalPath(root); depth != tc.after {
+				t.Errorf("Expected depth %d after rebalancing, got %d", tc.after, depth)
+			}
+			result, err := root.Evaluate()
+			if err != nil {
+				t.Fatalf("Evaluate returned error: %v", err)
+			}
+			if result != tc.result {
+				t.Errorf("Expected result %f, got %f", tc.result, result)
+			}
+		})
+	}
+}
+
+func TestScheduleExpressionDepth(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{})
+
+	testCases := []struct {
+		id          string
+		strictOrder bool
+		depth       int
+	}{
+		{id: "balanced", depth: 3},
+		{id: "strict", strictOrder: true, depth: 7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			expr := &entities.Expression{ID: tc.id, Expression: "1+2+3+4+5+6+7+8", StrictOrder: tc.strictOrder}
+			if err := s.ScheduleExpression(expr); err != nil {
+				t.Fatalf("ScheduleExpression returned error: %v", err)
+			}
+			stored, err := storage.GetExpression(tc.id)
+			if err != nil {
+				t.Fatalf("GetExpression returned error: %v", err)
+			}
+			if stored.Depth != tc.depth || stored.StrictOrder != tc.strictOrder {
+				t.Errorf("Expected depth %d, got %+v", tc.depth, stored)
+			}
+		})
+	}
+}
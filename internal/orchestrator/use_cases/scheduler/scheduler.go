@@ -5,7 +5,10 @@ import (
 	"calculator/internal/orchestrator/use_cases/parser"
 	"calculator/internal/shared/configs"
 	"calculator/internal/shared/entities"
+	"calculator/internal/shared/functions"
+	"calculator/internal/shared/units"
 	"calculator/pkg/logger"
+	"sync"
 	"time"
 )
 
@@ -14,100 +17,256 @@ type Scheduler struct {
 	cfg      *configs.Config
 	storage  ExpressionService
 	taskPoll TaskService
+	// catalog resolves the units of quantities like 5 km/h.
+	catalog *units.Catalog
+	// conditionalsMu serializes the resolution of conditional tasks.
+	conditionalsMu sync.Mutex
 }
 
 // NewScheduler creates a new instance of the Scheduler.
+// The unit catalog of the configuration is expected to be valid, an invalid
+// one is logged and replaced with an empty catalog.
 func NewScheduler(storage ExpressionService, task_poll TaskService, cfg *configs.Config) *Scheduler {
+	catalog, err := units.New(cfg.Units)
+	if err != nil {
+		logger.Errorf("Invalid unit catalog: %v", err)
+		catalog, _ = units.New(nil)
+	}
 	return &Scheduler{
 		cfg:      cfg,
 		storage:  storage,
 		taskPoll: task_poll,
+		catalog:  catalog,
 	}
 }
 
 // ScheduleExpression schedules an arithmetic expression for execution.
-func (s *Scheduler) ScheduleExpression(id, expr string) error {
-	if _, err := s.storage.GetExpression(id); err == nil {
-		logger.Errorf("Expression with ID %s already exists", id)
+func (s *Scheduler) ScheduleExpression(expr *entities.Expression) error {
+	if _, err := s.storage.GetExpression(expr.ID); err == nil {
+		logger.Errorf("Expression with ID %s already exists", expr.ID)
 		return use_cases_errors.ErrExpressionExists
 	}
 
-	rootNode, err := parser.Parse(expr)
+	switch expr.AngleUnit {
+	case "":
+		expr.AngleUnit = entities.AngleUnitRadians
+	case entities.AngleUnitRadians, entities.AngleUnitDegrees:
+	default:
+		return use_cases_errors.ErrInvalidAngleUnit
+	}
+	if err := validatePrecision(expr); err != nil {
+		return err
+	}
+	if err := validatePriority(expr); err != nil {
+		return err
+	}
+
+	rootNode, err := s.parse(expr)
 	if err != nil {
 		return err
 	}
-	tasksList := TreeToTasks(rootNode, id)
+	rootNode, defs, err := s.expandCalls(rootNode)
+	if err != nil {
+		return err
+	}
+	if err = parser.Bind(rootNode, expr.Variables); err != nil {
+		return err
+	}
+	if err = checkModeSupport(rootNode, expr.Mode); err != nil {
+		return err
+	}
+	rootNode = reduceAggregates(rootNode, expr.StrictOrder)
+	if rootNode, err = s.convertUnits(rootNode, expr); err != nil {
+		return err
+	}
+	if expr.AngleUnit == entities.AngleUnitDegrees {
+		rootNode = degreesToRadians(rootNode)
+	}
+	// the tasks saved by the optimizer and by sharing identical sub-trees
+	before := countTasks(rootNode)
+	if s.cfg.Optimize {
+		rootNode = s.optimize(rootNode, expr)
+	}
+	if !expr.StrictOrder {
+		rootNode = rebalance(rootNode)
+	}
+	expr.Depth = criticalPath(rootNode)
+	list := splitTree(rootNode, expr.ID)
+	if err = s.checkTasks(len(list.tasks)); err != nil {
+		return err
+	}
+	expr.TasksSaved = before - len(list.tasks)
+	setBindings(expr, list.literals)
+	if len(list.tasks) == 0 {
+		if err = s.completeNumber(expr, list.result); err != nil {
+			return err
+		}
+		return s.saveFunctions(defs)
+	}
+	setPrecision(list.tasks, expr)
+	setPriority(list.tasks, expr)
 
-	err = s.taskPoll.AddTasks(tasksList)
+	err = s.taskPoll.AddTasks(list.tasks)
 
 	if err != nil {
 		logger.Error(err)
 		return err
 	}
 
-	return s.storage.CreateExpression(id, expr)
+	if err = s.storage.CreateExpression(expr); err != nil {
+		return err
+	}
+	if err = s.saveFunctions(defs); err != nil {
+		return err
+	}
+
+	return s.resolveConditionals(expr.ID)
+}
+
+// completeNumber stores the expression whose tree is a single number
+// as completed, it has no tasks to send to agents.
+func (s *Scheduler) completeNumber(expr *entities.Expression, number entities.Arg) error {
+	if err := s.storage.CreateExpression(expr); err != nil {
+		return err
+	}
+
+	task := entities.Task{ID: expr.ID, Mode: expr.Mode, Scale: expr.Scale}
+	if err := s.storage.UpdateExpression(expr.ID, entities.ExpressionStatusCompleted, argResult(task, number)); err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
 }
 
-// GetTask retrieves the next task from the queue.
+// GetTask retrieves the next task from the queue in the order of the priorities of the expressions.
 func (s *Scheduler) GetTask() (*entities.AgentTask, error) {
-	task, err := s.taskPoll.GetTaskToCompute()
+	task, err := s.taskPoll.GetTaskToCompute(s.priorityAging(), s.leaseDeadline)
 
 	if err != nil {
 		logger.Error(err)
 		return nil, use_cases_errors.ErrNoTasksAvailable
 	}
+	if _, err = s.storage.ChangeStatus(task.ExprID, entities.ExpressionStatusProcessing, entities.ExpressionStatusPending); err != nil {
+		logger.Errorf("Failed to mark expression %s processing: %v", task.ExprID, err)
+	}
 	agentTask := s.taskToAgentTask(task)
 	return &agentTask, nil
 }
 
 // ProcessResult processes the result of a task computation.
-// Deletes the task from the queue after processing.
-func (s *Scheduler) ProcessResult(taskID string, result float64) error {
+// Deletes the task from the queue after processing and resolves the
+// conditionals whose condition became known. The result of a lease that
+// ended is rejected with ErrLeaseExpired. The result of a task of a cancelled
+// or failed expression is accepted and ignored, even after its lease ended.
+func (s *Scheduler) ProcessResult(result entities.TaskResult) error {
+	if s.isCancelled(result.ID) {
+		return nil
+	}
+	if err := s.checkLease(result.ID, result.Lease); err != nil {
+		return err
+	}
+	exprID, err := s.completeTask(result)
+	if err != nil {
+		return err
+	}
+
+	return s.resolveConditionals(exprID)
+}
+
+// completeTask passes the result of the task to the tasks that depend on it,
+// records it as the value of the names assigned to it and completes the
+// expression after its last task.
+func (s *Scheduler) completeTask(result entities.TaskResult) (string, error) {
+	taskID := result.ID
 
 	exprID, err := s.taskPoll.GetExpressionIDByTaskID(taskID)
 
 	if err != nil {
 		logger.Error(err)
-		return use_cases_errors.ErrNoTasksAvailable
+		return "", use_cases_errors.ErrNoTasksAvailable
 	}
 
-	err = s.taskPoll.SetTaskResultAfterCompute(taskID, result)
+	names, err := s.taskPoll.GetBindings(taskID)
 	if err != nil {
 		logger.Error(err)
-		return err
+		return "", err
+	}
+	if len(names) > 0 {
+		if err = s.storage.UpdateBindings(exprID, names, result); err != nil {
+			logger.Error(err)
+			return "", err
+		}
+	}
+
+	err = s.taskPoll.SetTaskResultAfterCompute(result)
+	if err != nil {
+		logger.Error(err)
+		return "", err
 	}
 	err = s.taskPoll.DeleteTask(taskID)
 	if err != nil {
 		logger.Error(err)
-		return err
+		return "", err
 	}
 
-	isLastTask, err := s.taskPoll.IsLastTask(taskID)
+	exprResult, computed, err := s.taskPoll.ExpressionResult(exprID)
 	if err != nil {
 		logger.Error(err)
-		return err
+		return "", err
 	}
 
-	if !isLastTask {
-		return nil
+	if !computed {
+		return exprID, nil
 	}
 
-	if isLastTask {
-		logger.Infof("Expression %s completed with result %f", exprID, result)
-	}
+	logger.Infof("Expression %s completed with result %f", exprID, exprResult.Result)
 
 	err = s.taskPoll.DeleteExpression(exprID)
 	if err != nil {
 		logger.Error(err)
-		return err
+		return "", err
 	}
 
-	if err = s.storage.UpdateExpression(exprID, entities.ExpressionStatusCompleted, result); err != nil {
+	if err = s.storage.UpdateExpression(exprID, entities.ExpressionStatusCompleted, exprResult); err != nil {
 		logger.Error(err)
-		return err
+		return "", err
 	}
 
-	return nil
+	return exprID, nil
+}
+
+// resolveConditionals chooses the branches of the conditional tasks of the
+// expression whose condition is computed. The tasks of the other branch are
+// deleted without being sent to agents. A branch that is a number completes
+// the conditional task at once, which may make further conditionals ready.
+func (s *Scheduler) resolveConditionals(exprID string) error {
+	s.conditionalsMu.Lock()
+	defer s.conditionalsMu.Unlock()
+
+	for {
+		conditionals, err := s.taskPoll.GetReadyConditionals(exprID)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		if len(conditionals) == 0 {
+			return nil
+		}
+
+		for _, task := range conditionals {
+			chosen, err := s.taskPoll.ChooseBranch(task.ID, isTrue(task.ArgLeft))
+			if err != nil {
+				logger.Error(err)
+				return err
+			}
+			if chosen.ArgType == entities.IsTask {
+				continue
+			}
+			if _, err = s.completeTask(argResult(task, chosen)); err != nil {
+				return err
+			}
+		}
+	}
 }
 
 func (s *Scheduler) taskToAgentTask(task entities.Task) entities.AgentTask {
@@ -118,7 +277,15 @@ func (s *Scheduler) taskToAgentTask(task entities.Task) entities.AgentTask {
 		Arg1:          task.ArgLeft.ArgFloat,
 		Arg2:          task.ArgRight.ArgFloat,
 		Operation:     task.Operation,
+		Kind:          task.Kind,
+		Mode:          task.Mode,
+		Scale:         task.Scale,
+		ExactArg1:     task.ArgLeft.ArgExact,
+		ExactArg2:     task.ArgRight.ArgExact,
+		Imag1:         task.ArgLeft.ArgImag,
+		Imag2:         task.ArgRight.ArgImag,
 		OperationTime: s.getOperationTime(task.Operation),
+		Lease:         task.Lease,
 	}
 }
 func (s *Scheduler) getOperationTime(operation string) time.Duration {
@@ -132,14 +299,36 @@ func (s *Scheduler) getOperationTime(operation string) time.Duration {
 		opTime = s.cfg.TimeMultiplicationMS
 	case "/":
 		opTime = s.cfg.TimeDivisionMS
+	case "%":
+		opTime = s.cfg.TimeModuloMS
+	case "//":
+		opTime = s.cfg.TimeFloorDivisionMS
+	case "^":
+		opTime = s.cfg.TimeExponentiationMS
+	case "<", "<=", ">", ">=", "==", "!=", "and", "or", "not":
+		opTime = s.cfg.TimeComparisonMS
+	case "min":
+		opTime = s.cfg.TimeMinimumMS
+	case "max":
+		opTime = s.cfg.TimeMaximumMS
+	default:
+		if functions.IsUnary(operation) {
+			opTime = s.cfg.TimeFunctionMS
+		}
 	}
 
 	return time.Duration(opTime) * time.Millisecond
 }
 
-// GetExpression retrieves an arithmetic expression by its ID.
+// GetExpression retrieves an arithmetic expression by its ID with the
+// progress of its tasks.
 func (s *Scheduler) GetExpression(id string) (*entities.Expression, error) {
-	return s.storage.GetExpression(id)
+	expr, err := s.storage.GetExpression(id)
+	if err != nil {
+		return nil, err
+	}
+	expr.Progress = s.progress(expr)
+	return expr, nil
 }
 
 // GetExpressions retrieves all arithmetic expressions.
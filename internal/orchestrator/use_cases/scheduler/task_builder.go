@@ -4,10 +4,15 @@ import (
 	"calculator/internal/orchestrator/use_cases/parser"
 	"calculator/internal/shared/entities"
 	"calculator/pkg/uuid"
+	"fmt"
+	"slices"
+	"strings"
 )
 
 // TreeToTasks converts a binary tree representation of an arithmetic expression
-// into a list of tasks.
+// into a list of tasks. Structurally identical sub-trees are computed by a
+// single task whose result feeds every task that uses it, and so is the
+// value of a name assigned in a script.
 //
 // Parameters:
 // - root: The root node of the binary tree representing the arithmetic expression.
@@ -16,50 +21,210 @@ import (
 // Returns:
 // - []Task: The list of tasks representing the arithmetic expression.
 func TreeToTasks(root *parser.Node, ExprID string) []entities.Task {
+	return splitTree(root, ExprID).tasks
+}
+
+// taskList is an expression tree split into tasks.
+type taskList struct {
+	// tasks holds the root task first.
+	tasks []entities.Task
+	// result is the argument the expression is computed to,
+	// it is a number when there are no tasks.
+	result entities.Arg
+	// literals are the assigned names whose values are numbers.
+	literals map[string]entities.Arg
+}
+
+func splitTree(root *parser.Node, exprID string) taskList {
+	list := taskList{tasks: []entities.Task{}, literals: map[string]entities.Arg{}}
 	if root == nil {
-		return []entities.Task{}
+		return list
 	}
-	tasks := []entities.Task{}
+	b := &taskBuilder{
+		exprID: exprID,
+		tasks:  []entities.Task{},
+		shared: make(map[string]*entities.Task),
+		keys:   make(map[string]string),
+		last:   make(map[string]*taskBinding),
+	}
+
+	list.result, _ = b.buildArgument(root, "", "")
 
-	appendTask(ExprID, root, &tasks)
+	// the names are reported with the value of their last assignment
+	names := map[string][]string{}
+	for name, binding := range b.last {
+		if strings.HasPrefix(name, "#") {
+			// the internal names of the reduced aggregates
+			continue
+		}
+		// the names never used and the ones whose uses the optimizer
+		// replaced with numbers are computed for the bindings only
+		if !binding.built {
+			b.resolve(binding, "", "")
+		}
+		switch {
+		case binding.arg.ArgType == entities.IsNumber:
+			list.literals[name] = binding.arg
+		case binding.arg.ArgTask != nil:
+			names[binding.arg.ArgTask.ID] = append(names[binding.arg.ArgTask.ID], name)
+		}
+	}
 
-	// root element to first
-	if len(tasks) > 0 {
-		tasks[0], tasks[len(tasks)-1] = tasks[len(tasks)-1], tasks[0]
+	list.tasks = b.tasks
+	for i := range list.tasks {
+		list.tasks[i].Bindings = names[list.tasks[i].ID]
+		slices.Sort(list.tasks[i].Bindings)
+		// root element to first
+		if list.result.ArgTask != nil && list.tasks[i].ID == list.result.ArgTask.ID {
+			list.tasks[0], list.tasks[i] = list.tasks[i], list.tasks[0]
+		}
 	}
-	return tasks
+	return list
 }
 
-func appendTask(exprID string, root *parser.Node, tasks *[]entities.Task) *entities.Task {
-	if root == nil || root.Left == nil || root.Right == nil {
-		return nil
+// taskBuilder collects the tasks of an expression tree.
+type taskBuilder struct {
+	exprID string
+	tasks  []entities.Task
+	// shared maps the scope and the structure of a sub-tree to its task.
+	shared map[string]*entities.Task
+	// keys maps the structures of the sub-trees to short keys, so the key
+	// of a node stays short when the values of names are used many times.
+	keys map[string]string
+	// bindings are the names visible at the node being split.
+	bindings *taskBinding
+	// last maps the assigned names to their last assignments.
+	last map[string]*taskBinding
+}
+
+// taskBinding is a name assigned in a script. The tasks of its value are
+// added on its first use, so a value whose uses were optimized away is not
+// computed. They are never guarded, the value may be used in several branches.
+// An internal name of a reduced aggregate is only used inside the aggregate,
+// the tasks of its value get the guard and the scope of the aggregate.
+type taskBinding struct {
+	name  string
+	value *parser.Node
+	// outer are the names visible at the assignment.
+	outer *taskBinding
+	built bool
+	arg   entities.Arg
+	key   string
+}
+
+func (t *taskBinding) lookup(name string) *taskBinding {
+	for ; t != nil; t = t.outer {
+		if t.name == name {
+			return t
+		}
 	}
+	return nil
+}
 
-	leftArg, rightArg := buildArguments(exprID, root, tasks)
+// appendTask appends the tasks of the subtree to tasks and returns the task
+// of the subtree with its structural key. The tasks of the branches of a
+// conditional are guarded by the conditional task, the other tasks get the
+// guard of their parent.
+//
+// A sub-tree is only shared inside its scope, which is the branch of the
+// conditional it belongs to, so dropping a branch never drops a task that
+// is used outside of it. Conditionals are never shared, their key is empty.
+func (b *taskBuilder) appendTask(root *parser.Node, guard, scope string) (*entities.Task, string) {
+	if root == nil || root.Left == nil {
+		return nil, ""
+	}
+	if root.Right == nil && !isUnaryNode(root) {
+		return nil, ""
+	}
 
 	task := &entities.Task{
 		ID:        uuid.New(),
-		ExprID:    exprID,
-		ArgLeft:   leftArg,
-		ArgRight:  rightArg,
+		ExprID:    b.exprID,
 		Operation: root.Token.Value,
+		Guard:     guard,
+	}
+
+	var leftKey, rightKey string
+	switch {
+	case root.Token.Type == parser.If:
+		task.Kind = entities.TaskKindConditional
+		task.ArgLeft, _ = b.buildArgument(root.Left, guard, scope)
+		task.ArgRight = entities.Arg{ArgType: entities.IsEmpty}
+		task.ArgThen, _ = b.buildArgument(root.Right.Left, task.ID, task.ID+"/then")
+		task.ArgElse, _ = b.buildArgument(root.Right.Right, task.ID, task.ID+"/else")
+		b.tasks = append(b.tasks, *task)
+		return task, ""
+	case isUnaryNode(root):
+		task.Kind = entities.TaskKindUnary
+		task.ArgLeft, leftKey = b.buildArgument(root.Left, guard, scope)
+		task.ArgRight = entities.Arg{ArgType: entities.IsEmpty}
+	default:
+		task.Kind = entities.TaskKindBinary
+		task.ArgLeft, leftKey = b.buildArgument(root.Left, guard, scope)
+		task.ArgRight, rightKey = b.buildArgument(root.Right, guard, scope)
+		if rightKey == "" {
+			leftKey = ""
+		}
+	}
+
+	if leftKey == "" {
+		b.tasks = append(b.tasks, *task)
+		return task, ""
+	}
+
+	key := b.key(fmt.Sprintf("(%d %s %s %s)", root.Token.Type, root.Token.Value, leftKey, rightKey))
+	if existing, ok := b.shared[scope+" "+key]; ok {
+		return existing, key
 	}
-	*tasks = append(*tasks, *task)
-	return task
+	b.shared[scope+" "+key] = task
+	b.tasks = append(b.tasks, *task)
+	return task, key
 }
 
-func buildArguments(exprID string, root *parser.Node, tasks *[]entities.Task) (entities.Arg, entities.Arg) {
-	var leftArg, rightArg entities.Arg
-	if root.Left.Token.Type == parser.Number {
-		leftArg = entities.Arg{ArgFloat: root.Left.Value, ArgType: entities.IsNumber}
-	} else {
-		leftArg = entities.Arg{ArgTask: appendTask(exprID, root.Left, tasks), ArgType: entities.IsTask}
+// key returns the short key of the structure of a sub-tree.
+func (b *taskBuilder) key(structure string) string {
+	key, ok := b.keys[structure]
+	if !ok {
+		key = fmt.Sprintf("#%d", len(b.keys))
+		b.keys[structure] = key
 	}
+	return key
+}
+
+func isUnaryNode(node *parser.Node) bool {
+	return node.Token.Type == parser.Function || node.Token.Type == parser.Not
+}
+
+// buildArgument returns the argument for the node with its structural key.
+func (b *taskBuilder) buildArgument(node *parser.Node, guard, scope string) (entities.Arg, string) {
+	switch node.Token.Type {
+	case parser.Number:
+		return entities.Arg{ArgFloat: node.Value, ArgExact: node.Token.Value, ArgType: entities.IsNumber}, node.Token.Value
+	case parser.Let:
+		b.bindings = &taskBinding{name: node.Token.Value, value: node.Left, outer: b.bindings}
+		b.last[node.Token.Value] = b.bindings
+		return b.buildArgument(node.Right, guard, scope)
+	case parser.Variable:
+		if binding := b.bindings.lookup(node.Token.Value); binding != nil {
+			return b.resolve(binding, guard, scope)
+		}
+	}
+	task, key := b.appendTask(node, guard, scope)
+	return entities.Arg{ArgTask: task, ArgType: entities.IsTask}, key
+}
 
-	if root.Right.Token.Type == parser.Number {
-		rightArg = entities.Arg{ArgFloat: root.Right.Value, ArgType: entities.IsNumber}
-	} else {
-		rightArg = entities.Arg{ArgTask: appendTask(exprID, root.Right, tasks), ArgType: entities.IsTask}
+// resolve returns the argument for the value of the assigned name used
+// under the guard and in the scope.
+func (b *taskBuilder) resolve(binding *taskBinding, guard, scope string) (entities.Arg, string) {
+	if !binding.built {
+		if !strings.HasPrefix(binding.name, "#") {
+			guard, scope = "", ""
+		}
+		visible := b.bindings
+		b.bindings = binding.outer
+		binding.arg, binding.key = b.buildArgument(binding.value, guard, scope)
+		b.bindings = visible
+		binding.built = true
 	}
-	return leftArg, rightArg
+	return binding.arg, binding.key
 }
@@ -1,9 +1,20 @@
 package scheduler
 
 import (
+	"calculator/internal/orchestrator/impl/memory_expression_storage"
+	"calculator/internal/orchestrator/impl/memory_task_storage"
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
 	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/complexnum"
+	"calculator/internal/shared/configs"
 	"calculator/internal/shared/entities"
 	"calculator/pkg/uuid"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -88,3 +99,632 @@ func Compare(t, t2 entities.Task) bool {
 		t.ArgLeft.ArgFloat == t2.ArgLeft.ArgFloat &&
 		t.ArgRight.ArgFloat == t2.ArgRight.ArgFloat
 }
+
+func TestTreeToTasksUnary(t *testing.T) {
+	// sqrt(2 * 8)
+	root := &parser.Node{
+		Token: parser.Token{Type: parser.Function, Value: "sqrt"},
+		Left: &parser.Node{
+			Token: parser.Token{Type: parser.Multiply, Value: "*"},
+			Left:  &parser.Node{Token: parser.Token{Type: parser.Number, Value: "2"}, Value: 2.0},
+			Right: &parser.Node{Token: parser.Token{Type: parser.Number, Value: "8"}, Value: 8.0},
+		},
+	}
+	tasks := TreeToTasks(root, "TestExprID")
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %v", tasks)
+	}
+
+	if tasks[0].Kind != entities.TaskKindUnary || tasks[0].Operation != "sqrt" {
+		t.Errorf("Expected unary sqrt task first, got %v", tasks[0])
+	}
+	if tasks[0].ArgLeft.ArgType != entities.IsTask || tasks[0].ArgLeft.ArgTask.ID != tasks[1].ID {
+		t.Errorf("Expected sqrt argument to be the multiplication task, got %v", tasks[0].ArgLeft)
+	}
+	if tasks[0].ArgRight.ArgType != entities.IsEmpty {
+		t.Errorf("Expected empty right argument, got %v", tasks[0].ArgRight)
+	}
+	if tasks[1].Kind != entities.TaskKindBinary || tasks[1].Operation != "*" {
+		t.Errorf("Expected binary multiplication task, got %v", tasks[1])
+	}
+	if tasks[1].ArgLeft.ArgExact != "2" || tasks[1].ArgRight.ArgExact != "8" {
+		t.Errorf("Expected literals to be kept as exact arguments, got %v", tasks[1])
+	}
+}
+
+func TestTreeToTasksConditional(t *testing.T) {
+	root, err := parser.Parse("if(x > 1, 2 * 3, 4 - 5)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.Bind(root, map[string]float64{"x": 2})
+
+	tasks := TreeToTasks(root, "TestExprID")
+	if len(tasks) != 4 {
+		t.Fatalf("Expected 4 tasks, got %v", tasks)
+	}
+
+	conditional := tasks[0]
+	if conditional.Kind != entities.TaskKindConditional || conditional.Guard != "" {
+		t.Fatalf("Expected unguarded conditional task first, got %v", conditional)
+	}
+	if conditional.ArgRight.ArgType != entities.IsEmpty {
+		t.Errorf("Expected empty right argument, got %v", conditional.ArgRight)
+	}
+
+	guards := map[string]string{}
+	for _, task := range tasks[1:] {
+		guards[task.Operation] = task.Guard
+	}
+	if guards[">"] != "" {
+		t.Errorf("Expected the condition not to be guarded, got %q", guards[">"])
+	}
+	if guards["*"] != conditional.ID || guards["-"] != conditional.ID {
+		t.Errorf("Expected the branches to be guarded by the conditional task, got %v", guards)
+	}
+	if conditional.ArgThen.ArgTask.Operation != "*" || conditional.ArgElse.ArgTask.Operation != "-" {
+		t.Errorf("Expected the branches in ArgThen and ArgElse, got %v", conditional)
+	}
+}
+
+func TestTreeToTasksShared(t *testing.T) {
+	root, err := parser.Parse("(a*b + c) / (a*b - c)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.Bind(root, map[string]float64{"a": 2, "b": 3, "c": 1})
+
+	tasks := TreeToTasks(root, "TestExprID")
+	if len(tasks) != 4 {
+		t.Fatalf("Expected 4 tasks, got %v", tasks)
+	}
+
+	var sum, difference *entities.Task
+	for i := range tasks {
+		switch tasks[i].Operation {
+		case "+":
+			sum = &tasks[i]
+		case "-":
+			difference = &tasks[i]
+		}
+	}
+	if sum == nil || difference == nil {
+		t.Fatalf("Expected sum and difference tasks, got %v", tasks)
+	}
+	if sum.ArgLeft.ArgTask == nil || difference.ArgLeft.ArgTask == nil || sum.ArgLeft.ArgTask.ID != difference.ArgLeft.ArgTask.ID {
+		t.Errorf("Expected the product to be computed once for both tasks, got %v and %v", sum.ArgLeft, difference.ArgLeft)
+	}
+
+	// identical sub-trees in different branches of a conditional are not shared,
+	// the tasks of the rejected branch are dropped
+	root, err = parser.Parse("x*2 + if(x > 0, x*2, 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.Bind(root, map[string]float64{"x": 2})
+
+	tasks = TreeToTasks(root, "TestExprID")
+	if len(tasks) != 5 {
+		t.Errorf("Expected 5 tasks, got %v", tasks)
+	}
+}
+
+func TestTreeToTasksBindings(t *testing.T) {
+	root, err := parser.Parse("a = x*2; b = a + 1; b * a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = parser.Bind(root, map[string]float64{"x": 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := TreeToTasks(root, "TestExprID")
+	if len(tasks) != 3 {
+		t.Fatalf("Expected 3 tasks, got %v", tasks)
+	}
+
+	product := tasks[0]
+	if product.Operation != "*" || product.ArgLeft.ArgTask == nil || product.ArgRight.ArgTask == nil {
+		t.Fatalf("Expected the root task to multiply b by a, got %v", product)
+	}
+	var sum, double entities.Task
+	for _, task := range tasks {
+		switch task.ID {
+		case product.ArgLeft.ArgTask.ID:
+			sum = task
+		case product.ArgRight.ArgTask.ID:
+			double = task
+		}
+	}
+	if sum.ArgLeft.ArgTask == nil || sum.ArgLeft.ArgTask.ID != double.ID {
+		t.Errorf("Expected a to be computed once for both tasks, got %v and %v", sum, double)
+	}
+	if !slices.Equal(sum.Bindings, []string{"b"}) || !slices.Equal(double.Bindings, []string{"a"}) || product.Bindings != nil {
+		t.Errorf("Expected a and b to be assigned their tasks, got %v", tasks)
+	}
+}
+
+func TestScheduleExpressionBindings(t *testing.T) {
+	cfg := &configs.Config{FoldThresholdMS: 1}
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), cfg)
+
+	testCases := []struct {
+		id         string
+		expression string
+		variables  map[string]float64
+		result     float64
+		bindings   map[string]float64
+	}{
+		{id: "1", expression: "a = 2 + 3; b = a * 4; b - a", result: 15, bindings: map[string]float64{"a": 5, "b": 20}},
+		{id: "2", expression: "a = x * 2; b = a + x; b * a", variables: map[string]float64{"x": 3}, result: 54, bindings: map[string]float64{"a": 6, "b": 9}},
+		{id: "3", expression: "a = x * x; a = a + 1; a", variables: map[string]float64{"x": 3}, result: 10, bindings: map[string]float64{"a": 10}},
+		// the value of a name used only in the rejected branch is still computed
+		{id: "4", expression: "a = x * 2; if(x < 0, a + 1, 7)", variables: map[string]float64{"x": 3}, result: 7, bindings: map[string]float64{"a": 6}},
+		{id: "5", expression: "a = x * 2; b = if(x > 0, a, a - 1); b * b", variables: map[string]float64{"x": 3}, result: 36, bindings: map[string]float64{"a": 6, "b": 6}},
+		// a name never used is computed for the bindings
+		{id: "6", expression: "a = 1 + 2; b = a * 4; a", result: 3, bindings: map[string]float64{"a": 3, "b": 12}},
+	}
+
+	// with the optimizer the script is computed at once, without it by the agents
+	for _, optimize := range []bool{false, true} {
+		cfg.Optimize = optimize
+		for _, tc := range testCases {
+			t.Run(tc.expression, func(t *testing.T) {
+				id := strconv.FormatBool(optimize) + tc.id
+				err := s.ScheduleExpression(&entities.Expression{ID: id, Expression: tc.expression, Variables: tc.variables})
+				if err != nil {
+					t.Fatalf("ScheduleExpression returned error: %v", err)
+				}
+				for {
+					task, err := s.GetTask()
+					if err != nil {
+						break
+					}
+					if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: compute(task), Lease: task.Lease}); err != nil {
+						t.Fatalf("ProcessResult returned error: %v", err)
+					}
+					// the expression is completed only after the values of all its names
+					if expr, _ := storage.GetExpression(id); expr.Status == entities.ExpressionStatusCompleted && !maps.Equal(expr.Bindings, tc.bindings) {
+						t.Fatalf("Expected the expression to be completed with bindings %v, got %v", tc.bindings, expr.Bindings)
+					}
+				}
+
+				expr, err := storage.GetExpression(id)
+				if err != nil {
+					t.Fatalf("GetExpression returned error: %v", err)
+				}
+				if expr.Status != entities.ExpressionStatusCompleted || expr.Result != tc.result {
+					t.Errorf("Expected completed expression with result %f, got %+v", tc.result, expr)
+				}
+				if !maps.Equal(expr.Bindings, tc.bindings) {
+					t.Errorf("Expected bindings %v, got %v", tc.bindings, expr.Bindings)
+				}
+			})
+		}
+	}
+}
+
+func TestScheduleExpressionFunctions(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{MaxExpansionNodes: 100})
+
+	if _, err := s.DefineFunction("sq(x) = x * x"); err != nil {
+		t.Fatalf("DefineFunction returned error: %v", err)
+	}
+
+	// the steps depend on the functions defined by the previous ones
+	steps := []struct {
+		expression string
+		definition string
+		result     float64
+		wantErr    bool
+	}{
+		{expression: "hyp(a, b) = sq(a) + sq(b); hyp(x, 4)", result: 25},
+		{expression: "hyp(1, 2) * 2", result: 10},
+		{expression: "g(x) = x + 1; g(1, 2)", wantErr: true},
+		// a script that failed defines nothing
+		{expression: "g(2)", wantErr: true},
+		{definition: "sq(x) = hyp(x, x)", wantErr: true},
+		{definition: "sq(x) = x * x * x"},
+		{expression: "hyp(1, 2)", result: 9},
+	}
+
+	for i, step := range steps {
+		if step.definition != "" {
+			_, err := s.DefineFunction(step.definition)
+			if (err != nil) != step.wantErr {
+				t.Fatalf("Step %d: DefineFunction(%q) returned error %v", i, step.definition, err)
+			}
+			continue
+		}
+
+		id := strconv.Itoa(i)
+		err := s.ScheduleExpression(&entities.Expression{ID: id, Expression: step.expression, Variables: map[string]float64{"x": 3}})
+		if (err != nil) != step.wantErr {
+			t.Fatalf("Step %d: ScheduleExpression(%q) returned error %v", i, step.expression, err)
+		}
+		if err != nil {
+			continue
+		}
+		for {
+			task, err := s.GetTask()
+			if err != nil {
+				break
+			}
+			if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: compute(task), Lease: task.Lease}); err != nil {
+				t.Fatalf("ProcessResult returned error: %v", err)
+			}
+		}
+		expr, err := storage.GetExpression(id)
+		if err != nil {
+			t.Fatalf("GetExpression returned error: %v", err)
+		}
+		if expr.Status != entities.ExpressionStatusCompleted || expr.Result != step.result {
+			t.Errorf("Step %d: expected completed expression with result %f, got %+v", i, step.result, expr)
+		}
+	}
+
+	functions, err := storage.GetFunctions()
+	if err != nil {
+		t.Fatalf("GetFunctions returned error: %v", err)
+	}
+	var definitions []string
+	for _, fn := range functions {
+		definitions = append(definitions, fn.Definition)
+	}
+	expected := []string{"hyp(a, b) = sq(a) + sq(b)", "sq(x) = x * x * x"}
+	if !slices.Equal(definitions, expected) {
+		t.Errorf("Expected functions %v, got %v", expected, definitions)
+	}
+}
+
+func TestScheduleExpressionComplex(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{})
+
+	err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "(x + 2i) * 3", Mode: entities.ModeComplex, Variables: map[string]float64{"x": 1}})
+	if err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+
+	// the agents get the real and imaginary parts of the arguments
+	steps := []struct {
+		arg1, arg2 complex128
+		result     complex128
+	}{
+		{arg1: 1, arg2: 2i, result: 1 + 2i},
+		{arg1: 1 + 2i, arg2: 3, result: 3 + 6i},
+	}
+	for _, step := range steps {
+		task, err := s.GetTask()
+		if err != nil {
+			t.Fatalf("GetTask returned error: %v", err)
+		}
+		if complex(task.Arg1, task.Imag1) != step.arg1 || complex(task.Arg2, task.Imag2) != step.arg2 {
+			t.Errorf("Expected arguments %v and %v, got %+v", step.arg1, step.arg2, task)
+		}
+		result := entities.TaskResult{ID: task.ID, Result: real(step.result), Imag: imag(step.result), Exact: complexnum.Format(step.result), Lease: task.Lease}
+		if err = s.ProcessResult(result); err != nil {
+			t.Fatalf("ProcessResult returned error: %v", err)
+		}
+	}
+
+	expr, err := storage.GetExpression("1")
+	if err != nil {
+		t.Fatalf("GetExpression returned error: %v", err)
+	}
+	if expr.Status != entities.ExpressionStatusCompleted || expr.Result != 3 || expr.Imag != 6 || expr.ExactResult != "3+6i" {
+		t.Errorf("Expected completed expression with result 3+6i, got %+v", expr)
+	}
+
+	err = s.ScheduleExpression(&entities.Expression{ID: "2", Expression: "2i + 1"})
+	if err == nil {
+		t.Errorf("Expected error for an imaginary number in float mode")
+	}
+}
+
+func TestScheduleExpressionUnits(t *testing.T) {
+	units, err := configs.LoadUnits("../../../../configs/units.yml")
+	if err != nil {
+		t.Fatalf("LoadUnits returned error: %v", err)
+	}
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{Units: units})
+
+	testCases := []struct {
+		expr     string
+		to       string
+		expected float64
+		unit     string
+	}{
+		{expr: "5 m + 30 cm", expected: 5.3, unit: "m"},
+		{expr: "100 km / 2 h", to: "km/h", expected: 50, unit: "km/h"},
+		{expr: "3 kg * 9.81 m/s^2", to: "N", expected: 29.43, unit: "N"},
+		{expr: "d = 2 km; d / 4 min", expected: 2000.0 / 240, unit: "m/s"},
+		{expr: "sqrt(2 m^2 * 8 m^2)", expected: 4, unit: "m^2"},
+		{expr: "(6 m)^2 / 3 ha", expected: 0.0012, unit: ""},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			id := strconv.Itoa(i)
+			if err := s.ScheduleExpression(&entities.Expression{ID: id, Expression: tc.expr, To: tc.to}); err != nil {
+				t.Fatalf("ScheduleExpression returned error: %v", err)
+			}
+			for {
+				task, err := s.GetTask()
+				if err != nil {
+					break
+				}
+				if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: compute(task), Lease: task.Lease}); err != nil {
+					t.Fatalf("ProcessResult returned error: %v", err)
+				}
+			}
+
+			expr, err := storage.GetExpression(id)
+			if err != nil {
+				t.Fatalf("GetExpression returned error: %v", err)
+			}
+			if expr.Status != entities.ExpressionStatusCompleted || math.Abs(expr.Result-tc.expected) > 1e-9 || expr.Unit != tc.unit {
+				t.Errorf("Expected completed expression with result %g %s, got %+v", tc.expected, tc.unit, expr)
+			}
+		})
+	}
+
+	errorCases := []struct {
+		expr    string
+		to      string
+		message string
+	}{
+		{expr: "5 m + 2 s", message: "incompatible units of +: m and s at position 4"},
+		{expr: "sin(2 m)", message: "function sin expects a dimensionless argument, got m at position 0"},
+		{expr: "(2 m)^1.5", message: "exponent of a quantity in m must be a whole number at position 5"},
+		{expr: "2 parsec", message: "unknown unit: parsec at position 2"},
+		{expr: "5 m", to: "s", message: "invalid unit: cannot convert m to s"},
+		{expr: "5", to: "parsec", message: "invalid unit: unknown unit: parsec"},
+	}
+	for i, tc := range errorCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			err := s.ScheduleExpression(&entities.Expression{ID: "error" + strconv.Itoa(i), Expression: tc.expr, To: tc.to, Variables: map[string]float64{"x": 2}})
+			if err == nil || err.Error() != tc.message {
+				t.Errorf("Expected error %q, got %v", tc.message, err)
+			}
+		})
+	}
+}
+
+func TestScheduleExpressionDialect(t *testing.T) {
+	units, err := configs.LoadUnits("../../../../configs/units.yml")
+	if err != nil {
+		t.Fatalf("LoadUnits returned error: %v", err)
+	}
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{Units: units})
+
+	testCases := []struct {
+		expr     string
+		dialect  entities.Dialect
+		locale   string
+		expected float64
+	}{
+		{expr: "2(x + 1)", dialect: entities.DialectMath, expected: 6},
+		{expr: "3x − x", dialect: entities.DialectMath, expected: 4},
+		{expr: "2 × 3 m + 20cm", dialect: entities.DialectMath, expected: 6.2},
+		{expr: "1,5 * x", locale: "de-DE", expected: 3},
+		{expr: "1,5x", dialect: entities.DialectMath, locale: "ru_RU", expected: 3},
+		// variables and names of the script are preferred to the units t, h and g
+		{expr: "2 t", dialect: entities.DialectMath, expected: 6},
+		{expr: "h = 4; 2 h", dialect: entities.DialectMath, expected: 8},
+		{expr: "f(g) = 2 g; f(x)", dialect: entities.DialectMath, expected: 4},
+		{expr: "3 min(x, 1)", dialect: entities.DialectMath, expected: 3},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			id := strconv.Itoa(i)
+			expr := &entities.Expression{ID: id, Expression: tc.expr, Dialect: tc.dialect, Locale: tc.locale, Variables: map[string]float64{"x": 2, "t": 3}}
+			if err := s.ScheduleExpression(expr); err != nil {
+				t.Fatalf("ScheduleExpression returned error: %v", err)
+			}
+			for {
+				task, err := s.GetTask()
+				if err != nil {
+					break
+				}
+				if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: compute(task), Lease: task.Lease}); err != nil {
+					t.Fatalf("ProcessResult returned error: %v", err)
+				}
+			}
+
+			stored, err := storage.GetExpression(id)
+			if err != nil {
+				t.Fatalf("GetExpression returned error: %v", err)
+			}
+			if stored.Status != entities.ExpressionStatusCompleted || math.Abs(stored.Result-tc.expected) > 1e-9 {
+				t.Errorf("Expected completed expression with result %g, got %+v", tc.expected, stored)
+			}
+		})
+	}
+
+	var syntaxErrors parser.SyntaxErrors
+	err = s.ScheduleExpression(&entities.Expression{ID: "strict", Expression: "2(3 + 4)"})
+	if !errors.As(err, &syntaxErrors) {
+		t.Errorf("Expected syntax errors for implicit multiplication in the strict dialect, got %v", err)
+	}
+	err = s.ScheduleExpression(&entities.Expression{ID: "unknown", Expression: "2 * 3", Dialect: "latex"})
+	if !errors.Is(err, use_cases_errors.ErrInvalidDialect) {
+		t.Errorf("Expected ErrInvalidDialect, got %v", err)
+	}
+}
+
+func TestScheduleExpressionNotation(t *testing.T) {
+	storage := memory_expression_storage.NewStorage()
+	s := NewScheduler(storage, memory_task_storage.NewTaskPool(), &configs.Config{})
+
+	testCases := []struct {
+		expr     string
+		notation entities.Notation
+	}{
+		{expr: "(3 + 4) * 2", notation: entities.NotationInfix},
+		{expr: "3 4 + 2 *", notation: entities.NotationRPN},
+		{expr: "* + 3 4 2", notation: entities.NotationPrefix},
+		{expr: "(* (+ 3 4) 2)", notation: entities.NotationPrefix},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			id := strconv.Itoa(i)
+			if err := s.ScheduleExpression(&entities.Expression{ID: id, Expression: tc.expr, Notation: tc.notation}); err != nil {
+				t.Fatalf("ScheduleExpression returned error: %v", err)
+			}
+			for {
+				task, err := s.GetTask()
+				if err != nil {
+					break
+				}
+				if err = s.ProcessResult(entities.TaskResult{ID: task.ID, Result: compute(task), Lease: task.Lease}); err != nil {
+					t.Fatalf("ProcessResult returned error: %v", err)
+				}
+			}
+
+			stored, err := storage.GetExpression(id)
+			if err != nil {
+				t.Fatalf("GetExpression returned error: %v", err)
+			}
+			if stored.Status != entities.ExpressionStatusCompleted || stored.Result != 14 {
+				t.Errorf("Expected completed expression with result 14, got %+v", stored)
+			}
+
+			rendered := map[entities.Notation]string{
+				entities.NotationInfix:  "(3 + 4) * 2",
+				entities.NotationRPN:    "3 4 + 2 *",
+				entities.NotationPrefix: "(* (+ 3 4) 2)",
+			}
+			for notation, expected := range rendered {
+				expr, err := s.RenderExpression(id, notation)
+				if err != nil {
+					t.Fatalf("RenderExpression(%s) returned error: %v", notation, err)
+				}
+				if expr.Expression != expected || expr.Notation != notation {
+					t.Errorf("Expected %q in %s, got %q in %s", expected, notation, expr.Expression, expr.Notation)
+				}
+			}
+			if stored.Expression != tc.expr {
+				t.Errorf("Expected the stored expression %q to be kept, got %q", tc.expr, stored.Expression)
+			}
+		})
+	}
+
+	err := s.ScheduleExpression(&entities.Expression{ID: "unknown", Expression: "3 4 +", Notation: "postfix"})
+	if !errors.Is(err, use_cases_errors.ErrInvalidNotation) {
+		t.Errorf("Expected ErrInvalidNotation, got %v", err)
+	}
+	if _, err = s.RenderExpression("0", "postfix"); !errors.Is(err, use_cases_errors.ErrInvalidNotation) {
+		t.Errorf("Expected ErrInvalidNotation for rendering, got %v", err)
+	}
+}
+
+func TestScheduleExpressionLimits(t *testing.T) {
+	cfg := &configs.Config{MaxExpressionLength: 40, MaxTokens: 20, MaxNestingDepth: 5, MaxTasks: 3}
+	s := NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), cfg)
+
+	testCases := []struct {
+		expr     string
+		expected error
+	}{
+		{expr: "1 + 2 + 3", expected: nil},
+		{expr: strings.Repeat("1 + ", 10) + "1", expected: use_cases_errors.ErrExpressionTooLarge},
+		{expr: strings.Repeat("1+", 10) + "1", expected: use_cases_errors.ErrExpressionTooComplex},
+		{expr: "((((((1))))))", expected: use_cases_errors.ErrExpressionTooComplex},
+		{expr: "1 + 2 + 3 + 4 + 5", expected: use_cases_errors.ErrExpressionTooComplex},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			err := s.ScheduleExpression(&entities.Expression{ID: strconv.Itoa(i), Expression: tc.expr})
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+
+	var limitErr *parser.LimitError
+	_, err := s.DefineFunction("f(x) = " + strings.Repeat("-", 6) + "x")
+	if !errors.Is(err, use_cases_errors.ErrExpressionTooComplex) || !errors.As(err, &limitErr) || limitErr.Limit != parser.LimitDepth {
+		t.Errorf("Expected the depth limit error for the definition, got %v", err)
+	}
+}
+
+// compute returns the result of the arithmetic, comparison, min and max tasks of the tests.
+func compute(task *entities.AgentTask) float64 {
+	switch task.Operation {
+	case "+":
+		return task.Arg1 + task.Arg2
+	case "-":
+		return task.Arg1 - task.Arg2
+	case "*":
+		return task.Arg1 * task.Arg2
+	case "/":
+		return task.Arg1 / task.Arg2
+	case "^":
+		return math.Pow(task.Arg1, task.Arg2)
+	case "sqrt":
+		return math.Sqrt(task.Arg1)
+	case "min":
+		return math.Min(task.Arg1, task.Arg2)
+	case "max":
+		return math.Max(task.Arg1, task.Arg2)
+	case "<":
+		if task.Arg1 < task.Arg2 {
+			return 1
+		}
+	case ">":
+		if task.Arg1 > task.Arg2 {
+			return 1
+		}
+	}
+	return 0
+}
+
+func TestDegreesToRadians(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected float64
+	}{
+		{"sin(90)", 1},
+		{"cos(180)", -1},
+		{"sin(45 + 45)", 1},
+		{"sqrt(4) + cos(0)", 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			root, err := parser.Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result, err := degreesToRadians(root).Evaluate()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if math.Abs(result-tc.expected) > 1e-12 {
+				t.Errorf("Expected %f, got %f", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDegreesToRadiansComplex(t *testing.T) {
+	s := NewScheduler(memory_expression_storage.NewStorage(), memory_task_storage.NewTaskPool(), &configs.Config{})
+
+	err := s.ScheduleExpression(&entities.Expression{ID: "1", Expression: "sin(2i)", Mode: entities.ModeComplex, AngleUnit: entities.AngleUnitDegrees})
+	if err != nil {
+		t.Fatalf("ScheduleExpression returned error: %v", err)
+	}
+
+	// the imaginary literal is scaled as a whole, not replaced by its real part
+	task, err := s.GetTask()
+	if err != nil {
+		t.Fatalf("GetTask returned error: %v", err)
+	}
+	if task.Operation != "*" || complex(task.Arg1, task.Imag1) != 2i || task.Arg2 != math.Pi/180 {
+		t.Errorf("Expected 2i * pi/180, got %+v", task)
+	}
+}
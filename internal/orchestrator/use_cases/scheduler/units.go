@@ -0,0 +1,303 @@
+package scheduler
+
+import (
+	use_cases_errors "calculator/internal/orchestrator/use_cases/errors"
+	"calculator/internal/orchestrator/use_cases/parser"
+	"calculator/internal/shared/complexnum"
+	"calculator/internal/shared/entities"
+	"calculator/internal/shared/exact"
+	"calculator/internal/shared/units"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// convertUnits checks the dimensions of the quantities of the tree and
+// replaces them with numbers in base units, so agents compute without
+// units. The unit of the result is recorded in the expression, the result
+// is divided by the factor of the unit the expression asks for.
+func (s *Scheduler) convertUnits(root *parser.Node, expr *entities.Expression) (*parser.Node, error) {
+	if expr.To == "" && !hasUnits(root) {
+		return root, nil
+	}
+
+	c := &unitConverter{catalog: s.catalog, mode: expr.Mode}
+	root, dimension := c.convert(root, nil)
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+	expr.Unit = s.catalog.Format(dimension)
+	if expr.To == "" {
+		return root, nil
+	}
+
+	to, err := s.catalog.Parse(expr.To)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", use_cases_errors.ErrInvalidUnit, err)
+	}
+	if !to.Dimension.Equal(dimension) {
+		return nil, fmt.Errorf("%w: cannot convert %s to %s", use_cases_errors.ErrInvalidUnit, describe(expr.Unit), expr.To)
+	}
+	expr.Unit = expr.To
+	if to.Factor.Cmp(big.NewRat(1, 1)) == 0 {
+		return root, nil
+	}
+	return divideResult(root, c.number(to.Factor, 0, "")), nil
+}
+
+func hasUnits(node *parser.Node) bool {
+	if node == nil {
+		return false
+	}
+	return node.Token.Type == parser.Unit || hasUnits(node.Left) || hasUnits(node.Right)
+}
+
+// divideResult divides the value of the last statement of the script.
+func divideResult(root, divisor *parser.Node) *parser.Node {
+	if root.Token.Type == parser.Let {
+		let := *root
+		let.Right = divideResult(root.Right, divisor)
+		return &let
+	}
+	return &parser.Node{
+		Token: parser.Token{Type: parser.Divide, Value: "/", Pos: root.Token.Pos},
+		Left:  root,
+		Right: divisor,
+	}
+}
+
+// dimensionScope holds the dimensions of the names bound around a node.
+type dimensionScope struct {
+	name      string
+	dimension units.Dimension
+	outer     *dimensionScope
+}
+
+func (s *dimensionScope) lookup(name string) units.Dimension {
+	for ; s != nil; s = s.outer {
+		if s.name == name {
+			return s.dimension
+		}
+	}
+	return nil
+}
+
+// unitConverter rewrites the tree without changing the nodes it is given,
+// the expansion of user-defined functions may share them between calls.
+type unitConverter struct {
+	catalog *units.Catalog
+	mode    entities.Mode
+	errs    parser.SyntaxErrors
+}
+
+// convert returns the node with its quantities replaced by numbers and the
+// dimension of its value. The dimension is nil when it is unknown because of
+// an error already reported, it does not cause more errors.
+func (c *unitConverter) convert(node *parser.Node, scope *dimensionScope) (*parser.Node, units.Dimension) {
+	if node == nil {
+		return nil, units.Dimension{}
+	}
+
+	switch node.Token.Type {
+	case parser.Number:
+		return node, units.Dimension{}
+	case parser.Variable:
+		return node, scope.lookup(node.Token.Value)
+	case parser.Unit:
+		return c.quantity(node)
+	case parser.Let:
+		let := *node
+		var value units.Dimension
+		let.Left, value = c.convert(node.Left, scope)
+		var dimension units.Dimension
+		let.Right, dimension = c.convert(node.Right, &dimensionScope{name: node.Token.Value, dimension: value, outer: scope})
+		return &let, dimension
+	case parser.If:
+		conditional := *node
+		branches := *node.Right
+		conditional.Left, _ = c.convert(node.Left, scope)
+		var then, otherwise units.Dimension
+		branches.Left, then = c.convert(node.Right.Left, scope)
+		branches.Right, otherwise = c.convert(node.Right.Right, scope)
+		conditional.Right = &branches
+		return &conditional, c.same(node, branches.Left, branches.Right, then, otherwise)
+	case parser.Function:
+		return c.function(node, scope)
+	}
+
+	converted := *node
+	var left, right units.Dimension
+	converted.Left, left = c.convert(node.Left, scope)
+	converted.Right, right = c.convert(node.Right, scope)
+
+	switch node.Token.Type {
+	case parser.Plus, parser.Minus, parser.Modulo, parser.Min, parser.Max:
+		return &converted, c.same(node, converted.Left, converted.Right, left, right)
+	case parser.Less, parser.LessEqual, parser.Greater, parser.GreaterEqual, parser.Equal, parser.NotEqual:
+		c.same(node, converted.Left, converted.Right, left, right)
+		return &converted, units.Dimension{}
+	case parser.Multiply:
+		return &converted, mul(left, right)
+	case parser.Divide, parser.FloorDivide:
+		if left == nil || right == nil {
+			return &converted, nil
+		}
+		return &converted, left.Div(right)
+	case parser.Power:
+		return &converted, c.power(node, converted.Right, left, right)
+	}
+	// not, and, or are true or false whatever the units of their operands are
+	return &converted, units.Dimension{}
+}
+
+// quantity replaces the quantity with its number in base units.
+func (c *unitConverter) quantity(node *parser.Node) (*parser.Node, units.Dimension) {
+	unit, err := c.catalog.Parse(node.Token.Value)
+	if err != nil {
+		c.errorAt(node, err.Error())
+		return node.Left, nil
+	}
+	pos := node.Left.Token.Pos
+	if node.Left.IsImaginary() {
+		return c.number(unit.Factor, pos, node.Left.Token.Value), unit.Dimension
+	}
+	value, err := exact.Parse(node.Left.Token.Value)
+	if err != nil {
+		c.errorAt(node.Left, err.Error())
+		return node.Left, nil
+	}
+	return c.number(value.Mul(value, unit.Factor), pos, ""), unit.Dimension
+}
+
+// number makes a number node of the value x, or of the complex number
+// literal scaled by x when literal is set.
+func (c *unitConverter) number(x *big.Rat, pos int, literal string) *parser.Node {
+	f, _ := x.Float64()
+	node := &parser.Node{Token: parser.Token{Type: parser.Number, Pos: pos}, Value: f, Parsed: true}
+	switch {
+	case literal != "" || c.mode == entities.ModeComplex:
+		z := complex(f, 0)
+		if literal != "" {
+			w, _ := complexnum.Parse(literal)
+			z *= w
+		}
+		node.Token.Value = complexnum.Format(z)
+		node.Value, node.Parsed = real(z), imag(z) == 0
+	case c.mode == entities.ModeFloat:
+		node.Token.Value = strconv.FormatFloat(f, 'g', -1, 64)
+	default:
+		node.Token.Value = x.RatString()
+	}
+	return node
+}
+
+// function returns the dimension of the value of the function.
+func (c *unitConverter) function(node *parser.Node, scope *dimensionScope) (*parser.Node, units.Dimension) {
+	converted := *node
+	var dimension units.Dimension
+	converted.Left, dimension = c.convert(node.Left, scope)
+	if dimension == nil {
+		return &converted, nil
+	}
+
+	switch node.Token.Value {
+	case "abs", "floor", "ceil", "round", "re", "im", "conj":
+		return &converted, dimension
+	case "arg", "not":
+		return &converted, units.Dimension{}
+	case "sqrt":
+		root, ok := dimension.Root(2)
+		if !ok {
+			c.errorAt(node, fmt.Sprintf("square root of %s is not a unit", describe(c.catalog.Format(dimension))))
+			return &converted, nil
+		}
+		return &converted, root
+	}
+	if len(dimension) > 0 {
+		c.errorAt(node, fmt.Sprintf("function %s expects a dimensionless argument, got %s", node.Token.Value, c.catalog.Format(dimension)))
+		return &converted, nil
+	}
+	return &converted, dimension
+}
+
+// power returns the dimension of a quantity raised to a power. The exponent
+// of a quantity with a unit must be a whole number written in the expression.
+func (c *unitConverter) power(node, exponent *parser.Node, base, dimension units.Dimension) units.Dimension {
+	if base == nil || dimension == nil {
+		return nil
+	}
+	if len(dimension) > 0 {
+		c.errorAt(node, fmt.Sprintf("exponent must be dimensionless, got %s", c.catalog.Format(dimension)))
+		return nil
+	}
+	if len(base) == 0 {
+		return base
+	}
+	n, ok := integerConstant(exponent)
+	if !ok {
+		c.errorAt(node, fmt.Sprintf("exponent of a quantity in %s must be a whole number", c.catalog.Format(base)))
+		return nil
+	}
+	return base.Pow(n)
+}
+
+// integerConstant returns the value of a whole number literal, which may
+// be negated like in 2^-1.
+func integerConstant(node *parser.Node) (int, bool) {
+	sign := 1
+	if node.Token.Type == parser.Minus && isZero(node.Left) {
+		sign, node = -1, node.Right
+	}
+	if node.Token.Type != parser.Number || !node.Parsed {
+		return 0, false
+	}
+	x, err := exact.Parse(node.Token.Value)
+	if err != nil || !x.IsInt() || !x.Num().IsInt64() {
+		return 0, false
+	}
+	return sign * int(x.Num().Int64()), true
+}
+
+// same checks that the operands have the same dimension and returns it.
+// The number 0 has any dimension, so -5 m, which is parsed as 0 - 5 m, and
+// x > 0 are valid.
+func (c *unitConverter) same(node, leftNode, rightNode *parser.Node, left, right units.Dimension) units.Dimension {
+	switch {
+	case left == nil || right == nil:
+		return nil
+	case isZero(leftNode):
+		return right
+	case isZero(rightNode) || left.Equal(right):
+		return left
+	}
+	c.errorAt(node, fmt.Sprintf("incompatible units of %s: %s and %s", node.Token.Value,
+		describe(c.catalog.Format(left)), describe(c.catalog.Format(right))))
+	return nil
+}
+
+func (c *unitConverter) errorAt(node *parser.Node, message string) {
+	c.errs = append(c.errs, &parser.SyntaxError{
+		Offset:  node.Token.Pos,
+		Length:  len(node.Token.Value),
+		Message: message,
+	})
+}
+
+func mul(left, right units.Dimension) units.Dimension {
+	if left == nil || right == nil {
+		return nil
+	}
+	return left.Mul(right)
+}
+
+func isZero(node *parser.Node) bool {
+	return node.Token.Type == parser.Number && node.Parsed && node.Value == 0
+}
+
+// describe writes the unit of a dimension, a dimensionless value has none.
+func describe(unit string) string {
+	if unit == "" {
+		return "dimensionless"
+	}
+	return unit
+}
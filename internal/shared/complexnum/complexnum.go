@@ -0,0 +1,144 @@
+package complexnum
+
+import (
+	"calculator/internal/shared/functions"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a complex number written like "3+4i", "2i" or "-1.5".
+func Parse(s string) (complex128, error) {
+	z, err := strconv.ParseComplex(strings.ReplaceAll(s, "_", ""), 128)
+	if err != nil {
+		return 0, fmt.Errorf("invalid complex number: %s", s)
+	}
+	return z, nil
+}
+
+// Format writes z like "3+4i". A number without an imaginary part is written
+// as a real number and a number without a real part like "4i".
+func Format(z complex128) string {
+	re, im := real(z), imag(z)
+	switch {
+	case im == 0:
+		return formatFloat(re)
+	case re == 0:
+		return formatFloat(im) + "i"
+	case im < 0 || math.IsNaN(im):
+		return formatFloat(re) + formatFloat(im) + "i"
+	default:
+		return formatFloat(re) + "+" + formatFloat(im) + "i"
+	}
+}
+
+func formatFloat(x float64) string {
+	if x == 0 {
+		// no negative zero
+		return "0"
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// Apply applies the binary operation op to a and b. The remainder, the floor
+// division, the ordering comparisons, the minimum and the maximum are only
+// defined for real numbers.
+func Apply(op string, a, b complex128) (complex128, error) {
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return 0, functions.ErrDivisionByZero
+		}
+		return a / b, nil
+	case "^":
+		if a == 0 && real(b) < 0 {
+			return 0, functions.ErrDivisionByZero
+		}
+		return cmplx.Pow(a, b), nil
+	case "==":
+		return boolComplex(a == b), nil
+	case "!=":
+		return boolComplex(a != b), nil
+	case "and":
+		return boolComplex(a != 0 && b != 0), nil
+	case "or":
+		return boolComplex(a != 0 || b != 0), nil
+	}
+
+	if imag(a) != 0 || imag(b) != 0 {
+		return 0, fmt.Errorf("operation %s is not defined for complex numbers", op)
+	}
+	var result float64
+	var err error
+	switch op {
+	case "%":
+		result, err = functions.Modulo(real(a), real(b))
+	case "//":
+		result, err = functions.FloorDivide(real(a), real(b))
+	case "<", "<=", ">", ">=":
+		result, err = functions.ApplyLogical(op, real(a), real(b))
+	case "min":
+		result = math.Min(real(a), real(b))
+	case "max":
+		result = math.Max(real(a), real(b))
+	default:
+		return 0, fmt.Errorf("%w: %s", functions.ErrUnknownOperation, op)
+	}
+	return complex(result, 0), err
+}
+
+// unary maps the names of single-argument functions to their complex implementations.
+// Rounding functions round the real and the imaginary part.
+var unary = map[string]func(complex128) (complex128, error){
+	"sqrt": func(z complex128) (complex128, error) { return cmplx.Sqrt(z), nil },
+	"abs":  func(z complex128) (complex128, error) { return complex(cmplx.Abs(z), 0), nil },
+	"sin":  func(z complex128) (complex128, error) { return cmplx.Sin(z), nil },
+	"cos":  func(z complex128) (complex128, error) { return cmplx.Cos(z), nil },
+	"tan":  func(z complex128) (complex128, error) { return cmplx.Tan(z), nil },
+	"ln": func(z complex128) (complex128, error) {
+		if z == 0 {
+			return 0, fmt.Errorf("logarithm of zero")
+		}
+		return cmplx.Log(z), nil
+	},
+	"log10": func(z complex128) (complex128, error) {
+		if z == 0 {
+			return 0, fmt.Errorf("logarithm of zero")
+		}
+		return cmplx.Log10(z), nil
+	},
+	"exp":   func(z complex128) (complex128, error) { return cmplx.Exp(z), nil },
+	"floor": func(z complex128) (complex128, error) { return complex(math.Floor(real(z)), math.Floor(imag(z))), nil },
+	"ceil":  func(z complex128) (complex128, error) { return complex(math.Ceil(real(z)), math.Ceil(imag(z))), nil },
+	"round": func(z complex128) (complex128, error) { return complex(math.Round(real(z)), math.Round(imag(z))), nil },
+	"re":    func(z complex128) (complex128, error) { return complex(real(z), 0), nil },
+	"im":    func(z complex128) (complex128, error) { return complex(imag(z), 0), nil },
+	"conj":  func(z complex128) (complex128, error) { return cmplx.Conj(z), nil },
+	"arg":   func(z complex128) (complex128, error) { return complex(cmplx.Phase(z), 0), nil },
+	"not":   func(z complex128) (complex128, error) { return boolComplex(z == 0), nil },
+}
+
+// ApplyUnary applies the single-argument function name to z.
+func ApplyUnary(name string, z complex128) (complex128, error) {
+	fn, ok := unary[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", functions.ErrUnknownFunction, name)
+	}
+	return fn(z)
+}
+
+// boolComplex converts a truth value to 1 or 0.
+func boolComplex(b bool) complex128 {
+	if b {
+		return 1
+	}
+	return 0
+}
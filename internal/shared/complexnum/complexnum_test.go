@@ -0,0 +1,114 @@
+package complexnum
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"3+4i", "3+4i"},
+		{"2i", "2i"},
+		{"-1.5", "-1.5"},
+		{"1_000i", "1000i"},
+		{"0.5-0.25i", "0.5-0.25i"},
+		{"1e3i", "1000i"},
+		{"-0", "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			z, err := Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if s := Format(z); s != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, s)
+			}
+		})
+	}
+
+	if _, err := Parse("3+i4"); err == nil {
+		t.Errorf("Expected error for an invalid number")
+	}
+}
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		name     string
+		op       string
+		a, b     complex128
+		expected complex128
+		errMsg   string
+	}{
+		{name: "addition", op: "+", a: 3 + 4i, b: 1 - 2i, expected: 4 + 2i},
+		{name: "subtraction", op: "-", a: 3 + 4i, b: 1 - 2i, expected: 2 + 6i},
+		{name: "multiplication", op: "*", a: 2i, b: 2i, expected: -4},
+		{name: "division", op: "/", a: 3 + 4i, b: 1 + 2i, expected: 2.2 - 0.4i},
+		{name: "division by zero", op: "/", a: 1i, b: 0, errMsg: "division by zero"},
+		{name: "power", op: "^", a: 1i, b: 2, expected: -1},
+		{name: "equality", op: "==", a: 3 + 4i, b: 3 + 4i, expected: 1},
+		{name: "imaginary number is true", op: "and", a: 1i, b: 2, expected: 1},
+		{name: "comparison of reals", op: "<", a: 1, b: 2, expected: 1},
+		{name: "comparison of complex numbers", op: "<", a: 1i, b: 2, errMsg: "operation < is not defined for complex numbers"},
+		{name: "modulo of reals", op: "%", a: -7, b: 2, expected: 1},
+		{name: "modulo of complex numbers", op: "%", a: 7i, b: 2, errMsg: "operation % is not defined for complex numbers"},
+		{name: "max of reals", op: "max", a: -7, b: 2, expected: 2},
+		{name: "min of complex numbers", op: "min", a: 7i, b: 2, errMsg: "operation min is not defined for complex numbers"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Apply(tc.op, tc.a, tc.b)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("Expected error %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !near(result, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestApplyUnary(t *testing.T) {
+	testCases := []struct {
+		name     string
+		z        complex128
+		expected complex128
+	}{
+		{"re", 3 + 4i, 3},
+		{"im", 3 + 4i, 4},
+		{"conj", 3 + 4i, 3 - 4i},
+		{"abs", 3 + 4i, 5},
+		{"arg", 1i, math.Pi / 2},
+		{"sqrt", -4, 2i},
+		{"exp", complex(0, math.Pi), -1},
+		{"floor", 1.5 - 1.5i, 1 - 2i},
+		{"not", 1i, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ApplyUnary(tc.name, tc.z)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !near(result, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func near(a, b complex128) bool {
+	return math.Abs(real(a-b))+math.Abs(imag(a-b)) < 1e-12
+}
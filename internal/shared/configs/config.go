@@ -21,6 +21,26 @@ type Config struct {
 	TimeSubtractionMS    int    `yaml:"timeSubtractionMS"`
 	TimeMultiplicationMS int    `yaml:"timeMultiplicationMS"`
 	TimeDivisionMS       int    `yaml:"timeDivisionMS"`
+	TimeModuloMS         int    `yaml:"timeModuloMS"`
+	TimeFloorDivisionMS  int    `yaml:"timeFloorDivisionMS"`
+	TimeExponentiationMS int    `yaml:"timeExponentiationMS"`
+	TimeFunctionMS       int    `yaml:"timeFunctionMS"`
+	TimeComparisonMS     int    `yaml:"timeComparisonMS"`
+	TimeMinimumMS        int    `yaml:"timeMinimumMS"`
+	TimeMaximumMS        int    `yaml:"timeMaximumMS"`
+	Optimize             bool   `yaml:"optimize"`
+	FoldThresholdMS      int    `yaml:"foldThresholdMS"`
+	MaxExpansionNodes    int    `yaml:"maxExpansionNodes"`
+	MaxExpressionLength  int    `yaml:"maxExpressionLength"`
+	MaxTokens            int    `yaml:"maxTokens"`
+	MaxNestingDepth      int    `yaml:"maxNestingDepth"`
+	MaxTasks             int    `yaml:"maxTasks"`
+	LeaseGraceMS         int    `yaml:"leaseGraceMS"`
+	LeaseCheckMS         int    `yaml:"leaseCheckMS"`
+	PriorityAgingMS      int    `yaml:"priorityAgingMS"`
+	UnitsPath            string `yaml:"unitsPath"`
+	// Units is the unit catalog loaded from UnitsPath.
+	Units *Units `yaml:"-"`
 }
 
 // LoadConfig loads the configuration from a YAML file.
@@ -33,6 +53,24 @@ func LoadConfig(path string) (*Config, error) {
 		TimeSubtractionMS:    200,
 		TimeMultiplicationMS: 300,
 		TimeDivisionMS:       400,
+		TimeModuloMS:         400,
+		TimeFloorDivisionMS:  400,
+		TimeExponentiationMS: 500,
+		TimeFunctionMS:       300,
+		TimeComparisonMS:     100,
+		TimeMinimumMS:        100,
+		TimeMaximumMS:        100,
+		Optimize:             true,
+		FoldThresholdMS:      1000,
+		MaxExpansionNodes:    10000,
+		MaxExpressionLength:  10000,
+		MaxTokens:            5000,
+		MaxNestingDepth:      100,
+		MaxTasks:             5000,
+		LeaseGraceMS:         10000,
+		LeaseCheckMS:         1000,
+		PriorityAgingMS:      1000,
+		UnitsPath:            "configs/units.yml",
 	}
 
 	data, err := os.ReadFile(path)
@@ -57,6 +95,24 @@ func ConfigFromEnvironment(cfg *Config) {
 	cfg.TimeSubtractionMS = getEnvAsInt("TIME_SUBTRACTION_MS", cfg.TimeSubtractionMS)
 	cfg.TimeMultiplicationMS = getEnvAsInt("TIME_MULTIPLICATIONS_MS", cfg.TimeMultiplicationMS)
 	cfg.TimeDivisionMS = getEnvAsInt("TIME_DIVISIONS_MS", cfg.TimeDivisionMS)
+	cfg.TimeModuloMS = getEnvAsInt("TIME_MODULO_MS", cfg.TimeModuloMS)
+	cfg.TimeFloorDivisionMS = getEnvAsInt("TIME_FLOOR_DIVISIONS_MS", cfg.TimeFloorDivisionMS)
+	cfg.TimeExponentiationMS = getEnvAsInt("TIME_EXPONENTIATIONS_MS", cfg.TimeExponentiationMS)
+	cfg.TimeFunctionMS = getEnvAsInt("TIME_FUNCTIONS_MS", cfg.TimeFunctionMS)
+	cfg.TimeComparisonMS = getEnvAsInt("TIME_COMPARISONS_MS", cfg.TimeComparisonMS)
+	cfg.TimeMinimumMS = getEnvAsInt("TIME_MINIMUM_MS", cfg.TimeMinimumMS)
+	cfg.TimeMaximumMS = getEnvAsInt("TIME_MAXIMUM_MS", cfg.TimeMaximumMS)
+	cfg.Optimize = getEnvAsBool("OPTIMIZE", cfg.Optimize)
+	cfg.FoldThresholdMS = getEnvAsInt("FOLD_THRESHOLD_MS", cfg.FoldThresholdMS)
+	cfg.MaxExpansionNodes = getEnvAsInt("MAX_EXPANSION_NODES", cfg.MaxExpansionNodes)
+	cfg.MaxExpressionLength = getEnvAsInt("MAX_EXPRESSION_LENGTH", cfg.MaxExpressionLength)
+	cfg.MaxTokens = getEnvAsInt("MAX_TOKENS", cfg.MaxTokens)
+	cfg.MaxNestingDepth = getEnvAsInt("MAX_NESTING_DEPTH", cfg.MaxNestingDepth)
+	cfg.MaxTasks = getEnvAsInt("MAX_TASKS", cfg.MaxTasks)
+	cfg.LeaseGraceMS = getEnvAsInt("LEASE_GRACE_MS", cfg.LeaseGraceMS)
+	cfg.LeaseCheckMS = getEnvAsInt("LEASE_CHECK_MS", cfg.LeaseCheckMS)
+	cfg.PriorityAgingMS = getEnvAsInt("PRIORITY_AGING_MS", cfg.PriorityAgingMS)
+	cfg.UnitsPath = getEnvAsString("UNITS_PATH", cfg.UnitsPath)
 	cfg.ComputingPower = getEnvAsInt("COMPUTING_POWER", cfg.ComputingPower)
 	cfg.OrchestratorURL = getEnvAsString("ORCHESTRATOR_URL", cfg.OrchestratorURL)
 	cfg.Server.HttpPort = getEnvAsInt("SERVER_PORT", cfg.Server.HttpPort)
@@ -82,6 +138,15 @@ func getEnvAsInt(key string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvAsBool(key string, defaultVal bool) bool {
+	if val, ok := os.LookupEnv(key); ok {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
 func getEnvAsString(key string, defaultVal string) string {
 	if val, ok := os.LookupEnv(key); ok {
 		return val
@@ -53,7 +53,56 @@ func TestConfigFromEnvironment(t *testing.T) {
 		os.Unsetenv("TIME_DIVISIONS_MS")
 	})
 
-	// Test case 5: ComputingPower environment variable is set
+	// Test case 5: TimeModuloMS and TimeFloorDivisionMS environment variables are set
+	t.Run("TimeModuloMS and TimeFloorDivisionMS environment variables are set", func(t *testing.T) {
+		os.Setenv("TIME_MODULO_MS", "410")
+		os.Setenv("TIME_FLOOR_DIVISIONS_MS", "420")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.TimeModuloMS != 410 {
+			t.Errorf("Expected TimeModuloMS to be 410, got %d", cfg.TimeModuloMS)
+		}
+		if cfg.TimeFloorDivisionMS != 420 {
+			t.Errorf("Expected TimeFloorDivisionMS to be 420, got %d", cfg.TimeFloorDivisionMS)
+		}
+		os.Unsetenv("TIME_MODULO_MS")
+		os.Unsetenv("TIME_FLOOR_DIVISIONS_MS")
+	})
+
+	// Test case 6: TimeExponentiationMS environment variable is set
+	t.Run("TimeExponentiationMS environment variable is set", func(t *testing.T) {
+		os.Setenv("TIME_EXPONENTIATIONS_MS", "500")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.TimeExponentiationMS != 500 {
+			t.Errorf("Expected TimeExponentiationMS to be 500, got %d", cfg.TimeExponentiationMS)
+		}
+		os.Unsetenv("TIME_EXPONENTIATIONS_MS")
+	})
+
+	// Test case 7: TimeFunctionMS environment variable is set
+	t.Run("TimeFunctionMS environment variable is set", func(t *testing.T) {
+		os.Setenv("TIME_FUNCTIONS_MS", "300")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.TimeFunctionMS != 300 {
+			t.Errorf("Expected TimeFunctionMS to be 300, got %d", cfg.TimeFunctionMS)
+		}
+		os.Unsetenv("TIME_FUNCTIONS_MS")
+	})
+
+	// Test case 8: TimeComparisonMS environment variable is set
+	t.Run("TimeComparisonMS environment variable is set", func(t *testing.T) {
+		os.Setenv("TIME_COMPARISONS_MS", "100")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.TimeComparisonMS != 100 {
+			t.Errorf("Expected TimeComparisonMS to be 100, got %d", cfg.TimeComparisonMS)
+		}
+		os.Unsetenv("TIME_COMPARISONS_MS")
+	})
+
+	// Test case 9: ComputingPower environment variable is set
 	t.Run("ComputingPower environment variable is set", func(t *testing.T) {
 		os.Setenv("COMPUTING_POWER", "5")
 		cfg := &Config{}
@@ -64,7 +113,7 @@ func TestConfigFromEnvironment(t *testing.T) {
 		os.Unsetenv("COMPUTING_POWER")
 	})
 
-	// Test case 6: OrchestratorURL environment variable is set
+	// Test case 10: OrchestratorURL environment variable is set
 	t.Run("OrchestratorURL environment variable is set", func(t *testing.T) {
 		os.Setenv("ORCHESTRATOR_URL", "http://example.com")
 		cfg := &Config{}
@@ -75,6 +124,105 @@ func TestConfigFromEnvironment(t *testing.T) {
 		os.Unsetenv("ORCHESTRATOR_URL")
 	})
 
+	// Test case 11: Optimize environment variable is set
+	t.Run("Optimize environment variable is set", func(t *testing.T) {
+		os.Setenv("OPTIMIZE", "true")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if !cfg.Optimize {
+			t.Errorf("Expected Optimize to be true, got %v", cfg.Optimize)
+		}
+		os.Unsetenv("OPTIMIZE")
+	})
+
+	// Test case 12: FoldThresholdMS environment variable is set
+	t.Run("FoldThresholdMS environment variable is set", func(t *testing.T) {
+		os.Setenv("FOLD_THRESHOLD_MS", "1000")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.FoldThresholdMS != 1000 {
+			t.Errorf("Expected FoldThresholdMS to be 1000, got %d", cfg.FoldThresholdMS)
+		}
+		os.Unsetenv("FOLD_THRESHOLD_MS")
+	})
+
+	// Test case 13: MaxExpansionNodes environment variable is set
+	t.Run("MaxExpansionNodes environment variable is set", func(t *testing.T) {
+		os.Setenv("MAX_EXPANSION_NODES", "500")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.MaxExpansionNodes != 500 {
+			t.Errorf("Expected MaxExpansionNodes to be 500, got %d", cfg.MaxExpansionNodes)
+		}
+		os.Unsetenv("MAX_EXPANSION_NODES")
+	})
+
+	// Test case 14: UnitsPath environment variable is set
+	t.Run("UnitsPath environment variable is set", func(t *testing.T) {
+		os.Setenv("UNITS_PATH", "units.yml")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.UnitsPath != "units.yml" {
+			t.Errorf("Expected UnitsPath to be units.yml, got %s", cfg.UnitsPath)
+		}
+		os.Unsetenv("UNITS_PATH")
+	})
+
+	// Test case 15: limit environment variables are set
+	t.Run("Limit environment variables are set", func(t *testing.T) {
+		os.Setenv("MAX_EXPRESSION_LENGTH", "2000")
+		os.Setenv("MAX_TOKENS", "300")
+		os.Setenv("MAX_NESTING_DEPTH", "20")
+		os.Setenv("MAX_TASKS", "100")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.MaxExpressionLength != 2000 || cfg.MaxTokens != 300 || cfg.MaxNestingDepth != 20 || cfg.MaxTasks != 100 {
+			t.Errorf("Expected limits 2000, 300, 20, 100, got %d, %d, %d, %d",
+				cfg.MaxExpressionLength, cfg.MaxTokens, cfg.MaxNestingDepth, cfg.MaxTasks)
+		}
+		os.Unsetenv("MAX_EXPRESSION_LENGTH")
+		os.Unsetenv("MAX_TOKENS")
+		os.Unsetenv("MAX_NESTING_DEPTH")
+		os.Unsetenv("MAX_TASKS")
+	})
+
+	// Test case 16: TimeMinimumMS and TimeMaximumMS environment variables are set
+	t.Run("TimeMinimumMS and TimeMaximumMS environment variables are set", func(t *testing.T) {
+		os.Setenv("TIME_MINIMUM_MS", "150")
+		os.Setenv("TIME_MAXIMUM_MS", "250")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.TimeMinimumMS != 150 || cfg.TimeMaximumMS != 250 {
+			t.Errorf("Expected TimeMinimumMS and TimeMaximumMS to be 150 and 250, got %d and %d", cfg.TimeMinimumMS, cfg.TimeMaximumMS)
+		}
+		os.Unsetenv("TIME_MINIMUM_MS")
+		os.Unsetenv("TIME_MAXIMUM_MS")
+	})
+
+	// Test case 17: lease environment variables are set
+	t.Run("Lease environment variables are set", func(t *testing.T) {
+		os.Setenv("LEASE_GRACE_MS", "3000")
+		os.Setenv("LEASE_CHECK_MS", "500")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.LeaseGraceMS != 3000 || cfg.LeaseCheckMS != 500 {
+			t.Errorf("Expected LeaseGraceMS and LeaseCheckMS to be 3000 and 500, got %d and %d", cfg.LeaseGraceMS, cfg.LeaseCheckMS)
+		}
+		os.Unsetenv("LEASE_GRACE_MS")
+		os.Unsetenv("LEASE_CHECK_MS")
+	})
+
+	// Test case 18: PRIORITY_AGING_MS environment variable is set
+	t.Run("PRIORITY_AGING_MS environment variable is set", func(t *testing.T) {
+		os.Setenv("PRIORITY_AGING_MS", "250")
+		cfg := &Config{}
+		ConfigFromEnvironment(cfg)
+		if cfg.PriorityAgingMS != 250 {
+			t.Errorf("Expected PriorityAgingMS to be 250, got %d", cfg.PriorityAgingMS)
+		}
+		os.Unsetenv("PRIORITY_AGING_MS")
+	})
+
 }
 
 func TestConfigFromData(t *testing.T) {
@@ -87,6 +235,24 @@ func TestConfigFromData(t *testing.T) {
 		TimeSubtractionMS:    200,
 		TimeMultiplicationMS: 300,
 		TimeDivisionMS:       400,
+		TimeModuloMS:         400,
+		TimeFloorDivisionMS:  400,
+		TimeExponentiationMS: 500,
+		TimeFunctionMS:       300,
+		TimeComparisonMS:     100,
+		TimeMinimumMS:        100,
+		TimeMaximumMS:        100,
+		Optimize:             true,
+		FoldThresholdMS:      1000,
+		MaxExpansionNodes:    10000,
+		MaxExpressionLength:  10000,
+		MaxTokens:            5000,
+		MaxNestingDepth:      100,
+		MaxTasks:             5000,
+		LeaseGraceMS:         10000,
+		LeaseCheckMS:         1000,
+		PriorityAgingMS:      1000,
+		UnitsPath:            "configs/units.yml",
 	}
 	data, err := yaml.Marshal(validConfig)
 	if err != nil {
@@ -107,6 +273,34 @@ func TestConfigFromData(t *testing.T) {
 	}
 }
 
+func TestLoadUnits(t *testing.T) {
+	units, err := LoadUnits("../../../configs/units.yml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(units.Base) != 7 || len(units.Units) == 0 {
+		t.Errorf("Expected the SI base units and more units, got %v", units)
+	}
+
+	// Test case: Missing file
+	units, err = LoadUnits("missing.yml")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(units.Base) != 7 || len(units.Units) != 0 {
+		t.Errorf("Expected only the SI base units, got %v", units)
+	}
+
+	// Test case: Invalid YAML
+	path := t.TempDir() + "/units.yml"
+	if err = os.WriteFile(path, []byte("base: {"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadUnits(path); err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
 func TestGetEnvAsInt(t *testing.T) {
 	// Test case 1: Environment variable not set
 	key := "TEST_KEY"
@@ -135,6 +329,27 @@ func TestGetEnvAsInt(t *testing.T) {
 	}
 }
 
+func TestGetEnvAsBool(t *testing.T) {
+	// Test case 1: Environment variable not set
+	os.Unsetenv("TEST_KEY")
+	if result := getEnvAsBool("TEST_KEY", true); !result {
+		t.Errorf("Expected true, got %v", result)
+	}
+
+	// Test case 2: Environment variable set to valid boolean
+	os.Setenv("TEST_KEY", "false")
+	if result := getEnvAsBool("TEST_KEY", true); result {
+		t.Errorf("Expected false, got %v", result)
+	}
+
+	// Test case 3: Environment variable set to non-boolean value
+	os.Setenv("TEST_KEY", "not a bool")
+	if result := getEnvAsBool("TEST_KEY", true); !result {
+		t.Errorf("Expected true, got %v", result)
+	}
+	os.Unsetenv("TEST_KEY")
+}
+
 func TestGetEnvAsString(t *testing.T) {
 	// Test case: environment variable exists
 	os.Setenv("TEST_KEY", "test_value")
@@ -0,0 +1,41 @@
+package configs
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Unit is a unit of measurement of the unit catalog. Factor converts a
+// value in the unit to the base units of Dimension, which is written with
+// the base units like kg*m/s^2.
+type Unit struct {
+	Name      string `yaml:"name"`
+	Factor    string `yaml:"factor"`
+	Dimension string `yaml:"dimension"`
+}
+
+// Units is the unit catalog. Every base unit is a unit of its own dimension.
+type Units struct {
+	Base  []string `yaml:"base"`
+	Units []Unit   `yaml:"units"`
+}
+
+// LoadUnits loads the unit catalog from a YAML file.
+// Without the file the catalog has only the SI base units.
+func LoadUnits(path string) (*Units, error) {
+	defaultUnits := &Units{
+		Base: []string{"m", "kg", "s", "A", "K", "mol", "cd"},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return defaultUnits, nil
+	}
+
+	units := &Units{}
+	if err = yaml.Unmarshal(data, units); err != nil {
+		return nil, err
+	}
+	return units, nil
+}
@@ -7,12 +7,146 @@ const (
 	ExpressionStatusPending    ExpressionStatus = "pending"
 	ExpressionStatusProcessing ExpressionStatus = "processing"
 	ExpressionStatusCompleted  ExpressionStatus = "completed"
+	// ExpressionStatusFailed is the status of an expression a task of which
+	// could not be computed, Error tells why.
+	ExpressionStatusFailed ExpressionStatus = "failed"
+	// ExpressionStatusPaused is the status of an expression whose tasks are
+	// not sent to agents until it is resumed.
+	ExpressionStatusPaused ExpressionStatus = "paused"
+	// ExpressionStatusCancelled is the status of an expression whose
+	// unfinished tasks were removed, it is never completed.
+	ExpressionStatusCancelled ExpressionStatus = "cancelled"
 )
 
+// IsFinal reports whether an expression with the status is never changed
+// again: it is completed, failed or cancelled.
+func (s ExpressionStatus) IsFinal() bool {
+	return s == ExpressionStatusCompleted || s == ExpressionStatusFailed || s == ExpressionStatusCancelled
+}
+
+// ErrorCode is the kind of error a task failed with.
+type ErrorCode string
+
+const (
+	ErrorCodeDivisionByZero   ErrorCode = "division_by_zero"
+	ErrorCodeUnknownOperation ErrorCode = "unknown_operation"
+	// ErrorCodeComputation is any other error of an operation, like the
+	// square root of a negative number.
+	ErrorCodeComputation ErrorCode = "computation_error"
+)
+
+// ExpressionError is the error a failed expression failed with: the code and
+// the message of the error and the ID of the task that failed.
+type ExpressionError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	TaskID  string    `json:"taskId"`
+}
+
+// AngleUnit is the unit of the arguments of trigonometric functions.
+type AngleUnit string
+
+const (
+	AngleUnitRadians AngleUnit = "radians"
+	AngleUnitDegrees AngleUnit = "degrees"
+)
+
+// Dialect is the notation an expression is written in.
+type Dialect string
+
+const (
+	// DialectStrict accepts the ASCII operators and explicit multiplication only.
+	DialectStrict Dialect = "strict"
+	// DialectMath accepts the Unicode operators like × and − and implicit
+	// multiplication like 2(3+4) or 3x.
+	DialectMath Dialect = "math"
+)
+
+// Notation is the order the operators of an expression are written in.
+type Notation string
+
+const (
+	// NotationInfix writes operators between their operands, like (3 + 4) * 2.
+	NotationInfix Notation = "infix"
+	// NotationRPN writes operators after their operands, like 3 4 + 2 *.
+	NotationRPN Notation = "rpn"
+	// NotationPrefix writes operators before their operands, like (* (+ 3 4) 2).
+	NotationPrefix Notation = "prefix"
+)
+
+// Mode is the arithmetic an expression is evaluated with.
+type Mode string
+
+const (
+	// ModeFloat evaluates with float64 numbers.
+	ModeFloat Mode = "float"
+	// ModeDecimal evaluates with exact decimal numbers rounded to the scale of the expression.
+	ModeDecimal Mode = "decimal"
+	// ModeRational evaluates with exact fractions.
+	ModeRational Mode = "rational"
+	// ModeComplex evaluates with complex numbers of float64 parts.
+	ModeComplex Mode = "complex"
+)
+
+// Progress counts the tasks of an expression, the tasks of the branches not
+// chosen are not counted.
+type Progress struct {
+	// Total is the number of tasks computed and left to compute.
+	Total int `json:"total"`
+	// Ready tasks wait for an agent, the ones neither ready, in flight nor
+	// completed wait for the tasks they use.
+	Ready int `json:"ready"`
+	// InFlight tasks are leased to agents.
+	InFlight  int `json:"inFlight"`
+	Completed int `json:"completed"`
+	// EstimatedMS is the time left at the rate the tasks were computed so far,
+	// it is not known until the first task is computed.
+	EstimatedMS int64 `json:"estimatedMs,omitempty"`
+}
+
 // Expression represents an arithmetic expression and its current status.
 type Expression struct {
-	ID         string           `json:"id"`
-	Expression string           `json:"expression"`
-	Status     ExpressionStatus `json:"status"`
-	Result     float64          `json:"result,omitempty"`
+	ID         string             `json:"id"`
+	Expression string             `json:"expression"`
+	Variables  map[string]float64 `json:"variables,omitempty"`
+	AngleUnit  AngleUnit          `json:"angleUnit,omitempty"`
+	// Notation is the order its operators are written in.
+	Notation Notation `json:"notation,omitempty"`
+	// Dialect is the set of symbols and shorthands it is written with.
+	Dialect Dialect `json:"dialect,omitempty"`
+	// Locale like de-DE makes a comma between digits a decimal separator in
+	// the languages that write numbers so.
+	Locale string `json:"locale,omitempty"`
+	Mode   Mode   `json:"mode,omitempty"`
+	// Scale is the number of digits kept after the decimal point in decimal mode.
+	Scale int `json:"scale,omitempty"`
+	// TasksSaved is the number of tasks the optimizer removed before dispatch.
+	TasksSaved int `json:"tasksSaved,omitempty"`
+	// StrictOrder keeps chains like a+b+c computed from left to right instead
+	// of being regrouped for parallel computation.
+	StrictOrder bool `json:"strictOrder,omitempty"`
+	// The tasks of the expressions of a higher Priority are sent to agents first.
+	Priority int `json:"priority,omitempty"`
+	// To is the unit the result is converted to.
+	To string `json:"to,omitempty"`
+	// Depth is the number of tasks on the longest chain of tasks that wait for each other.
+	Depth  int              `json:"depth,omitempty"`
+	Status ExpressionStatus `json:"status"`
+	// Result and Imag are the real and the imaginary part of the result, Imag
+	// is only set in complex mode.
+	Result float64 `json:"result,omitempty"`
+	Imag   float64 `json:"imag,omitempty"`
+	// ExactResult is the result written as a decimal string in decimal mode,
+	// as a reduced fraction in rational mode and like 3+4i in complex mode.
+	ExactResult string `json:"exactResult,omitempty"`
+	// Unit is the unit of the result: To when it is set and the base units
+	// of the result otherwise.
+	Unit string `json:"unit,omitempty"`
+	// Bindings and ExactBindings hold the values of the names assigned in a script.
+	Bindings      map[string]float64 `json:"bindings,omitempty"`
+	ExactBindings map[string]string  `json:"exactBindings,omitempty"`
+	// Error is set when the status is failed.
+	Error *ExpressionError `json:"error,omitempty"`
+	// Progress counts its tasks, it is only returned for a single expression.
+	Progress *Progress `json:"progress,omitempty"`
 }
@@ -0,0 +1,9 @@
+package entities
+
+// Function is a function defined by the user, like f(x, y) = x^2 + y.
+// Definition holds the whole definition written in its normalized form.
+type Function struct {
+	Name       string   `json:"name"`
+	Params     []string `json:"params"`
+	Definition string   `json:"definition"`
+}
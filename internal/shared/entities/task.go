@@ -5,13 +5,24 @@ import (
 )
 
 // AgentTask represents a single arithmetic operation task.
+// In complex mode Imag1 and Imag2 are the imaginary parts of the arguments.
 type AgentTask struct {
 	ExprID        string        `json:"-"`
 	ID            string        `json:"id"`
 	Arg1          float64       `json:"arg1"`
 	Arg2          float64       `json:"arg2"`
 	Operation     string        `json:"operation"`
+	Kind          TaskKind      `json:"kind"`
+	Mode          Mode          `json:"mode"`
+	Scale         int           `json:"scale"`
+	ExactArg1     string        `json:"exactArg1,omitempty"`
+	ExactArg2     string        `json:"exactArg2,omitempty"`
+	Imag1         float64       `json:"imag1,omitempty"`
+	Imag2         float64       `json:"imag2,omitempty"`
 	OperationTime time.Duration `json:"operationTime"`
+	// Lease tells this dispatch of the task from the earlier ones,
+	// the result is accepted only with the lease of the last one.
+	Lease int64 `json:"lease"`
 }
 
 type ArgType = int
@@ -19,21 +30,59 @@ type ArgType = int
 const (
 	IsTask ArgType = iota
 	IsNumber
+	IsEmpty
+)
+
+// TaskKind tells how many arguments the operation of a task takes.
+type TaskKind = int
+
+const (
+	TaskKindBinary TaskKind = iota
+	TaskKindUnary
+	// TaskKindConditional tasks are resolved by the orchestrator, they are never sent to agents.
+	TaskKindConditional
 )
 
 // Task represents a task in the task pool.
+// Unary tasks keep their argument in ArgLeft and have an empty ArgRight.
+// Conditional tasks keep the condition in ArgLeft, have an empty ArgRight
+// and keep their branches in ArgThen and ArgElse.
+// Guard is the ID of the conditional task whose branch contains the task,
+// such a task is not sent to agents until the branch is chosen.
+// Bindings are the names the script assigns the result of the task to.
+// Priority is the priority of the expression of the task.
 type Task struct {
 	ExprID    string
 	ID        string
 	ArgLeft   Arg
 	ArgRight  Arg
+	ArgThen   Arg
+	ArgElse   Arg
 	Operation string
+	Kind      TaskKind
+	Mode      Mode
+	Scale     int
+	Guard     string
+	Bindings  []string
+	Priority  int
 	Result    float64
+	// Lease is the number of times the task was sent to agents,
+	// the result of the last dispatch carries it.
+	Lease int64
+}
+
+// IsReady reports whether all arguments of the task are computed.
+func (t *Task) IsReady() bool {
+	return t.ArgLeft.ArgType != IsTask && t.ArgRight.ArgType != IsTask
 }
 
 // Arg represents an argument in a task.
+// ArgExact keeps the number as a decimal string or a fraction for the exact modes.
+// In complex mode ArgFloat and ArgImag are the real and the imaginary part.
 type Arg struct {
 	ArgFloat float64
+	ArgImag  float64
+	ArgExact string
 	ArgTask  *Task
 	ArgType  ArgType
 }
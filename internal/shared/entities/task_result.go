@@ -1,7 +1,23 @@
 package entities
 
 // TaskResult represents the result of a task computation.
+// Exact holds the result written as a decimal string in decimal mode
+// and as a reduced fraction in rational mode. In complex mode Result and Imag
+// are the real and the imaginary part and Exact holds the result written like 3+4i.
+// Lease is the lease of the task the agent computed.
 type TaskResult struct {
 	ID     string  `json:"id"`
 	Result float64 `json:"result"`
+	Imag   float64 `json:"imag,omitempty"`
+	Exact  string  `json:"exact,omitempty"`
+	Lease  int64   `json:"lease,omitempty"`
+}
+
+// TaskError represents the error an agent failed to compute a task with.
+// Lease is the lease of the task the agent computed.
+type TaskError struct {
+	ID      string    `json:"id"`
+	Lease   int64     `json:"lease,omitempty"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
 }
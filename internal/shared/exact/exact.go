@@ -0,0 +1,208 @@
+package exact
+
+import (
+	"calculator/internal/shared/functions"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DefaultScale is the number of digits after the decimal point used when the scale is not set.
+const DefaultScale = 28
+
+// MaxScale limits the number of digits after the decimal point.
+const MaxScale = 1000
+
+// maxExponent limits exponents, so a single task can not grow a number without bounds.
+const maxExponent = 4096
+
+// maxResultBits limits the size of the numerator and the denominator of a power,
+// a small exponent of a big number can grow it as much as a big exponent.
+const maxResultBits = 1 << 16
+
+// Parse parses a number written as a decimal literal ("0.1", "6.02e23")
+// or as a fraction ("1/3").
+func Parse(s string) (*big.Rat, error) {
+	x, ok := new(big.Rat).SetString(strings.ReplaceAll(s, "_", ""))
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %s", s)
+	}
+	return x, nil
+}
+
+// FormatDecimal formats x as a decimal with at most scale digits after the point.
+// The last digit is rounded half away from zero and trailing zeros are removed.
+func FormatDecimal(x *big.Rat, scale int) string {
+	s := x.FloatString(scale)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// Apply applies the binary operation op to a and b exactly.
+func Apply(op string, a, b *big.Rat) (*big.Rat, error) {
+	switch op {
+	case "+":
+		return new(big.Rat).Add(a, b), nil
+	case "-":
+		return new(big.Rat).Sub(a, b), nil
+	case "*":
+		return new(big.Rat).Mul(a, b), nil
+	case "/":
+		if b.Sign() == 0 {
+			return nil, functions.ErrDivisionByZero
+		}
+		return new(big.Rat).Quo(a, b), nil
+	case "//":
+		if b.Sign() == 0 {
+			return nil, functions.ErrDivisionByZero
+		}
+		return new(big.Rat).SetInt(floor(new(big.Rat).Quo(a, b))), nil
+	case "%":
+		if b.Sign() == 0 {
+			return nil, functions.ErrDivisionByZero
+		}
+		q := new(big.Rat).SetInt(floor(new(big.Rat).Quo(a, b)))
+		return new(big.Rat).Sub(a, q.Mul(q, b)), nil
+	case "^":
+		return pow(a, b)
+	case "min":
+		if a.Cmp(b) <= 0 {
+			return new(big.Rat).Set(a), nil
+		}
+		return new(big.Rat).Set(b), nil
+	case "max":
+		if a.Cmp(b) >= 0 {
+			return new(big.Rat).Set(a), nil
+		}
+		return new(big.Rat).Set(b), nil
+	case "<":
+		return boolRat(a.Cmp(b) < 0), nil
+	case "<=":
+		return boolRat(a.Cmp(b) <= 0), nil
+	case ">":
+		return boolRat(a.Cmp(b) > 0), nil
+	case ">=":
+		return boolRat(a.Cmp(b) >= 0), nil
+	case "==":
+		return boolRat(a.Cmp(b) == 0), nil
+	case "!=":
+		return boolRat(a.Cmp(b) != 0), nil
+	case "and":
+		return boolRat(a.Sign() != 0 && b.Sign() != 0), nil
+	case "or":
+		return boolRat(a.Sign() != 0 || b.Sign() != 0), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", functions.ErrUnknownOperation, op)
+	}
+}
+
+// ApplyUnary applies the single-argument function name to x.
+// Functions whose result is not a rational number are only supported by
+// ApplyUnaryDecimal.
+func ApplyUnary(name string, x *big.Rat) (*big.Rat, error) {
+	switch name {
+	case "abs":
+		return new(big.Rat).Abs(x), nil
+	case "floor":
+		return new(big.Rat).SetInt(floor(x)), nil
+	case "ceil":
+		return new(big.Rat).SetInt(ceil(x)), nil
+	case "round":
+		return new(big.Rat).SetInt(round(x)), nil
+	case "not":
+		return boolRat(x.Sign() == 0), nil
+	default:
+		return nil, fmt.Errorf("function %s has no exact result", name)
+	}
+}
+
+// ApplyUnaryDecimal applies the single-argument function name to x and
+// rounds the result to scale digits after the point.
+func ApplyUnaryDecimal(name string, x *big.Rat, scale int) (*big.Rat, error) {
+	if name != "sqrt" {
+		return ApplyUnary(name, x)
+	}
+	if x.Sign() < 0 {
+		return nil, fmt.Errorf("square root of negative number")
+	}
+
+	// sqrt(p/q) * 10^(scale+1) = sqrt(p * 10^(2*scale+2) / q), the extra digit is used for rounding
+	shift := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(2*scale+2)), nil)
+	n := new(big.Int).Mul(x.Num(), shift)
+	n.Quo(n, x.Denom())
+	root := n.Sqrt(n)
+	root.Add(root, big.NewInt(5))
+	root.Quo(root, big.NewInt(10))
+
+	return new(big.Rat).SetFrac(root, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)), nil
+}
+
+// IsExactFunction reports whether the single-argument function name is supported in exact modes.
+func IsExactFunction(name string, decimal bool) bool {
+	switch name {
+	case "abs", "floor", "ceil", "round":
+		return true
+	case "sqrt":
+		return decimal
+	default:
+		return false
+	}
+}
+
+// boolRat converts a truth value to 1 or 0.
+func boolRat(b bool) *big.Rat {
+	if b {
+		return big.NewRat(1, 1)
+	}
+	return new(big.Rat)
+}
+
+func pow(a, b *big.Rat) (*big.Rat, error) {
+	if !b.IsInt() {
+		return nil, fmt.Errorf("non-integer exponent")
+	}
+	if b.Num().CmpAbs(big.NewInt(maxExponent)) > 0 {
+		return nil, fmt.Errorf("exponent too large")
+	}
+
+	e := b.Num().Int64()
+	if e < 0 {
+		if a.Sign() == 0 {
+			return nil, functions.ErrDivisionByZero
+		}
+		a = new(big.Rat).Inv(a)
+		e = -e
+	}
+	if bits := max(a.Num().BitLen(), a.Denom().BitLen()); int64(bits)*e > maxResultBits {
+		return nil, fmt.Errorf("result too large")
+	}
+
+	num := new(big.Int).Exp(a.Num(), big.NewInt(e), nil)
+	den := new(big.Int).Exp(a.Denom(), big.NewInt(e), nil)
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// floor returns the greatest integer not greater than x.
+func floor(x *big.Rat) *big.Int {
+	// Div rounds towards negative infinity for the positive denominator of x
+	return new(big.Int).Div(x.Num(), x.Denom())
+}
+
+func ceil(x *big.Rat) *big.Int {
+	return new(big.Int).Neg(floor(new(big.Rat).Neg(x)))
+}
+
+// round rounds x to the nearest integer, halves away from zero.
+func round(x *big.Rat) *big.Int {
+	half := big.NewRat(1, 2)
+	if x.Sign() < 0 {
+		return ceil(new(big.Rat).Sub(x, half))
+	}
+	return floor(new(big.Rat).Add(x, half))
+}
@@ -0,0 +1,138 @@
+package exact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		name     string
+		op       string
+		a, b     string
+		expected string
+		errMsg   string
+	}{
+		{name: "addition", op: "+", a: "0.1", b: "0.2", expected: "0.3"},
+		{name: "subtraction", op: "-", a: "1", b: "0.9", expected: "0.1"},
+		{name: "multiplication", op: "*", a: "1.5", b: "-2", expected: "-3"},
+		{name: "division", op: "/", a: "1", b: "4", expected: "0.25"},
+		{name: "division by zero", op: "/", a: "1", b: "0", errMsg: "division by zero"},
+		{name: "floor division", op: "//", a: "-7", b: "2", expected: "-4"},
+		{name: "modulo", op: "%", a: "-7", b: "2", expected: "1"},
+		{name: "modulo of decimals", op: "%", a: "5.5", b: "2", expected: "1.5"},
+		{name: "modulo by zero", op: "%", a: "1", b: "0", errMsg: "division by zero"},
+		{name: "scientific notation", op: "*", a: "1e3", b: "1.5", expected: "1500"},
+		{name: "power", op: "^", a: "0.1", b: "3", expected: "0.001"},
+		{name: "negative power", op: "^", a: "2", b: "-2", expected: "0.25"},
+		{name: "zero to negative power", op: "^", a: "0", b: "-1", errMsg: "division by zero"},
+		{name: "non-integer exponent", op: "^", a: "2", b: "0.5", errMsg: "non-integer exponent"},
+		{name: "exponent too large", op: "^", a: "2", b: "100000", errMsg: "exponent too large"},
+		{name: "result too large", op: "^", a: "1e300", b: "4000", errMsg: "result too large"},
+		{name: "large result within the limit", op: "^", a: "10", b: "4000", expected: "1" + strings.Repeat("0", 4000)},
+		{name: "unknown operation", op: "?", a: "1", b: "1", errMsg: "unknown operation: ?"},
+		{name: "exact equality", op: "==", a: "0.3", b: "3/10", expected: "1"},
+		{name: "less", op: "<", a: "1/3", b: "0.3333", expected: "0"},
+		{name: "min", op: "min", a: "1/3", b: "0.3333", expected: "0.3333"},
+		{name: "max", op: "max", a: "1/3", b: "0.3333", expected: "0.3333333333333333333333333333"},
+		{name: "greater or equal", op: ">=", a: "1/3", b: "0.3333", expected: "1"},
+		{name: "and", op: "and", a: "0.5", b: "0", expected: "0"},
+		{name: "or", op: "or", a: "0.5", b: "0", expected: "1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, _ := Parse(tc.a)
+			b, _ := Parse(tc.b)
+			result, err := Apply(tc.op, a, b)
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if err.Error() != tc.errMsg {
+					t.Errorf("Expected error %q, got %q", tc.errMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if s := FormatDecimal(result, DefaultScale); s != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, s)
+			}
+		})
+	}
+}
+
+func TestApplyUnaryDecimal(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fn       string
+		arg      string
+		scale    int
+		expected string
+		errMsg   string
+	}{
+		{name: "abs", fn: "abs", arg: "-2.5", scale: 28, expected: "2.5"},
+		{name: "floor", fn: "floor", arg: "-1.5", scale: 28, expected: "-2"},
+		{name: "ceil", fn: "ceil", arg: "1.2", scale: 28, expected: "2"},
+		{name: "round half away from zero", fn: "round", arg: "-2.5", scale: 28, expected: "-3"},
+		{name: "not", fn: "not", arg: "0", scale: 28, expected: "1"},
+		{name: "sqrt of perfect square", fn: "sqrt", arg: "0.0625", scale: 28, expected: "0.25"},
+		{name: "sqrt rounded", fn: "sqrt", arg: "2", scale: 20, expected: "1.4142135623730950488"},
+		{name: "sqrt of negative", fn: "sqrt", arg: "-1", scale: 28, errMsg: "square root of negative number"},
+		{name: "inexact function", fn: "sin", arg: "1", scale: 28, errMsg: "function sin has no exact result"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			x, _ := Parse(tc.arg)
+			result, err := ApplyUnaryDecimal(tc.fn, x, tc.scale)
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if err.Error() != tc.errMsg {
+					t.Errorf("Expected error %q, got %q", tc.errMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if s := FormatDecimal(result, tc.scale); s != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, s)
+			}
+		})
+	}
+}
+
+func TestParseAndFormat(t *testing.T) {
+	testCases := []struct {
+		input    string
+		scale    int
+		expected string
+	}{
+		{input: "1_000.50", scale: 28, expected: "1000.5"},
+		{input: ".5", scale: 28, expected: "0.5"},
+		{input: "5.", scale: 28, expected: "5"},
+		{input: "2.5E-3", scale: 28, expected: "0.0025"},
+		{input: "1/3", scale: 4, expected: "0.3333"},
+		{input: "2/3", scale: 4, expected: "0.6667"},
+		{input: "-0.00001", scale: 2, expected: "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			x, err := Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if s := FormatDecimal(x, tc.scale); s != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, s)
+			}
+		})
+	}
+
+	if _, err := Parse("abc"); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
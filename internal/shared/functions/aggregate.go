@@ -0,0 +1,64 @@
+package functions
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// aggregates lists the functions that take any number of arguments.
+var aggregates = map[string]bool{
+	"sum":     true,
+	"product": true,
+	"min":     true,
+	"max":     true,
+	"avg":     true,
+	"median":  true,
+}
+
+// IsAggregate reports whether name is a function of any number of arguments like sum or max.
+func IsAggregate(name string) bool {
+	return aggregates[name]
+}
+
+// ApplyAggregate applies the function name of any number of arguments to args.
+func ApplyAggregate(name string, args []float64) (float64, error) {
+	if !IsAggregate(name) {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownFunction, name)
+	}
+	if len(args) == 0 {
+		return 0, fmt.Errorf("function %s expects at least 1 argument", name)
+	}
+
+	result := args[0]
+	switch name {
+	case "sum", "avg":
+		for _, x := range args[1:] {
+			result += x
+		}
+		if name == "avg" {
+			result /= float64(len(args))
+		}
+	case "product":
+		for _, x := range args[1:] {
+			result *= x
+		}
+	case "min":
+		for _, x := range args[1:] {
+			result = math.Min(result, x)
+		}
+	case "max":
+		for _, x := range args[1:] {
+			result = math.Max(result, x)
+		}
+	case "median":
+		sorted := slices.Clone(args)
+		slices.Sort(sorted)
+		middle := len(sorted) / 2
+		result = sorted[middle]
+		if len(sorted)%2 == 0 {
+			result = (sorted[middle-1] + sorted[middle]) / 2
+		}
+	}
+	return result, nil
+}
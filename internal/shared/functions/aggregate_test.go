@@ -0,0 +1,39 @@
+package functions
+
+import "testing"
+
+func TestApplyAggregate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fn       string
+		args     []float64
+		expected float64
+		errMsg   string
+	}{
+		{name: "sum", fn: "sum", args: []float64{1, 2, 3, 4}, expected: 10},
+		{name: "product", fn: "product", args: []float64{2, 3, 4}, expected: 24},
+		{name: "min", fn: "min", args: []float64{3, -1, 2}, expected: -1},
+		{name: "max", fn: "max", args: []float64{3, -1, 2}, expected: 3},
+		{name: "avg", fn: "avg", args: []float64{1, 2, 6}, expected: 3},
+		{name: "median of odd number", fn: "median", args: []float64{5, 1, 3}, expected: 3},
+		{name: "median of even number", fn: "median", args: []float64{4, 1, 3, 2}, expected: 2.5},
+		{name: "single argument", fn: "sum", args: []float64{7}, expected: 7},
+		{name: "no arguments", fn: "max", errMsg: "function max expects at least 1 argument"},
+		{name: "unknown function", fn: "foo", args: []float64{1}, errMsg: "unknown function: foo"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ApplyAggregate(tc.fn, tc.args)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Errorf("Expected error %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil || result != tc.expected {
+				t.Errorf("Expected %g, got %g (%v)", tc.expected, result, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package functions
+
+import (
+	"fmt"
+	"math"
+)
+
+// Modulo returns the remainder of the floor division of a by b.
+// The result has the sign of b, so a == b*FloorDivide(a, b) + Modulo(a, b).
+func Modulo(a, b float64) (float64, error) {
+	if err := checkFloorOperands(a, b); err != nil {
+		return 0, err
+	}
+	return a - b*math.Floor(a/b), nil
+}
+
+// FloorDivide returns a/b rounded towards negative infinity.
+func FloorDivide(a, b float64) (float64, error) {
+	if err := checkFloorOperands(a, b); err != nil {
+		return 0, err
+	}
+	return math.Floor(a / b), nil
+}
+
+func checkFloorOperands(a, b float64) error {
+	if math.IsInf(a, 0) || math.IsNaN(a) || math.IsInf(b, 0) || math.IsNaN(b) {
+		return fmt.Errorf("non-finite operand")
+	}
+	if b == 0 {
+		return ErrDivisionByZero
+	}
+	return nil
+}
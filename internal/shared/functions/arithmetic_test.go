@@ -0,0 +1,46 @@
+package functions
+
+import (
+	"math"
+	"testing"
+)
+
+func TestModuloAndFloorDivide(t *testing.T) {
+	testCases := []struct {
+		name      string
+		a, b      float64
+		quotient  float64
+		remainder float64
+		errMsg    string
+	}{
+		{name: "positive operands", a: 7, b: 3, quotient: 2, remainder: 1},
+		{name: "negative dividend", a: -7, b: 3, quotient: -3, remainder: 2},
+		{name: "negative divisor", a: 7, b: -3, quotient: -3, remainder: -2},
+		{name: "fractional operands", a: 7.5, b: 2, quotient: 3, remainder: 1.5},
+		{name: "division by zero", a: 7, b: 0, errMsg: "division by zero"},
+		{name: "infinite operand", a: math.Inf(1), b: 3, errMsg: "non-finite operand"},
+		{name: "NaN operand", a: 7, b: math.NaN(), errMsg: "non-finite operand"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			quotient, err := FloorDivide(tc.a, tc.b)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Errorf("Expected error %q, got %v", tc.errMsg, err)
+				}
+			} else if err != nil || quotient != tc.quotient {
+				t.Errorf("Expected quotient %f, got %f (%v)", tc.quotient, quotient, err)
+			}
+
+			remainder, err := Modulo(tc.a, tc.b)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Errorf("Expected error %q, got %v", tc.errMsg, err)
+				}
+			} else if err != nil || remainder != tc.remainder {
+				t.Errorf("Expected remainder %f, got %f (%v)", tc.remainder, remainder, err)
+			}
+		})
+	}
+}
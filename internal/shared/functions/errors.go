@@ -0,0 +1,11 @@
+package functions
+
+import "errors"
+
+// The errors of the operations agents tell the orchestrator about when they
+// fail to compute a task.
+var (
+	ErrDivisionByZero   = errors.New("division by zero")
+	ErrUnknownOperation = errors.New("unknown operation")
+	ErrUnknownFunction  = errors.New("unknown function")
+)
@@ -0,0 +1,70 @@
+package functions
+
+import (
+	"fmt"
+	"math"
+)
+
+// unary maps the names of single-argument functions to their implementations.
+var unary = map[string]func(float64) (float64, error){
+	"sqrt": func(x float64) (float64, error) {
+		if x < 0 {
+			return 0, fmt.Errorf("square root of negative number")
+		}
+		return math.Sqrt(x), nil
+	},
+	"abs": func(x float64) (float64, error) { return math.Abs(x), nil },
+	"sin": func(x float64) (float64, error) { return math.Sin(x), nil },
+	"cos": func(x float64) (float64, error) { return math.Cos(x), nil },
+	"tan": func(x float64) (float64, error) { return math.Tan(x), nil },
+	"ln": func(x float64) (float64, error) {
+		if x <= 0 {
+			return 0, fmt.Errorf("logarithm of non-positive number")
+		}
+		return math.Log(x), nil
+	},
+	"log10": func(x float64) (float64, error) {
+		if x <= 0 {
+			return 0, fmt.Errorf("logarithm of non-positive number")
+		}
+		return math.Log10(x), nil
+	},
+	"exp":   func(x float64) (float64, error) { return math.Exp(x), nil },
+	"floor": func(x float64) (float64, error) { return math.Floor(x), nil },
+	"ceil":  func(x float64) (float64, error) { return math.Ceil(x), nil },
+	"round": func(x float64) (float64, error) { return math.Round(x), nil },
+	// the functions of complex numbers, a real number has no imaginary part
+	"re":   func(x float64) (float64, error) { return x, nil },
+	"im":   func(x float64) (float64, error) { return 0, nil },
+	"conj": func(x float64) (float64, error) { return x, nil },
+	"arg":  func(x float64) (float64, error) { return math.Atan2(0, x), nil },
+	// not is written as an operator, it is computed like a function
+	"not": func(x float64) (float64, error) { return Not(x), nil },
+}
+
+// trigonometric lists the functions whose argument is an angle.
+var trigonometric = map[string]bool{
+	"sin": true,
+	"cos": true,
+	"tan": true,
+}
+
+// IsUnary reports whether name is a known single-argument function.
+func IsUnary(name string) bool {
+	_, ok := unary[name]
+	return ok
+}
+
+// IsTrigonometric reports whether name is a function that takes an angle.
+func IsTrigonometric(name string) bool {
+	return trigonometric[name]
+}
+
+// ApplyUnary applies the single-argument function name to x.
+func ApplyUnary(name string, x float64) (float64, error) {
+	fn, ok := unary[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownFunction, name)
+	}
+	return fn(x)
+}
@@ -0,0 +1,53 @@
+package functions
+
+import (
+	"math"
+	"testing"
+)
+
+func TestApplyUnary(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fn       string
+		arg      float64
+		expected float64
+		errMsg   string
+	}{
+		{name: "sqrt", fn: "sqrt", arg: 16, expected: 4},
+		{name: "sqrt of negative", fn: "sqrt", arg: -1, errMsg: "square root of negative number"},
+		{name: "abs", fn: "abs", arg: -2.5, expected: 2.5},
+		{name: "sin", fn: "sin", arg: math.Pi / 2, expected: 1},
+		{name: "cos", fn: "cos", arg: 0, expected: 1},
+		{name: "tan", fn: "tan", arg: 0, expected: 0},
+		{name: "ln", fn: "ln", arg: math.E, expected: 1},
+		{name: "ln of zero", fn: "ln", arg: 0, errMsg: "logarithm of non-positive number"},
+		{name: "log10", fn: "log10", arg: 1000, expected: 3},
+		{name: "exp", fn: "exp", arg: 0, expected: 1},
+		{name: "floor", fn: "floor", arg: -1.5, expected: -2},
+		{name: "ceil", fn: "ceil", arg: 1.2, expected: 2},
+		{name: "round", fn: "round", arg: 2.5, expected: 3},
+		{name: "im of real", fn: "im", arg: 3, expected: 0},
+		{name: "arg of negative", fn: "arg", arg: -2, expected: math.Pi},
+		{name: "unknown function", fn: "foo", arg: 1, errMsg: "unknown function: foo"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ApplyUnary(tc.fn, tc.arg)
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if err.Error() != tc.errMsg {
+					t.Errorf("Expected error %q, got %q", tc.errMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if math.Abs(result-tc.expected) > 1e-12 {
+				t.Errorf("Expected %f, got %f", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package functions
+
+import "fmt"
+
+// Comparisons and logical operators return 1 for true and 0 for false.
+// Any non-zero operand is true.
+
+// ApplyLogical applies the comparison or binary logical operator op to a and b.
+func ApplyLogical(op string, a, b float64) (float64, error) {
+	switch op {
+	case "<":
+		return Bool(a < b), nil
+	case "<=":
+		return Bool(a <= b), nil
+	case ">":
+		return Bool(a > b), nil
+	case ">=":
+		return Bool(a >= b), nil
+	case "==":
+		return Bool(a == b), nil
+	case "!=":
+		return Bool(a != b), nil
+	case "and":
+		return Bool(a != 0 && b != 0), nil
+	case "or":
+		return Bool(a != 0 || b != 0), nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownOperation, op)
+	}
+}
+
+// Not returns the logical negation of x.
+func Not(x float64) float64 {
+	return Bool(x == 0)
+}
+
+// Bool converts a truth value to 1 or 0.
+func Bool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
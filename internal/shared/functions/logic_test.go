@@ -0,0 +1,42 @@
+package functions
+
+import (
+	"testing"
+)
+
+func TestApplyLogical(t *testing.T) {
+	testCases := []struct {
+		op       string
+		a, b     float64
+		expected float64
+	}{
+		{"<", 1, 2, 1},
+		{"<", 2, 2, 0},
+		{"<=", 2, 2, 1},
+		{">", 3, 2, 1},
+		{">=", 1, 2, 0},
+		{"==", 0.5, 0.5, 1},
+		{"!=", 0.5, 0.5, 0},
+		{"and", 2, -1, 1},
+		{"and", 2, 0, 0},
+		{"or", 0, 0, 0},
+		{"or", 0, 3, 1},
+	}
+
+	for _, tc := range testCases {
+		result, err := ApplyLogical(tc.op, tc.a, tc.b)
+		if err != nil {
+			t.Errorf("%v %s %v: unexpected error: %v", tc.a, tc.op, tc.b, err)
+		}
+		if result != tc.expected {
+			t.Errorf("%v %s %v: expected %v, got %v", tc.a, tc.op, tc.b, tc.expected, result)
+		}
+	}
+
+	if _, err := ApplyLogical("?", 1, 1); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if Not(0) != 1 || Not(-2) != 0 {
+		t.Error("Expected not to negate the truth value")
+	}
+}
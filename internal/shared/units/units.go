@@ -0,0 +1,229 @@
+package units
+
+import (
+	"calculator/internal/shared/configs"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Dimension maps the base units to their exponents, like m/s^2 is
+// {"m": 1, "s": -2}. A dimensionless quantity has an empty dimension.
+type Dimension map[string]int
+
+// Equal reports whether the dimensions are the same.
+func (d Dimension) Equal(other Dimension) bool {
+	if len(d) != len(other) {
+		return false
+	}
+	for base, exponent := range d {
+		if other[base] != exponent {
+			return false
+		}
+	}
+	return true
+}
+
+// Mul returns the dimension of the product of quantities of d and other.
+func (d Dimension) Mul(other Dimension) Dimension {
+	return d.combine(other, 1)
+}
+
+// Div returns the dimension of the quotient of quantities of d and other.
+func (d Dimension) Div(other Dimension) Dimension {
+	return d.combine(other, -1)
+}
+
+// Pow returns the dimension of a quantity of d raised to the power n.
+func (d Dimension) Pow(n int) Dimension {
+	result := Dimension{}
+	if n == 0 {
+		return result
+	}
+	for base, exponent := range d {
+		result[base] = exponent * n
+	}
+	return result
+}
+
+// Root returns the dimension of the n-th root of a quantity of d.
+// It reports false when an exponent is not divisible by n.
+func (d Dimension) Root(n int) (Dimension, bool) {
+	result := Dimension{}
+	for base, exponent := range d {
+		if exponent%n != 0 {
+			return nil, false
+		}
+		result[base] = exponent / n
+	}
+	return result, true
+}
+
+func (d Dimension) combine(other Dimension, sign int) Dimension {
+	result := Dimension{}
+	for base, exponent := range d {
+		result[base] = exponent
+	}
+	for base, exponent := range other {
+		result[base] += sign * exponent
+		if result[base] == 0 {
+			delete(result, base)
+		}
+	}
+	return result
+}
+
+// Unit is a unit of measurement: a value in the unit times Factor
+// is the value in the base units of Dimension.
+type Unit struct {
+	Factor    *big.Rat
+	Dimension Dimension
+}
+
+// Catalog resolves the names of units and writes dimensions with its base units.
+type Catalog struct {
+	base  []string
+	units map[string]Unit
+}
+
+// New compiles the unit catalog. A nil catalog has no units.
+func New(cfg *configs.Units) (*Catalog, error) {
+	c := &Catalog{units: map[string]Unit{}}
+	if cfg == nil {
+		return c, nil
+	}
+
+	for _, base := range cfg.Base {
+		c.base = append(c.base, base)
+		c.units[base] = Unit{Factor: big.NewRat(1, 1), Dimension: Dimension{base: 1}}
+	}
+	for _, u := range cfg.Units {
+		if _, ok := c.units[u.Name]; ok {
+			return nil, fmt.Errorf("unit %s is defined twice", u.Name)
+		}
+		factor, ok := new(big.Rat).SetString(u.Factor)
+		if !ok || factor.Sign() <= 0 {
+			return nil, fmt.Errorf("unit %s has an invalid factor: %s", u.Name, u.Factor)
+		}
+		dimension, err := c.parse(u.Dimension, true)
+		if err != nil {
+			return nil, fmt.Errorf("unit %s: %w", u.Name, err)
+		}
+		c.units[u.Name] = Unit{Factor: factor, Dimension: dimension.Dimension}
+	}
+	return c, nil
+}
+
+// IsUnit reports whether the catalog has a unit of the name.
+func (c *Catalog) IsUnit(name string) bool {
+	_, ok := c.units[name]
+	return ok
+}
+
+// Parse resolves a unit written like km/h, m/s^2 or kg*m^2. The operators
+// apply from left to right, so m/s/s is m/s^2.
+func (c *Catalog) Parse(unit string) (Unit, error) {
+	return c.parse(unit, false)
+}
+
+// parse resolves the unit with the base units only, when base is set.
+func (c *Catalog) parse(unit string, base bool) (Unit, error) {
+	result := Unit{Factor: big.NewRat(1, 1), Dimension: Dimension{}}
+	rest := strings.ReplaceAll(unit, " ", "")
+	sign := 1
+	for i := 0; ; i++ {
+		end := strings.IndexAny(rest, "*/")
+		if end < 0 {
+			end = len(rest)
+		}
+		term := rest[:end]
+
+		// a leading 1 is a dimensionless numerator, like in 1/s
+		if !(i == 0 && term == "1" && end < len(rest)) {
+			name, power, err := splitPower(term)
+			if err != nil {
+				return Unit{}, err
+			}
+			u, ok := c.units[name]
+			if !ok || base && !c.isBase(name) {
+				return Unit{}, fmt.Errorf("unknown unit: %s", name)
+			}
+			power *= sign
+			result.Dimension = result.Dimension.Mul(u.Dimension.Pow(power))
+			result.Factor.Mul(result.Factor, ratPow(u.Factor, power))
+		}
+
+		if end == len(rest) {
+			return result, nil
+		}
+		sign = 1
+		if rest[end] == '/' {
+			sign = -1
+		}
+		rest = rest[end+1:]
+	}
+}
+
+func (c *Catalog) isBase(name string) bool {
+	for _, base := range c.base {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPower splits a term like s^-2 into the name and the exponent.
+func splitPower(term string) (string, int, error) {
+	name, exponent, found := strings.Cut(term, "^")
+	if name == "" {
+		return "", 0, fmt.Errorf("missing unit name")
+	}
+	if !found {
+		return name, 1, nil
+	}
+	power, err := strconv.Atoi(exponent)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid exponent of unit %s: %s", name, exponent)
+	}
+	return name, power, nil
+}
+
+// ratPow raises x to the integer power n.
+func ratPow(x *big.Rat, n int) *big.Rat {
+	result := big.NewRat(1, 1)
+	if n < 0 {
+		x = new(big.Rat).Inv(x)
+		n = -n
+	}
+	for ; n > 0; n-- {
+		result.Mul(result, x)
+	}
+	return result
+}
+
+// Format writes the dimension with the base units in the order of the catalog, like m*kg/s^2.
+// A dimensionless quantity is written as an empty string.
+func (c *Catalog) Format(d Dimension) string {
+	var numerator, denominator []string
+	for _, base := range c.base {
+		switch exponent := d[base]; {
+		case exponent == 1:
+			numerator = append(numerator, base)
+		case exponent > 1:
+			numerator = append(numerator, fmt.Sprintf("%s^%d", base, exponent))
+		case exponent == -1:
+			denominator = append(denominator, base)
+		case exponent < -1:
+			denominator = append(denominator, fmt.Sprintf("%s^%d", base, -exponent))
+		}
+	}
+	if len(denominator) == 0 {
+		return strings.Join(numerator, "*")
+	}
+	if len(numerator) == 0 {
+		numerator = []string{"1"}
+	}
+	return strings.Join(numerator, "*") + "/" + strings.Join(denominator, "/")
+}
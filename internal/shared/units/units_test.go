@@ -0,0 +1,145 @@
+package units
+
+import (
+	"calculator/internal/shared/configs"
+	"math/big"
+	"testing"
+)
+
+func testCatalog(t *testing.T) *Catalog {
+	catalog, err := New(&configs.Units{
+		Base: []string{"m", "kg", "s"},
+		Units: []configs.Unit{
+			{Name: "cm", Factor: "1/100", Dimension: "m"},
+			{Name: "km", Factor: "1000", Dimension: "m"},
+			{Name: "h", Factor: "3600", Dimension: "s"},
+			{Name: "N", Factor: "1", Dimension: "kg*m/s^2"},
+			{Name: "Hz", Factor: "1", Dimension: "s^-1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return catalog
+}
+
+func TestParse(t *testing.T) {
+	catalog := testCatalog(t)
+
+	testCases := []struct {
+		unit      string
+		factor    string
+		dimension string
+		errMsg    string
+	}{
+		{unit: "m", factor: "1", dimension: "m"},
+		{unit: "cm", factor: "1/100", dimension: "m"},
+		{unit: "km/h", factor: "5/18", dimension: "m/s"},
+		{unit: "m/s^2", factor: "1", dimension: "m/s^2"},
+		{unit: "m/s/s", factor: "1", dimension: "m/s^2"},
+		{unit: "cm^2", factor: "1/10000", dimension: "m^2"},
+		{unit: "N*m", factor: "1", dimension: "m^2*kg/s^2"},
+		{unit: "1/h", factor: "1/3600", dimension: "1/s"},
+		{unit: "Hz*s", factor: "1", dimension: ""},
+		{unit: "ft", errMsg: "unknown unit: ft"},
+		{unit: "m^x", errMsg: "invalid exponent of unit m: x"},
+		{unit: "m/", errMsg: "missing unit name"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.unit, func(t *testing.T) {
+			unit, err := catalog.Parse(tc.unit)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("Expected error %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if unit.Factor.RatString() != tc.factor {
+				t.Errorf("Expected factor %s, got %s", tc.factor, unit.Factor.RatString())
+			}
+			if dimension := catalog.Format(unit.Dimension); dimension != tc.dimension {
+				t.Errorf("Expected dimension %q, got %q", tc.dimension, dimension)
+			}
+		})
+	}
+}
+
+func TestDimension(t *testing.T) {
+	catalog := testCatalog(t)
+	speed := Dimension{"m": 1, "s": -1}
+	area := Dimension{"m": 2}
+
+	if !speed.Mul(Dimension{"s": 1}).Equal(Dimension{"m": 1}) {
+		t.Errorf("Expected m/s*s to be m")
+	}
+	if !speed.Div(speed).Equal(Dimension{}) {
+		t.Errorf("Expected m/s/(m/s) to be dimensionless")
+	}
+	if s := catalog.Format(speed.Pow(2)); s != "m^2/s^2" {
+		t.Errorf("Expected m^2/s^2, got %s", s)
+	}
+	if root, ok := area.Root(2); !ok || !root.Equal(Dimension{"m": 1}) {
+		t.Errorf("Expected the square root of m^2 to be m, got %v", root)
+	}
+	if _, ok := speed.Root(2); ok {
+		t.Errorf("Expected no square root of m/s")
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name   string
+		units  []configs.Unit
+		errMsg string
+	}{
+		{"duplicate unit", []configs.Unit{{Name: "m", Factor: "1", Dimension: "m"}}, "unit m is defined twice"},
+		{"invalid factor", []configs.Unit{{Name: "cm", Factor: "x", Dimension: "m"}}, "unit cm has an invalid factor: x"},
+		{"negative factor", []configs.Unit{{Name: "cm", Factor: "-1", Dimension: "m"}}, "unit cm has an invalid factor: -1"},
+		{"derived dimension", []configs.Unit{
+			{Name: "cm", Factor: "0.01", Dimension: "m"},
+			{Name: "mL", Factor: "1", Dimension: "cm^3"},
+		}, "unit mL: unknown unit: cm"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(&configs.Units{Base: []string{"m"}, Units: tc.units})
+			if err == nil || err.Error() != tc.errMsg {
+				t.Fatalf("Expected error %q, got %v", tc.errMsg, err)
+			}
+		})
+	}
+
+	catalog, err := New(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := catalog.Parse("m"); err == nil {
+		t.Errorf("Expected an empty catalog")
+	}
+	if unit := testCatalog(t).units["cm"]; unit.Factor.Cmp(big.NewRat(1, 100)) != 0 {
+		t.Errorf("Expected factor 1/100, got %s", unit.Factor)
+	}
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	cfg, err := configs.LoadUnits("../../../configs/units.yml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	catalog, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	unit, err := catalog.Parse("kWh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if unit.Factor.RatString() != "3600000" || catalog.Format(unit.Dimension) != "m^2*kg/s^2" {
+		t.Errorf("Expected 3600000 m^2*kg/s^2, got %s %s", unit.Factor.RatString(), catalog.Format(unit.Dimension))
+	}
+}
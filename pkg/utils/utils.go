@@ -48,6 +48,18 @@ func RespondWith404(w http.ResponseWriter) error {
 		http.StatusText(http.StatusNotFound))
 }
 
+func RespondWith409(w http.ResponseWriter, message string) error {
+	return RespondWithError(w,
+		http.StatusConflict,
+		message)
+}
+
+func RespondWith413(w http.ResponseWriter, message string) error {
+	return RespondWithError(w,
+		http.StatusRequestEntityTooLarge,
+		message)
+}
+
 func RespondWith422(w http.ResponseWriter) error {
 	return RespondWithError(w,
 		http.StatusUnprocessableEntity,
@@ -20,6 +20,104 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type TaskKind int32
+
+const (
+	TaskKind_TASK_KIND_BINARY TaskKind = 0
+	TaskKind_TASK_KIND_UNARY  TaskKind = 1
+)
+
+// Enum value maps for TaskKind.
+var (
+	TaskKind_name = map[int32]string{
+		0: "TASK_KIND_BINARY",
+		1: "TASK_KIND_UNARY",
+	}
+	TaskKind_value = map[string]int32{
+		"TASK_KIND_BINARY": 0,
+		"TASK_KIND_UNARY":  1,
+	}
+)
+
+func (x TaskKind) Enum() *TaskKind {
+	p := new(TaskKind)
+	*p = x
+	return p
+}
+
+func (x TaskKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_calculator_proto_enumTypes[0].Descriptor()
+}
+
+func (TaskKind) Type() protoreflect.EnumType {
+	return &file_proto_calculator_proto_enumTypes[0]
+}
+
+func (x TaskKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskKind.Descriptor instead.
+func (TaskKind) EnumDescriptor() ([]byte, []int) {
+	return file_proto_calculator_proto_rawDescGZIP(), []int{0}
+}
+
+type Mode int32
+
+const (
+	Mode_MODE_FLOAT    Mode = 0
+	Mode_MODE_DECIMAL  Mode = 1
+	Mode_MODE_RATIONAL Mode = 2
+	Mode_MODE_COMPLEX  Mode = 3
+)
+
+// Enum value maps for Mode.
+var (
+	Mode_name = map[int32]string{
+		0: "MODE_FLOAT",
+		1: "MODE_DECIMAL",
+		2: "MODE_RATIONAL",
+		3: "MODE_COMPLEX",
+	}
+	Mode_value = map[string]int32{
+		"MODE_FLOAT":    0,
+		"MODE_DECIMAL":  1,
+		"MODE_RATIONAL": 2,
+		"MODE_COMPLEX":  3,
+	}
+)
+
+func (x Mode) Enum() *Mode {
+	p := new(Mode)
+	*p = x
+	return p
+}
+
+func (x Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_calculator_proto_enumTypes[1].Descriptor()
+}
+
+func (Mode) Type() protoreflect.EnumType {
+	return &file_proto_calculator_proto_enumTypes[1]
+}
+
+func (x Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Mode.Descriptor instead.
+func (Mode) EnumDescriptor() ([]byte, []int) {
+	return file_proto_calculator_proto_rawDescGZIP(), []int{1}
+}
+
 type GetTaskRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -63,12 +161,21 @@ type Task struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ExprId        string  `protobuf:"bytes,1,opt,name=expr_id,json=exprId,proto3" json:"expr_id,omitempty"`
-	Id            string  `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
-	Arg1          float64 `protobuf:"fixed64,3,opt,name=arg1,proto3" json:"arg1,omitempty"`
-	Arg2          float64 `protobuf:"fixed64,4,opt,name=arg2,proto3" json:"arg2,omitempty"`
-	Operation     string  `protobuf:"bytes,5,opt,name=operation,proto3" json:"operation,omitempty"`
-	OperationTime int64   `protobuf:"varint,6,opt,name=operation_time,json=operationTime,proto3" json:"operation_time,omitempty"`
+	ExprId        string   `protobuf:"bytes,1,opt,name=expr_id,json=exprId,proto3" json:"expr_id,omitempty"`
+	Id            string   `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Arg1          float64  `protobuf:"fixed64,3,opt,name=arg1,proto3" json:"arg1,omitempty"`
+	Arg2          float64  `protobuf:"fixed64,4,opt,name=arg2,proto3" json:"arg2,omitempty"`
+	Operation     string   `protobuf:"bytes,5,opt,name=operation,proto3" json:"operation,omitempty"`
+	OperationTime int64    `protobuf:"varint,6,opt,name=operation_time,json=operationTime,proto3" json:"operation_time,omitempty"`
+	Kind          TaskKind `protobuf:"varint,7,opt,name=kind,proto3,enum=calculator.TaskKind" json:"kind,omitempty"`
+	Mode          Mode     `protobuf:"varint,8,opt,name=mode,proto3,enum=calculator.Mode" json:"mode,omitempty"`
+	Scale         int32    `protobuf:"varint,9,opt,name=scale,proto3" json:"scale,omitempty"`
+	ExactArg1     string   `protobuf:"bytes,10,opt,name=exact_arg1,json=exactArg1,proto3" json:"exact_arg1,omitempty"`
+	ExactArg2     string   `protobuf:"bytes,11,opt,name=exact_arg2,json=exactArg2,proto3" json:"exact_arg2,omitempty"`
+	ImagArg1      float64  `protobuf:"fixed64,12,opt,name=imag_arg1,json=imagArg1,proto3" json:"imag_arg1,omitempty"`
+	ImagArg2      float64  `protobuf:"fixed64,13,opt,name=imag_arg2,json=imagArg2,proto3" json:"imag_arg2,omitempty"`
+	// lease identifies this dispatch of the task, the result must carry it
+	Lease int64 `protobuf:"varint,14,opt,name=lease,proto3" json:"lease,omitempty"`
 }
 
 func (x *Task) Reset() {
@@ -145,13 +252,72 @@ func (x *Task) GetOperationTime() int64 {
 	return 0
 }
 
+func (x *Task) GetKind() TaskKind {
+	if x != nil {
+		return x.Kind
+	}
+	return TaskKind_TASK_KIND_BINARY
+}
+
+func (x *Task) GetMode() Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Mode_MODE_FLOAT
+}
+
+func (x *Task) GetScale() int32 {
+	if x != nil {
+		return x.Scale
+	}
+	return 0
+}
+
+func (x *Task) GetExactArg1() string {
+	if x != nil {
+		return x.ExactArg1
+	}
+	return ""
+}
+
+func (x *Task) GetExactArg2() string {
+	if x != nil {
+		return x.ExactArg2
+	}
+	return ""
+}
+
+func (x *Task) GetImagArg1() float64 {
+	if x != nil {
+		return x.ImagArg1
+	}
+	return 0
+}
+
+func (x *Task) GetImagArg2() float64 {
+	if x != nil {
+		return x.ImagArg2
+	}
+	return 0
+}
+
+func (x *Task) GetLease() int64 {
+	if x != nil {
+		return x.Lease
+	}
+	return 0
+}
+
 type TaskResult struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id     string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Result float64 `protobuf:"fixed64,2,opt,name=result,proto3" json:"result,omitempty"`
+	Id          string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Result      float64 `protobuf:"fixed64,2,opt,name=result,proto3" json:"result,omitempty"`
+	ExactResult string  `protobuf:"bytes,3,opt,name=exact_result,json=exactResult,proto3" json:"exact_result,omitempty"`
+	ImagResult  float64 `protobuf:"fixed64,4,opt,name=imag_result,json=imagResult,proto3" json:"imag_result,omitempty"`
+	Lease       int64   `protobuf:"varint,5,opt,name=lease,proto3" json:"lease,omitempty"`
 }
 
 func (x *TaskResult) Reset() {
@@ -200,6 +366,27 @@ func (x *TaskResult) GetResult() float64 {
 	return 0
 }
 
+func (x *TaskResult) GetExactResult() string {
+	if x != nil {
+		return x.ExactResult
+	}
+	return ""
+}
+
+func (x *TaskResult) GetImagResult() float64 {
+	if x != nil {
+		return x.ImagResult
+	}
+	return 0
+}
+
+func (x *TaskResult) GetLease() int64 {
+	if x != nil {
+		return x.Lease
+	}
+	return 0
+}
+
 type SubmitResultResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -238,13 +425,122 @@ func (*SubmitResultResponse) Descriptor() ([]byte, []int) {
 	return file_proto_calculator_proto_rawDescGZIP(), []int{3}
 }
 
+type TaskError struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id      string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Lease   int64  `protobuf:"varint,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	Code    string `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *TaskError) Reset() {
+	*x = TaskError{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_calculator_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TaskError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskError) ProtoMessage() {}
+
+func (x *TaskError) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculator_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskError.ProtoReflect.Descriptor instead.
+func (*TaskError) Descriptor() ([]byte, []int) {
+	return file_proto_calculator_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TaskError) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TaskError) GetLease() int64 {
+	if x != nil {
+		return x.Lease
+	}
+	return 0
+}
+
+func (x *TaskError) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *TaskError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ReportTaskErrorResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReportTaskErrorResponse) Reset() {
+	*x = ReportTaskErrorResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_calculator_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReportTaskErrorResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportTaskErrorResponse) ProtoMessage() {}
+
+func (x *ReportTaskErrorResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculator_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportTaskErrorResponse.ProtoReflect.Descriptor instead.
+func (*ReportTaskErrorResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calculator_proto_rawDescGZIP(), []int{5}
+}
+
 var File_proto_calculator_proto protoreflect.FileDescriptor
 
 var file_proto_calculator_proto_rawDesc = []byte{
 	0x0a, 0x16, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74,
 	0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0a, 0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c,
 	0x61, 0x74, 0x6f, 0x72, 0x22, 0x10, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x54, 0x61, 0x73, 0x6b, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x9c, 0x01, 0x0a, 0x04, 0x54, 0x61, 0x73, 0x6b, 0x12,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x90, 0x03, 0x0a, 0x04, 0x54, 0x61, 0x73, 0x6b, 0x12,
 	0x17, 0x0a, 0x07, 0x65, 0x78, 0x70, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
 	0x52, 0x06, 0x65, 0x78, 0x70, 0x72, 0x49, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x72, 0x67, 0x31,
@@ -254,23 +550,65 @@ var file_proto_calculator_proto_rawDesc = []byte{
 	0x01, 0x28, 0x09, 0x52, 0x09, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x25,
 	0x0a, 0x0e, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x69, 0x6d, 0x65,
 	0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
-	0x6e, 0x54, 0x69, 0x6d, 0x65, 0x22, 0x34, 0x0a, 0x0a, 0x54, 0x61, 0x73, 0x6b, 0x52, 0x65, 0x73,
-	0x75, 0x6c, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x01, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0x16, 0x0a, 0x14, 0x53,
-	0x75, 0x62, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x32, 0x93, 0x01, 0x0a, 0x0a, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74,
-	0x6f, 0x72, 0x12, 0x39, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x54, 0x61, 0x73, 0x6b, 0x12, 0x1a, 0x2e,
-	0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x61,
-	0x73, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x63, 0x61, 0x6c, 0x63,
-	0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x54, 0x61, 0x73, 0x6b, 0x22, 0x00, 0x12, 0x4a, 0x0a,
-	0x0c, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x16, 0x2e,
-	0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x54, 0x61, 0x73, 0x6b, 0x52,
-	0x65, 0x73, 0x75, 0x6c, 0x74, 0x1a, 0x20, 0x2e, 0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74,
-	0x6f, 0x72, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x12, 0x5a, 0x10, 0x63, 0x61, 0x6c,
-	0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x28, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72,
+	0x2e, 0x54, 0x61, 0x73, 0x6b, 0x4b, 0x69, 0x6e, 0x64, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12,
+	0x24, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e,
+	0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x52,
+	0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x63, 0x61, 0x6c, 0x65, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x73, 0x63, 0x61, 0x6c, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x65,
+	0x78, 0x61, 0x63, 0x74, 0x5f, 0x61, 0x72, 0x67, 0x31, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x65, 0x78, 0x61, 0x63, 0x74, 0x41, 0x72, 0x67, 0x31, 0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78,
+	0x61, 0x63, 0x74, 0x5f, 0x61, 0x72, 0x67, 0x32, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x65, 0x78, 0x61, 0x63, 0x74, 0x41, 0x72, 0x67, 0x32, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x6d, 0x61,
+	0x67, 0x5f, 0x61, 0x72, 0x67, 0x31, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x69, 0x6d,
+	0x61, 0x67, 0x41, 0x72, 0x67, 0x31, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x6d, 0x61, 0x67, 0x5f, 0x61,
+	0x72, 0x67, 0x32, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x69, 0x6d, 0x61, 0x67, 0x41,
+	0x72, 0x67, 0x32, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x18, 0x0e, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x22, 0x8e, 0x01, 0x0a, 0x0a, 0x54, 0x61,
+	0x73, 0x6b, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x12, 0x21, 0x0a, 0x0c, 0x65, 0x78, 0x61, 0x63, 0x74, 0x5f, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x65, 0x78, 0x61, 0x63, 0x74, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x69, 0x6d, 0x61, 0x67, 0x5f, 0x72, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0a, 0x69, 0x6d, 0x61, 0x67, 0x52, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x22, 0x16, 0x0a, 0x14, 0x53, 0x75,
+	0x62, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x5f, 0x0a, 0x09, 0x54, 0x61, 0x73, 0x6b, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12,
+	0x14, 0x0a, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x22, 0x19, 0x0a, 0x17, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x54, 0x61, 0x73,
+	0x6b, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2a, 0x35,
+	0x0a, 0x08, 0x54, 0x61, 0x73, 0x6b, 0x4b, 0x69, 0x6e, 0x64, 0x12, 0x14, 0x0a, 0x10, 0x54, 0x41,
+	0x53, 0x4b, 0x5f, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x42, 0x49, 0x4e, 0x41, 0x52, 0x59, 0x10, 0x00,
+	0x12, 0x13, 0x0a, 0x0f, 0x54, 0x41, 0x53, 0x4b, 0x5f, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x55, 0x4e,
+	0x41, 0x52, 0x59, 0x10, 0x01, 0x2a, 0x4d, 0x0a, 0x04, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x0e, 0x0a,
+	0x0a, 0x4d, 0x4f, 0x44, 0x45, 0x5f, 0x46, 0x4c, 0x4f, 0x41, 0x54, 0x10, 0x00, 0x12, 0x10, 0x0a,
+	0x0c, 0x4d, 0x4f, 0x44, 0x45, 0x5f, 0x44, 0x45, 0x43, 0x49, 0x4d, 0x41, 0x4c, 0x10, 0x01, 0x12,
+	0x11, 0x0a, 0x0d, 0x4d, 0x4f, 0x44, 0x45, 0x5f, 0x52, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x41, 0x4c,
+	0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c, 0x4d, 0x4f, 0x44, 0x45, 0x5f, 0x43, 0x4f, 0x4d, 0x50, 0x4c,
+	0x45, 0x58, 0x10, 0x03, 0x32, 0xe4, 0x01, 0x0a, 0x0a, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61,
+	0x74, 0x6f, 0x72, 0x12, 0x39, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x54, 0x61, 0x73, 0x6b, 0x12, 0x1a,
+	0x2e, 0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x54,
+	0x61, 0x73, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x63, 0x61, 0x6c,
+	0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x54, 0x61, 0x73, 0x6b, 0x22, 0x00, 0x12, 0x4a,
+	0x0a, 0x0c, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x16,
+	0x2e, 0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x54, 0x61, 0x73, 0x6b,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x1a, 0x20, 0x2e, 0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61,
+	0x74, 0x6f, 0x72, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x4f, 0x0a, 0x0f, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x54, 0x61, 0x73, 0x6b, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x15, 0x2e,
+	0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x54, 0x61, 0x73, 0x6b, 0x45,
+	0x72, 0x72, 0x6f, 0x72, 0x1a, 0x23, 0x2e, 0x63, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f,
+	0x72, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x54, 0x61, 0x73, 0x6b, 0x45, 0x72, 0x72, 0x6f,
+	0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x12, 0x5a, 0x10, 0x63,
+	0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -285,23 +623,32 @@ func file_proto_calculator_proto_rawDescGZIP() []byte {
 	return file_proto_calculator_proto_rawDescData
 }
 
-var file_proto_calculator_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_calculator_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_calculator_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_proto_calculator_proto_goTypes = []any{
-	(*GetTaskRequest)(nil),       // 0: calculator.GetTaskRequest
-	(*Task)(nil),                 // 1: calculator.Task
-	(*TaskResult)(nil),           // 2: calculator.TaskResult
-	(*SubmitResultResponse)(nil), // 3: calculator.SubmitResultResponse
+	(TaskKind)(0),                   // 0: calculator.TaskKind
+	(Mode)(0),                       // 1: calculator.Mode
+	(*GetTaskRequest)(nil),          // 2: calculator.GetTaskRequest
+	(*Task)(nil),                    // 3: calculator.Task
+	(*TaskResult)(nil),              // 4: calculator.TaskResult
+	(*SubmitResultResponse)(nil),    // 5: calculator.SubmitResultResponse
+	(*TaskError)(nil),               // 6: calculator.TaskError
+	(*ReportTaskErrorResponse)(nil), // 7: calculator.ReportTaskErrorResponse
 }
 var file_proto_calculator_proto_depIdxs = []int32{
-	0, // 0: calculator.Calculator.GetTask:input_type -> calculator.GetTaskRequest
-	2, // 1: calculator.Calculator.SubmitResult:input_type -> calculator.TaskResult
-	1, // 2: calculator.Calculator.GetTask:output_type -> calculator.Task
-	3, // 3: calculator.Calculator.SubmitResult:output_type -> calculator.SubmitResultResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: calculator.Task.kind:type_name -> calculator.TaskKind
+	1, // 1: calculator.Task.mode:type_name -> calculator.Mode
+	2, // 2: calculator.Calculator.GetTask:input_type -> calculator.GetTaskRequest
+	4, // 3: calculator.Calculator.SubmitResult:input_type -> calculator.TaskResult
+	6, // 4: calculator.Calculator.ReportTaskError:input_type -> calculator.TaskError
+	3, // 5: calculator.Calculator.GetTask:output_type -> calculator.Task
+	5, // 6: calculator.Calculator.SubmitResult:output_type -> calculator.SubmitResultResponse
+	7, // 7: calculator.Calculator.ReportTaskError:output_type -> calculator.ReportTaskErrorResponse
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proto_calculator_proto_init() }
@@ -358,19 +705,44 @@ func file_proto_calculator_proto_init() {
 				return nil
 			}
 		}
+		file_proto_calculator_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*TaskError); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_calculator_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*ReportTaskErrorResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_calculator_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      2,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_calculator_proto_goTypes,
 		DependencyIndexes: file_proto_calculator_proto_depIdxs,
+		EnumInfos:         file_proto_calculator_proto_enumTypes,
 		MessageInfos:      file_proto_calculator_proto_msgTypes,
 	}.Build()
 	File_proto_calculator_proto = out.File
@@ -19,8 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	Calculator_GetTask_FullMethodName      = "/calculator.Calculator/GetTask"
-	Calculator_SubmitResult_FullMethodName = "/calculator.Calculator/SubmitResult"
+	Calculator_GetTask_FullMethodName         = "/calculator.Calculator/GetTask"
+	Calculator_SubmitResult_FullMethodName    = "/calculator.Calculator/SubmitResult"
+	Calculator_ReportTaskError_FullMethodName = "/calculator.Calculator/ReportTaskError"
 )
 
 // CalculatorClient is the client API for Calculator service.
@@ -29,6 +30,7 @@ const (
 type CalculatorClient interface {
 	GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error)
 	SubmitResult(ctx context.Context, in *TaskResult, opts ...grpc.CallOption) (*SubmitResultResponse, error)
+	ReportTaskError(ctx context.Context, in *TaskError, opts ...grpc.CallOption) (*ReportTaskErrorResponse, error)
 }
 
 type calculatorClient struct {
@@ -59,12 +61,23 @@ func (c *calculatorClient) SubmitResult(ctx context.Context, in *TaskResult, opt
 	return out, nil
 }
 
+func (c *calculatorClient) ReportTaskError(ctx context.Context, in *TaskError, opts ...grpc.CallOption) (*ReportTaskErrorResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportTaskErrorResponse)
+	err := c.cc.Invoke(ctx, Calculator_ReportTaskError_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CalculatorServer is the server API for Calculator service.
 // All implementations must embed UnimplementedCalculatorServer
 // for forward compatibility
 type CalculatorServer interface {
 	GetTask(context.Context, *GetTaskRequest) (*Task, error)
 	SubmitResult(context.Context, *TaskResult) (*SubmitResultResponse, error)
+	ReportTaskError(context.Context, *TaskError) (*ReportTaskErrorResponse, error)
 	mustEmbedUnimplementedCalculatorServer()
 }
 
@@ -78,6 +91,9 @@ func (UnimplementedCalculatorServer) GetTask(context.Context, *GetTaskRequest) (
 func (UnimplementedCalculatorServer) SubmitResult(context.Context, *TaskResult) (*SubmitResultResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SubmitResult not implemented")
 }
+func (UnimplementedCalculatorServer) ReportTaskError(context.Context, *TaskError) (*ReportTaskErrorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportTaskError not implemented")
+}
 func (UnimplementedCalculatorServer) mustEmbedUnimplementedCalculatorServer() {}
 
 // UnsafeCalculatorServer may be embedded to opt out of forward compatibility for this service.
@@ -127,6 +143,24 @@ func _Calculator_SubmitResult_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Calculator_ReportTaskError_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskError)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).ReportTaskError(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Calculator_ReportTaskError_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).ReportTaskError(ctx, req.(*TaskError))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Calculator_ServiceDesc is the grpc.ServiceDesc for Calculator service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -142,6 +176,10 @@ var Calculator_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SubmitResult",
 			Handler:    _Calculator_SubmitResult_Handler,
 		},
+		{
+			MethodName: "ReportTaskError",
+			Handler:    _Calculator_ReportTaskError_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/calculator.proto",
@@ -1,8 +1,14 @@
 // Initialization of variables
 const expressionInput = document.getElementById('expressionInput');
+const angleUnitSelect = document.getElementById('angleUnitSelect');
+const dialectSelect = document.getElementById('dialectSelect');
+const notationSelect = document.getElementById('notationSelect');
+const modeSelect = document.getElementById('modeSelect');
+const toInput = document.getElementById('toInput');
 const submitButton = document.getElementById('submitButton');
 const expressionsList = document.getElementById('expressionsList');
 const errorMessage = document.getElementById('errorMessage');
+const syntaxErrors = document.getElementById('syntaxErrors');
 
 let intervalId; // Interval variable for periodic updates
 let expressions = []; // Expression array for rendering
@@ -19,7 +25,12 @@ function submitExpression() {
     if (expression) {
         const data = {
             id: id,
-            expression: expression
+            expression: expression,
+            angleUnit: angleUnitSelect.value,
+            dialect: dialectSelect.value,
+            notation: notationSelect.value,
+            mode: modeSelect.value,
+            to: toInput.value.trim()
         };
 
         fetch('/api/v1/calculate', {
@@ -30,11 +41,20 @@ function submitExpression() {
             body: JSON.stringify(data)
         })
         .then(response => {
+            hideSyntaxErrors();
             if (response.ok) {
                 expressionInput.value = '';
                 // Add the expression to the array
                 expressions.push({ expression: expression, id: id, status: 'Pending', result: null });
                 renderExpressions()
+            } else if (response.status === 400) {
+                response.json().then(body => {
+                    if (body.details) {
+                        showSyntaxErrors(expression, body.details);
+                    } else {
+                        showErrorMessage(`Error submitting expression: ${response.status}`, body.error);
+                    }
+                });
             } else {
                 // Show error message if the request was not successful
                 console.error('Error submitting expression:', response.status);
@@ -65,7 +85,15 @@ function renderExpressions() {
     expressionsList.innerHTML = '';
     expressions.forEach(expression => {
         const listItem = document.createElement('li');
-        listItem.textContent = `Expression: ${expression.expression}, ID: ${expression.id}, Status: ${expression.status}, Result: ${expression.result}`;
+        listItem.textContent = `Expression: ${expression.expression}, ID: ${expression.id}, Status: ${expression.status}, Result: ${expression.exactResult || expression.result}${expression.unit ? ' ' + expression.unit : ''}`;
+        if (expression.depth) {
+            listItem.textContent += `, Depth: ${expression.depth}`;
+        }
+        const bindings = expression.exactBindings || expression.bindings;
+        if (bindings) {
+            const values = Object.entries(bindings).map(([name, value]) => `${name} = ${value}`);
+            listItem.textContent += `, Bindings: ${values.join('; ')}`;
+        }
 
         // Check the status of the expression and assign the appropriate CSS class
         if (expression.status === 'completed') {
@@ -87,6 +115,49 @@ function generateUUID() {
     });
 }
 
+// Underline the parts of the expression reported by the server.
+// Offsets and lengths are in bytes of the UTF-8 encoded expression.
+function showSyntaxErrors(expression, details) {
+    const bytes = new TextEncoder().encode(expression);
+    const decoder = new TextDecoder();
+    const spans = [...details].sort((a, b) => a.offset - b.offset);
+
+    syntaxErrors.innerHTML = '';
+    const source = document.createElement('div');
+    let position = 0;
+    spans.forEach(span => {
+        if (span.offset < position) {
+            return;
+        }
+        source.appendChild(document.createTextNode(decoder.decode(bytes.slice(position, span.offset))));
+        const marked = document.createElement('span');
+        marked.classList.add('error-span');
+        // Errors at the end of the expression have no length, mark a space instead
+        marked.textContent = span.length > 0 ? decoder.decode(bytes.slice(span.offset, span.offset + span.length)) : ' ';
+        source.appendChild(marked);
+        position = span.offset + span.length;
+    });
+    source.appendChild(document.createTextNode(decoder.decode(bytes.slice(position))));
+    syntaxErrors.appendChild(source);
+
+    const messages = document.createElement('ul');
+    details.forEach(detail => {
+        const item = document.createElement('li');
+        item.textContent = detail.message;
+        if (detail.expected && detail.expected.length > 0) {
+            item.textContent += ` (expected ${detail.expected.join(', ')})`;
+        }
+        messages.appendChild(item);
+    });
+    syntaxErrors.appendChild(messages);
+    syntaxErrors.style.display = 'block';
+}
+
+function hideSyntaxErrors() {
+    syntaxErrors.style.display = 'none';
+    syntaxErrors.innerHTML = '';
+}
+
 // Show an error message to the user with a timeout
 function showErrorMessage(message, additionalText = '') {
     let errorMessageText = message;